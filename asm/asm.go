@@ -0,0 +1,578 @@
+// Package asm assembles CHIP-8 programs written with the mnemonics that
+// the disasm package prints in its Cowgod syntax.
+//
+// A line holds an optional "label:" followed by an instruction, a
+// directive or a macro invocation, and ends at an optional ';' comment.
+// Mnemonics and directives are case insensitive, symbols are not.
+// Operands are expressions over numbers (decimal, 0x hex, 0b binary or
+// 'c' characters), labels and constants with C operators and
+// precedence. The directives are:
+//
+//	name = expr         define a constant; "name equ expr" also works
+//	org addr            continue at addr, padding with zeros
+//	db expr, "str", ... emit bytes
+//	dw expr, ...        emit big endian words
+//	include "file"      assemble file in place, relative to this one
+//	macro name a, b     start a macro taking parameters a and b
+//	endm                end the macro
+package asm
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+
+	chip8 "github.com/hermesdt/go-plan8"
+)
+
+// maxDepth bounds nested includes and macro expansions.
+const maxDepth = 32
+
+// Pos is a 1-based source position.
+type Pos struct {
+	File string
+	Line int
+	Col  int
+}
+
+func (p Pos) String() string {
+	return fmt.Sprintf("%s:%d:%d", p.File, p.Line, p.Col)
+}
+
+type Error struct {
+	Pos Pos
+	Msg string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%v: %s", e.Pos, e.Msg)
+}
+
+func errorf(pos Pos, format string, args ...interface{}) error {
+	return &Error{Pos: pos, Msg: fmt.Sprintf(format, args...)}
+}
+
+var ErrProgramTooLarge = errors.New("asm: program does not fit in memory")
+
+// Assembler holds the settings a program is assembled with. The zero
+// value assembles CHIP-8 programs read from the file system.
+type Assembler struct {
+	// Variant rejects instructions the variant cannot execute and
+	// bounds the program size.
+	Variant chip8.Variant
+	// ReadFile reads included files. It defaults to ioutil.ReadFile.
+	ReadFile func(name string) ([]byte, error)
+}
+
+// Program is the output of a successful assembly.
+type Program struct {
+	// Bytes is the ROM image, loaded at chip8.RomStart.
+	Bytes   []byte
+	Symbols map[string]int64
+	Listing []Line
+}
+
+// Line is a source line in the listing, with the bytes it assembled to.
+type Line struct {
+	Pos     Pos
+	Address uint16
+	Bytes   []byte
+	Text    string
+}
+
+// WriteListing prints every line with its address and bytes.
+func (p *Program) WriteListing(w io.Writer) error {
+	for _, l := range p.Listing {
+		if _, err := fmt.Fprintf(w, "%04X  %-9X %s\n", l.Address, l.Bytes, l.Text); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Assemble assembles src using the zero Assembler for variant.
+func Assemble(src string, variant chip8.Variant) ([]byte, error) {
+	a := &Assembler{Variant: variant}
+	p, err := a.Assemble("<input>", []byte(src))
+	if err != nil {
+		return nil, err
+	}
+	return p.Bytes, nil
+}
+
+func (a *Assembler) AssembleFile(name string) (*Program, error) {
+	src, err := a.readFile(name)
+	if err != nil {
+		return nil, err
+	}
+	return a.Assemble(name, src)
+}
+
+// Assemble assembles src, naming it file in errors and listings.
+func (a *Assembler) Assemble(file string, src []byte) (*Program, error) {
+	s := &assembler{
+		Assembler: a,
+		symbols:   map[string]*symbol{},
+		macros:    map[string]*macro{},
+	}
+	if err := s.read(file, src, 0); err != nil {
+		return nil, err
+	}
+	if s.macro != nil {
+		return nil, errorf(s.macro.pos, "macro %s has no endm", s.macro.name)
+	}
+	if err := s.layout(); err != nil {
+		return nil, err
+	}
+	return s.emit()
+}
+
+func (a *Assembler) readFile(name string) ([]byte, error) {
+	if a.ReadFile != nil {
+		return a.ReadFile(name)
+	}
+	return ioutil.ReadFile(name)
+}
+
+// assembler is the state of one assembly.
+type assembler struct {
+	*Assembler
+	stmts   []*stmt
+	symbols map[string]*symbol
+	macros  map[string]*macro
+	// macro is the macro being defined, if any.
+	macro *macro
+}
+
+// stmt is a source line after includes and macros are expanded.
+type stmt struct {
+	pos   Pos
+	text  string
+	label *token
+	// op is the mnemonic or directive, absent on lines with only a
+	// label.
+	op   *token
+	args [][]token
+
+	addr uint16
+	size int
+	form *form
+}
+
+type symbol struct {
+	pos   Pos
+	value int64
+	// expr is the definition of a constant still to be evaluated.
+	expr      []token
+	resolving bool
+}
+
+type macro struct {
+	pos    Pos
+	name   string
+	params []string
+	body   []macroLine
+}
+
+type macroLine struct {
+	pos  Pos
+	text string
+	toks []token
+}
+
+// read splits src into statements, expanding includes and macros.
+func (a *assembler) read(file string, src []byte, depth int) error {
+	for i, text := range strings.Split(string(src), "\n") {
+		text = strings.TrimRight(text, "\r")
+		pos := Pos{File: file, Line: i + 1, Col: 1}
+		toks, err := lex(pos, text)
+		if err != nil {
+			return err
+		}
+		if err := a.line(pos, text, toks, depth); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (a *assembler) line(pos Pos, text string, toks []token, depth int) error {
+	if a.macro != nil {
+		if len(toks) == 1 && strings.EqualFold(toks[0].text, "endm") {
+			a.macros[strings.ToUpper(a.macro.name)] = a.macro
+			a.macro = nil
+			return nil
+		}
+		a.macro.body = append(a.macro.body, macroLine{pos, text, toks})
+		return nil
+	}
+	if len(toks) == 0 {
+		return nil
+	}
+
+	s := &stmt{pos: pos, text: strings.TrimSpace(text)}
+	if len(toks) >= 2 && toks[0].kind == tokIdent && toks[1].is(":") {
+		s.label = &toks[0]
+		toks = toks[2:]
+	}
+	if len(toks) == 0 {
+		a.stmts = append(a.stmts, s)
+		return nil
+	}
+	if toks[0].kind != tokIdent {
+		return a.errorf(pos, toks[0], "expected instruction, got %q", toks[0].text)
+	}
+	if len(toks) >= 2 && (toks[1].is("=") || strings.EqualFold(toks[1].text, "equ")) {
+		if s.label != nil {
+			return a.errorf(pos, toks[0], "constant cannot have a label")
+		}
+		return a.define(pos, toks[0], &symbol{pos: at(pos, toks[0]), expr: toks[2:]})
+	}
+	s.op = &toks[0]
+	args, err := a.split(pos, toks[1:])
+	if err != nil {
+		return err
+	}
+	s.args = args
+
+	switch name := strings.ToUpper(s.op.text); {
+	case name == "MACRO":
+		return a.startMacro(pos, s)
+	case name == "ENDM":
+		return a.errorf(pos, *s.op, "endm outside a macro")
+	case name == "INCLUDE":
+		if len(args) != 1 || len(args[0]) != 1 || args[0][0].kind != tokString {
+			return a.errorf(pos, *s.op, "include takes a quoted file name")
+		}
+		if depth >= maxDepth {
+			return a.errorf(pos, *s.op, "includes nested too deeply")
+		}
+		if s.label != nil {
+			a.stmts = append(a.stmts, &stmt{pos: pos, text: s.label.text + ":", label: s.label})
+		}
+		name := args[0][0].str
+		if !filepath.IsAbs(name) {
+			name = filepath.Join(filepath.Dir(pos.File), name)
+		}
+		src, err := a.readFile(name)
+		if err != nil {
+			return a.errorf(pos, args[0][0], "%v", err)
+		}
+		return a.read(name, src, depth+1)
+	case a.macros[name] != nil:
+		if depth >= maxDepth {
+			return a.errorf(pos, *s.op, "macros nested too deeply")
+		}
+		if s.label != nil {
+			a.stmts = append(a.stmts, &stmt{pos: pos, text: s.label.text + ":", label: s.label})
+		}
+		return a.expand(pos, a.macros[name], s, depth)
+	}
+	a.stmts = append(a.stmts, s)
+	return nil
+}
+
+// split separates operands at the commas outside parentheses.
+func (a *assembler) split(pos Pos, toks []token) ([][]token, error) {
+	var args [][]token
+	depth, start := 0, 0
+	for i, t := range toks {
+		switch {
+		case t.is("("):
+			depth++
+		case t.is(")"):
+			depth--
+		case t.is(",") && depth == 0:
+			if i == start {
+				return nil, a.errorf(pos, t, "missing operand")
+			}
+			args = append(args, toks[start:i])
+			start = i + 1
+		}
+	}
+	if start < len(toks) {
+		args = append(args, toks[start:])
+	} else if len(toks) > 0 {
+		return nil, a.errorf(pos, toks[len(toks)-1], "missing operand")
+	}
+	return args, nil
+}
+
+func (a *assembler) startMacro(pos Pos, s *stmt) error {
+	if len(s.args) == 0 || len(s.args[0]) == 0 || s.args[0][0].kind != tokIdent {
+		return a.errorf(pos, *s.op, "macro needs a name")
+	}
+	name := s.args[0][0]
+	m := &macro{pos: at(pos, name), name: name.text}
+	params := append([][]token{s.args[0][1:]}, s.args[1:]...)
+	if len(params[0]) == 0 {
+		params = params[1:]
+	}
+	for _, p := range params {
+		if len(p) != 1 || p[0].kind != tokIdent {
+			return a.errorf(pos, p[0], "macro parameters must be names")
+		}
+		m.params = append(m.params, p[0].text)
+	}
+	if _, ok := forms[strings.ToUpper(m.name)]; ok || isDirective(m.name) {
+		return a.errorf(pos, name, "macro name %s is an instruction", m.name)
+	}
+	a.macro = m
+	return nil
+}
+
+// expand replays the body of m with every parameter replaced by the
+// tokens of its argument.
+func (a *assembler) expand(pos Pos, m *macro, s *stmt, depth int) error {
+	if len(s.args) != len(m.params) {
+		return a.errorf(pos, *s.op, "macro %s takes %d arguments, got %d", m.name, len(m.params), len(s.args))
+	}
+	for _, l := range m.body {
+		var toks []token
+		for _, t := range l.toks {
+			arg := a.param(m, s.args, t)
+			if arg == nil {
+				toks = append(toks, t)
+				continue
+			}
+			for _, at := range arg {
+				at.col = t.col
+				toks = append(toks, at)
+			}
+		}
+		text := l.text
+		for i := len(l.toks) - 1; i >= 0; i-- {
+			t := l.toks[i]
+			if arg := a.param(m, s.args, t); arg != nil {
+				text = text[:t.col-1] + join(arg) + text[t.col-1+len(t.text):]
+			}
+		}
+		if err := a.line(l.pos, text, toks, depth+1); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func join(toks []token) string {
+	var parts []string
+	for _, t := range toks {
+		parts = append(parts, t.text)
+	}
+	return strings.Join(parts, " ")
+}
+
+func (a *assembler) param(m *macro, args [][]token, t token) []token {
+	if t.kind != tokIdent {
+		return nil
+	}
+	for i, p := range m.params {
+		if p == t.text {
+			return args[i]
+		}
+	}
+	return nil
+}
+
+func isDirective(name string) bool {
+	switch strings.ToUpper(name) {
+	case "ORG", "DB", "DW", "INCLUDE", "MACRO", "ENDM":
+		return true
+	}
+	return false
+}
+
+func (a *assembler) define(pos Pos, name token, sym *symbol) error {
+	if isReserved(name.text) || isDirective(name.text) {
+		return a.errorf(pos, name, "%s is a reserved word", name.text)
+	}
+	if prev, ok := a.symbols[name.text]; ok {
+		return a.errorf(pos, name, "%s redefined, previously defined at %v", name.text, prev.pos)
+	}
+	a.symbols[name.text] = sym
+	return nil
+}
+
+// resolve returns the value of a symbol, evaluating constants on first
+// use so they may refer to labels defined later.
+func (a *assembler) resolve(pos Pos, name string) (int64, error) {
+	sym, ok := a.symbols[name]
+	if !ok {
+		return 0, errorf(pos, "undefined symbol %s", name)
+	}
+	if sym.expr != nil {
+		if sym.resolving {
+			return 0, errorf(pos, "constant %s refers to itself", name)
+		}
+		sym.resolving = true
+		v, err := a.eval(sym.pos, sym.expr)
+		sym.resolving = false
+		if err != nil {
+			return 0, err
+		}
+		sym.value, sym.expr = v, nil
+	}
+	return sym.value, nil
+}
+
+// layout assigns addresses to statements and defines labels.
+func (a *assembler) layout() error {
+	limit := a.Variant.MemorySize()
+	addr := chip8.RomStart
+	for _, s := range a.stmts {
+		s.addr = uint16(addr)
+		if s.label != nil {
+			if err := a.define(s.pos, *s.label, &symbol{pos: at(s.pos, *s.label), value: int64(addr)}); err != nil {
+				return err
+			}
+		}
+		if s.op == nil {
+			continue
+		}
+		switch strings.ToUpper(s.op.text) {
+		case "ORG":
+			if len(s.args) != 1 {
+				return a.errorf(s.pos, *s.op, "org takes one address")
+			}
+			v, err := a.eval(at(s.pos, s.args[0][0]), s.args[0])
+			if err != nil {
+				return err
+			}
+			if v < int64(addr) || v > int64(limit) {
+				return a.errorf(s.pos, s.args[0][0], "org %#x is before %#x or past memory", v, addr)
+			}
+			addr = int(v)
+			continue
+		case "DB", "DW":
+			if len(s.args) == 0 {
+				return a.errorf(s.pos, *s.op, "%s needs at least one value", strings.ToLower(s.op.text))
+			}
+		}
+		switch strings.ToUpper(s.op.text) {
+		case "DB":
+			for _, arg := range s.args {
+				if len(arg) == 1 && arg[0].kind == tokString {
+					s.size += len(arg[0].str)
+				} else {
+					s.size++
+				}
+			}
+		case "DW":
+			s.size = 2 * len(s.args)
+		default:
+			f, err := a.match(s)
+			if err != nil {
+				return err
+			}
+			s.form = f
+			s.size = f.op.Len()
+		}
+		addr += s.size
+		if addr > limit {
+			return fmt.Errorf("%v: %w", s.pos, ErrProgramTooLarge)
+		}
+	}
+	return nil
+}
+
+// match finds the form of an instruction statement that the variant
+// can execute.
+func (a *assembler) match(s *stmt) (*form, error) {
+	fs, ok := forms[strings.ToUpper(s.op.text)]
+	if !ok {
+		return nil, a.errorf(s.pos, *s.op, "unknown instruction %s", s.op.text)
+	}
+	var unsupported *form
+	for i := range fs {
+		f := &fs[i]
+		if !f.match(s.args) {
+			continue
+		}
+		if a.Variant >= f.op.Variant {
+			return f, nil
+		}
+		unsupported = f
+	}
+	if unsupported != nil {
+		return nil, a.errorf(s.pos, *s.op, "%s needs the %v variant", s.text, unsupported.op.Variant)
+	}
+	return nil, a.errorf(s.pos, *s.op, "invalid operands for %s", strings.ToUpper(s.op.text))
+}
+
+// emit encodes every statement now that all labels are known.
+func (a *assembler) emit() (*Program, error) {
+	var out bytes.Buffer
+	p := &Program{Symbols: map[string]int64{}}
+	for _, s := range a.stmts {
+		var bs []byte
+		var err error
+		if s.op != nil {
+			switch strings.ToUpper(s.op.text) {
+			case "DB":
+				bs, err = a.data(s, 1)
+			case "DW":
+				bs, err = a.data(s, 2)
+			case "ORG":
+			default:
+				bs, err = a.encode(s, s.form)
+			}
+		}
+		if err != nil {
+			return nil, err
+		}
+		if len(bs) > 0 {
+			for out.Len() < int(s.addr)-chip8.RomStart {
+				out.WriteByte(0)
+			}
+			out.Write(bs)
+		}
+		p.Listing = append(p.Listing, Line{Pos: s.pos, Address: s.addr, Bytes: bs, Text: s.text})
+	}
+	for name := range a.symbols {
+		v, err := a.resolve(a.symbols[name].pos, name)
+		if err != nil {
+			return nil, err
+		}
+		p.Symbols[name] = v
+	}
+	p.Bytes = out.Bytes()
+	return p, nil
+}
+
+func (a *assembler) data(s *stmt, width int) ([]byte, error) {
+	var bs []byte
+	for _, arg := range s.args {
+		if width == 1 && len(arg) == 1 && arg[0].kind == tokString {
+			bs = append(bs, arg[0].str...)
+			continue
+		}
+		pos := at(s.pos, arg[0])
+		v, err := a.eval(pos, arg)
+		if err != nil {
+			return nil, err
+		}
+		min, max := int64(-1)<<uint(8*width-1), int64(1)<<uint(8*width)-1
+		if v < min || v > max {
+			return nil, errorf(pos, "value %d does not fit in %d bytes", v, width)
+		}
+		if width == 2 {
+			bs = append(bs, byte(v>>8))
+		}
+		bs = append(bs, byte(v))
+	}
+	return bs, nil
+}
+
+func (a *assembler) errorf(pos Pos, t token, format string, args ...interface{}) error {
+	return errorf(at(pos, t), format, args...)
+}
+
+// at returns pos moved to the column of t.
+func at(pos Pos, t token) Pos {
+	pos.Col = t.col
+	return pos
+}
@@ -0,0 +1,146 @@
+package asm
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+
+	chip8 "github.com/hermesdt/go-plan8"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAssemble(t *testing.T) {
+	bs, err := Assemble(`
+		; draw a digit forever
+		start:
+			LD V0, 5        ; x
+			ld v1, 'A' - 60 ; y
+			LD F, V0
+			DRW V0, V1, 5
+			JP start
+	`, chip8.VariantChip8)
+
+	assert.Nil(t, err)
+	assert.Equal(t, []byte{0x60, 0x05, 0x61, 0x05, 0xF0, 0x29, 0xD0, 0x15, 0x12, 0x00}, bs)
+}
+
+func TestAssemble_everyForm(t *testing.T) {
+	cases := []struct {
+		src string
+		out []byte
+	}{
+		{"CLS", []byte{0x00, 0xE0}},
+		{"SYS 0x123", []byte{0x01, 0x23}},
+		{"SE V3, -1", []byte{0x33, 0xFF}},
+		{"SE V3, V4", []byte{0x53, 0x40}},
+		{"SHR VA, VB", []byte{0x8A, 0xB6}},
+		{"JP V0, 0x300", []byte{0xB3, 0x00}},
+		{"LD I, 0x300", []byte{0xA3, 0x00}},
+		{"LD [I], V5", []byte{0xF5, 0x55}},
+		{"LD V5, [I]", []byte{0xF5, 0x65}},
+		{"LD DT, V2", []byte{0xF2, 0x15}},
+		{"LD V2, K", []byte{0xF2, 0x0A}},
+		{"ADD I, V2", []byte{0xF2, 0x1E}},
+		{"LD I, LONG 0xBEEF", []byte{0xF0, 0x00, 0xBE, 0xEF}},
+		{"PLANE 3", []byte{0xF3, 0x01}},
+		{"SCD 4", []byte{0x00, 0xC4}},
+	}
+	for _, c := range cases {
+		bs, err := Assemble(c.src, chip8.VariantXOChip)
+		assert.Nil(t, err, c.src)
+		assert.Equal(t, c.out, bs, c.src)
+	}
+}
+
+func TestAssemble_data(t *testing.T) {
+	bs, err := Assemble(`
+		CLS
+		sprite: db 0b11110000, 0x90, "hi", -1
+		        dw sprite, 0x1234
+		        org 0x20E
+		        db 1
+	`, chip8.VariantChip8)
+
+	assert.Nil(t, err)
+	assert.Equal(t, []byte{
+		0x00, 0xE0,
+		0xF0, 0x90, 'h', 'i', 0xFF,
+		0x02, 0x02, 0x12, 0x34,
+		0x00, 0x00, 0x00,
+		0x01,
+	}, bs)
+}
+
+func TestAssemble_expressions(t *testing.T) {
+	bs, err := Assemble(`
+		width = height * 2
+		height equ 16 + end - end
+		db width, (width - 1) >> 1, 1 << 4 | 3, ~0 & 0x7F, 7 % 4, 10 / 3, 2 + 3 * 4
+		end:
+	`, chip8.VariantChip8)
+
+	assert.Nil(t, err)
+	assert.Equal(t, []byte{32, 15, 0x13, 0x7F, 3, 3, 14}, bs)
+}
+
+func TestAssemble_macros(t *testing.T) {
+	a := &Assembler{ReadFile: func(name string) ([]byte, error) {
+		assert.Equal(t, "lib/sprites.asm", name)
+		return []byte("macro move reg, n\n\tADD reg, n\nendm\n"), nil
+	}}
+
+	p, err := a.Assemble("main.asm", []byte(`
+		include "lib/sprites.asm"
+		loop: move V1, 2 * 2
+		move V2, 1
+		JP loop
+	`))
+
+	assert.Nil(t, err)
+	assert.Equal(t, []byte{0x71, 0x04, 0x72, 0x01, 0x12, 0x00}, p.Bytes)
+	assert.Equal(t, int64(0x200), p.Symbols["loop"])
+}
+
+func TestAssemble_errors(t *testing.T) {
+	cases := []struct {
+		src string
+		err string
+	}{
+		{"\tJP nowhere", "<input>:1:5: undefined symbol nowhere"},
+		{"CLS\n  LD V1, 0x100", "<input>:2:10: operand 256 out of range -0x80-0xff"},
+		{"  FOO V1", "<input>:1:3: unknown instruction FOO"},
+		{"  LD V1, I", "<input>:1:3: invalid operands for LD"},
+		{"  SCR", "<input>:1:3: SCR needs the schip variant"},
+		{"x: CLS\nx: CLS", "<input>:2:1: x redefined, previously defined at <input>:1:1"},
+		{"a = p\np = a\ndb a", "<input>:2:5: constant a refers to itself"},
+		{"db 1 / (2 - 2)", "<input>:1:6: division by zero"},
+		{"db 1 $ 2", "<input>:1:6: unexpected character '$'"},
+		{"macro m\nCLS", "<input>:1:7: macro m has no endm"},
+		{"I: CLS", "<input>:1:1: I is a reserved word"},
+		{"org 0x100", "<input>:1:5: org 0x100 is before 0x200 or past memory"},
+	}
+	for _, c := range cases {
+		_, err := Assemble(c.src, chip8.VariantChip8)
+		if assert.NotNil(t, err, c.src) {
+			assert.Equal(t, c.err, err.Error())
+		}
+	}
+}
+
+func TestAssemble_tooLarge(t *testing.T) {
+	_, err := Assemble(fmt.Sprintf("org %d\nCLS", chip8.MemorySize-1), chip8.VariantChip8)
+	assert.Contains(t, err.Error(), ErrProgramTooLarge.Error())
+}
+
+func TestWriteListing(t *testing.T) {
+	a := &Assembler{}
+	p, err := a.Assemble("x.asm", []byte("start:\n\tLD V0, 1 ; one\n\tJP start\n"))
+	assert.Nil(t, err)
+
+	var buf bytes.Buffer
+	assert.Nil(t, p.WriteListing(&buf))
+	assert.Equal(t, ""+
+		"0200            start:\n"+
+		"0200  6001      LD V0, 1 ; one\n"+
+		"0202  1200      JP start\n", buf.String())
+}
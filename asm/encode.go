@@ -0,0 +1,166 @@
+package asm
+
+import (
+	"strings"
+
+	chip8 "github.com/hermesdt/go-plan8"
+	"github.com/hermesdt/go-plan8/disasm"
+)
+
+// form is one way of writing an instruction, parsed from its Cowgod
+// template in the disasm package so that the assembler accepts exactly
+// what the disassembler prints.
+type form struct {
+	op       *chip8.Instruction
+	mnemonic string
+	operands []operandForm
+}
+
+// operandForm is a fixed prefix, such as "[I]" or "LONG", followed by
+// an optional placeholder.
+type operandForm struct {
+	prefix      []token
+	placeholder string
+}
+
+// forms maps upper case mnemonics to their forms in decoding table
+// order.
+var forms = map[string][]form{}
+
+func init() {
+	ops := chip8.Instructions()
+	for i := range ops {
+		op := &ops[i]
+		parts := strings.SplitN(disasm.Template(op, disasm.Cowgod), " ", 2)
+		f := form{op: op, mnemonic: parts[0]}
+		if len(parts) > 1 {
+			for _, operand := range strings.Split(parts[1], ", ") {
+				var of operandForm
+				if i := strings.LastIndexByte(operand, '%'); i >= 0 {
+					operand, of.placeholder = operand[:i], operand[i:]
+				}
+				of.prefix, _ = lex(Pos{}, operand)
+				f.operands = append(f.operands, of)
+			}
+		}
+		forms[f.mnemonic] = append(forms[f.mnemonic], f)
+	}
+}
+
+// reserved are the names that operands use as keywords and so cannot
+// be symbols.
+var reserved = map[string]bool{
+	"I": true, "DT": true, "ST": true, "K": true, "F": true, "HF": true,
+	"B": true, "R": true, "LONG": true,
+}
+
+func isReserved(name string) bool {
+	_, ok := register(name)
+	return ok || reserved[strings.ToUpper(name)]
+}
+
+func register(name string) (uint16, bool) {
+	if len(name) != 2 || name[0] != 'V' && name[0] != 'v' {
+		return 0, false
+	}
+	v, err := parseNumber("0x" + name[1:])
+	if err != nil {
+		return 0, false
+	}
+	return uint16(v), true
+}
+
+func (f *form) match(args [][]token) bool {
+	if len(args) != len(f.operands) {
+		return false
+	}
+	for i, of := range f.operands {
+		if !of.match(args[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+func (of operandForm) match(toks []token) bool {
+	if len(toks) < len(of.prefix) {
+		return false
+	}
+	for i, p := range of.prefix {
+		if toks[i].kind != p.kind || !strings.EqualFold(toks[i].text, p.text) {
+			return false
+		}
+	}
+	rest := toks[len(of.prefix):]
+	switch of.placeholder {
+	case "":
+		return len(rest) == 0
+	case "%X", "%Y":
+		if len(rest) != 1 || rest[0].kind != tokIdent {
+			return false
+		}
+		_, ok := register(rest[0].text)
+		return ok
+	}
+	return len(rest) > 0 && !rest[0].is("[") && !(rest[0].kind == tokIdent && isReserved(rest[0].text))
+}
+
+// operandRange is the inclusive range each placeholder accepts. Bytes
+// may also be written as negative numbers.
+var operandRange = map[string][2]int64{
+	"%N":   {0, 0xF},
+	"%P":   {0, 0xF},
+	"%NN":  {-0x80, 0xFF},
+	"%NNN": {0, 0xFFF},
+	"%L":   {0, 0xFFFF},
+}
+
+// encode fills in the operands of an instruction matched by f.
+func (a *assembler) encode(s *stmt, f *form) ([]byte, error) {
+	w := f.op.Pattern
+	var long uint16
+	for i, of := range f.operands {
+		toks := s.args[i][len(of.prefix):]
+		pos := s.pos
+		if len(toks) > 0 {
+			pos.Col = toks[0].col
+		}
+		switch of.placeholder {
+		case "":
+			continue
+		case "%X", "%Y":
+			r, _ := register(toks[0].text)
+			if of.placeholder == "%X" {
+				w |= r << 8
+			} else {
+				w |= r << 4
+			}
+			continue
+		}
+		v, err := a.eval(pos, toks)
+		if err != nil {
+			return nil, err
+		}
+		limits := operandRange[of.placeholder]
+		if v < limits[0] || v > limits[1] {
+			return nil, errorf(pos, "operand %d out of range %#x-%#x", v, limits[0], limits[1])
+		}
+		switch of.placeholder {
+		case "%N":
+			w |= uint16(v)
+		case "%P":
+			w |= uint16(v) << 8
+		case "%NN":
+			w |= uint16(v) & 0xFF
+		case "%NNN":
+			w |= uint16(v)
+		case "%L":
+			long = uint16(v)
+		}
+	}
+	bs := []byte{byte(w >> 8), byte(w)}
+	if f.op.Len() == 4 {
+		bs = append(bs, byte(long>>8), byte(long))
+	}
+	return bs, nil
+}
@@ -0,0 +1,132 @@
+package asm
+
+// Binary operators by precedence, loosest first, as in C.
+var precedence = [][]string{
+	{"|"},
+	{"^"},
+	{"&"},
+	{"<<", ">>"},
+	{"+", "-"},
+	{"*", "/", "%"},
+}
+
+// exprParser evaluates an expression over the assembler's symbols.
+type exprParser struct {
+	a    *assembler
+	pos  Pos
+	toks []token
+}
+
+// eval evaluates toks as a single expression.
+func (a *assembler) eval(pos Pos, toks []token) (int64, error) {
+	p := &exprParser{a: a, pos: pos, toks: toks}
+	v, err := p.binary(0)
+	if err != nil {
+		return 0, err
+	}
+	if len(p.toks) > 0 {
+		return 0, p.errorf(p.toks[0], "unexpected %q in expression", p.toks[0].text)
+	}
+	return v, nil
+}
+
+func (p *exprParser) errorf(t token, format string, args ...interface{}) error {
+	pos := p.pos
+	pos.Col = t.col
+	return errorf(pos, format, args...)
+}
+
+func (p *exprParser) binary(level int) (int64, error) {
+	if level == len(precedence) {
+		return p.unary()
+	}
+	l, err := p.binary(level + 1)
+	if err != nil {
+		return 0, err
+	}
+	for len(p.toks) > 0 && p.toks[0].kind == tokPunct && contains(precedence[level], p.toks[0].text) {
+		op := p.toks[0]
+		p.toks = p.toks[1:]
+		r, err := p.binary(level + 1)
+		if err != nil {
+			return 0, err
+		}
+		switch op.text {
+		case "|":
+			l |= r
+		case "^":
+			l ^= r
+		case "&":
+			l &= r
+		case "<<":
+			l <<= uint64(r)
+		case ">>":
+			l >>= uint64(r)
+		case "+":
+			l += r
+		case "-":
+			l -= r
+		case "*":
+			l *= r
+		case "/", "%":
+			if r == 0 {
+				return 0, p.errorf(op, "division by zero")
+			}
+			if op.text == "/" {
+				l /= r
+			} else {
+				l %= r
+			}
+		}
+	}
+	return l, nil
+}
+
+func (p *exprParser) unary() (int64, error) {
+	if len(p.toks) == 0 {
+		pos := p.pos
+		return 0, errorf(pos, "missing expression")
+	}
+	t := p.toks[0]
+	p.toks = p.toks[1:]
+	switch {
+	case t.is("-"), t.is("~"), t.is("+"):
+		v, err := p.unary()
+		if err != nil {
+			return 0, err
+		}
+		switch t.text {
+		case "-":
+			return -v, nil
+		case "~":
+			return ^v, nil
+		}
+		return v, nil
+	case t.is("("):
+		v, err := p.binary(0)
+		if err != nil {
+			return 0, err
+		}
+		if len(p.toks) == 0 || !p.toks[0].is(")") {
+			return 0, p.errorf(t, "unbalanced parenthesis")
+		}
+		p.toks = p.toks[1:]
+		return v, nil
+	case t.kind == tokNumber:
+		return t.val, nil
+	case t.kind == tokIdent:
+		pos := p.pos
+		pos.Col = t.col
+		return p.a.resolve(pos, t.text)
+	}
+	return 0, p.errorf(t, "unexpected %q in expression", t.text)
+}
+
+func contains(list []string, s string) bool {
+	for _, e := range list {
+		if e == s {
+			return true
+		}
+	}
+	return false
+}
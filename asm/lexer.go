@@ -0,0 +1,122 @@
+package asm
+
+import (
+	"strconv"
+	"strings"
+)
+
+type tokenKind int
+
+const (
+	tokIdent tokenKind = iota
+	tokNumber
+	tokString
+	tokPunct
+)
+
+type token struct {
+	kind tokenKind
+	// text is the token as written in the source.
+	text string
+	// val holds the value of numbers and character literals, str the
+	// unquoted contents of strings.
+	val int64
+	str string
+	col int
+}
+
+func (t token) is(punct string) bool {
+	return t.kind == tokPunct && t.text == punct
+}
+
+// lex splits one source line into tokens, stopping at a ';' comment.
+// Columns are 1-based.
+func lex(pos Pos, text string) ([]token, error) {
+	var toks []token
+	for i := 0; i < len(text); {
+		c := text[i]
+		start := i
+		pos.Col = i + 1
+		switch {
+		case c == ';':
+			return toks, nil
+		case c == ' ' || c == '\t' || c == '\r':
+			i++
+			continue
+		case isIdentStart(c):
+			for i < len(text) && isIdentPart(text[i]) {
+				i++
+			}
+			toks = append(toks, token{kind: tokIdent, text: text[start:i], col: pos.Col})
+		case isDigit(c):
+			for i < len(text) && isIdentPart(text[i]) {
+				i++
+			}
+			v, err := parseNumber(text[start:i])
+			if err != nil {
+				return nil, errorf(pos, "invalid number %q", text[start:i])
+			}
+			toks = append(toks, token{kind: tokNumber, text: text[start:i], val: v, col: pos.Col})
+		case c == '"' || c == '\'':
+			i++
+			for i < len(text) && text[i] != c {
+				if text[i] == '\\' {
+					i++
+				}
+				i++
+			}
+			if i >= len(text) {
+				return nil, errorf(pos, "unterminated literal")
+			}
+			i++
+			inner := text[start+1 : i-1]
+			if c == '\'' {
+				inner = strings.NewReplacer(`\'`, `'`, `"`, `\"`).Replace(inner)
+			}
+			s, err := strconv.Unquote(`"` + inner + `"`)
+			if err != nil {
+				return nil, errorf(pos, "invalid literal %s", text[start:i])
+			}
+			if c == '"' {
+				toks = append(toks, token{kind: tokString, text: text[start:i], str: s, col: pos.Col})
+				break
+			}
+			if len(s) != 1 {
+				return nil, errorf(pos, "character literal %s must hold one byte", text[start:i])
+			}
+			toks = append(toks, token{kind: tokNumber, text: text[start:i], val: int64(s[0]), col: pos.Col})
+		case strings.HasPrefix(text[i:], "<<") || strings.HasPrefix(text[i:], ">>"):
+			i += 2
+			toks = append(toks, token{kind: tokPunct, text: text[start:i], col: pos.Col})
+		case strings.IndexByte(",:()[]+-*/%&|^~=", c) >= 0:
+			i++
+			toks = append(toks, token{kind: tokPunct, text: text[start:i], col: pos.Col})
+		default:
+			return nil, errorf(pos, "unexpected character %q", c)
+		}
+	}
+	return toks, nil
+}
+
+func parseNumber(s string) (int64, error) {
+	base := 10
+	switch {
+	case strings.HasPrefix(s, "0x") || strings.HasPrefix(s, "0X"):
+		base, s = 16, s[2:]
+	case strings.HasPrefix(s, "0b") || strings.HasPrefix(s, "0B"):
+		base, s = 2, s[2:]
+	}
+	return strconv.ParseInt(strings.Replace(s, "_", "", -1), base, 64)
+}
+
+func isIdentStart(c byte) bool {
+	return c == '_' || c == '.' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+func isIdentPart(c byte) bool {
+	return isIdentStart(c) || isDigit(c)
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
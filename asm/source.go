@@ -0,0 +1,63 @@
+package asm
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	chip8 "github.com/hermesdt/go-plan8"
+	"github.com/hermesdt/go-plan8/disasm"
+)
+
+// branches are the instructions whose address operand Source replaces
+// with a label.
+var branches = map[string]bool{
+	"Jump": true, "CallSub": true, "JumpPlusV0": true, "SetI": true,
+}
+
+// Source returns assembly source for rom that assembles back to the
+// same bytes. Code is found as disasm.Disassemble finds it and branch
+// targets get labels. Instructions that would not assemble to the bytes
+// they were decoded from, like 5XY1, are written as data.
+func Source(rom []byte, variant chip8.Variant) []byte {
+	ins := disasm.Disassemble(rom, chip8.RomStart, variant)
+	starts := map[uint16]bool{}
+	for _, in := range ins {
+		starts[in.Address] = true
+	}
+	labels := map[uint16]bool{}
+	for _, in := range ins {
+		if !in.IsData() && branches[in.Op.Name] && starts[in.Word&0xFFF] {
+			labels[in.Word&0xFFF] = true
+		}
+	}
+
+	var b bytes.Buffer
+	for _, in := range ins {
+		if labels[in.Address] {
+			fmt.Fprintf(&b, "%s:\n", label(in.Address))
+		}
+		text := in.Format(disasm.Cowgod)
+		switch {
+		case in.IsData():
+		case !reassembles(text, in.Bytes, variant):
+			text = disasm.Instruction{Bytes: in.Bytes}.Format(disasm.Cowgod)
+		case branches[in.Op.Name] && labels[in.Word&0xFFF]:
+			n := len(in.Operands) - 1
+			in.Operands[n] = label(in.Word & 0xFFF)
+			text = in.Mnemonic + " " + strings.Join(in.Operands, ", ")
+		}
+		fmt.Fprintf(&b, "\t%s\n", text)
+	}
+	return b.Bytes()
+}
+
+func label(addr uint16) string {
+	return fmt.Sprintf("L%03X", addr)
+}
+
+// reassembles reports whether text assembles to want on its own.
+func reassembles(text string, want []byte, variant chip8.Variant) bool {
+	got, err := Assemble(text, variant)
+	return err == nil && bytes.Equal(got, want)
+}
@@ -0,0 +1,44 @@
+package asm
+
+import (
+	"math/rand"
+	"testing"
+
+	chip8 "github.com/hermesdt/go-plan8"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSource(t *testing.T) {
+	rom := []byte{
+		0x22, 0x06, // CALL 206
+		0x12, 0x02, // JP 202
+		0x51, 0x21, // unreachable
+		0x51, 0x21, // SE V1, V2 with a nonzero low nibble
+		0x00, 0xEE,
+	}
+
+	assert.Equal(t, ""+
+		"\tCALL L206\n"+
+		"L202:\n"+
+		"\tJP L202\n"+
+		"\tDB 0x51, 0x21\n"+
+		"L206:\n"+
+		"\tDB 0x51, 0x21\n"+
+		"\tRET\n", string(Source(rom, chip8.VariantChip8)))
+}
+
+func TestSource_reassembles(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	for _, v := range []chip8.Variant{chip8.VariantChip8, chip8.VariantSChip, chip8.VariantXOChip} {
+		for i := 0; i < 50; i++ {
+			rom := make([]byte, r.Intn(512))
+			r.Read(rom)
+
+			src := Source(rom, v)
+			bs, err := Assemble(string(src), v)
+
+			assert.Nil(t, err)
+			assert.Equal(t, rom, bs)
+		}
+	}
+}
@@ -0,0 +1,13 @@
+package chip8
+
+import "math"
+
+// DefaultPitch is the XO-CHIP pitch register value that plays the audio
+// pattern at 4000 samples per second.
+const DefaultPitch = 64
+
+// PlaybackRate returns the audio pattern sample rate, in Hz, selected
+// by the FX3A pitch register.
+func (c *Chip8) PlaybackRate() float64 {
+	return 4000 * math.Pow(2, (float64(c.Pitch)-64)/48)
+}
@@ -0,0 +1,76 @@
+package chip8
+
+// AccessPolicy decides what happens when an instruction reaches outside
+// memory or the stack.
+type AccessPolicy int
+
+const (
+	// AccessFault stops execution with ErrMemoryOutOfBounds,
+	// ErrStackOverflow or ErrStackUnderflow.
+	AccessFault AccessPolicy = iota
+	// AccessWrap wraps addresses around the end of memory and the stack
+	// pointer around the 16 stack slots, as some interpreters do.
+	AccessWrap
+	// AccessStrict faults like AccessFault, and additionally refuses
+	// writes to and execution from the interpreter area below RomStart.
+	AccessStrict
+)
+
+func (p AccessPolicy) String() string {
+	switch p {
+	case AccessFault:
+		return "fault"
+	case AccessWrap:
+		return "wrap"
+	case AccessStrict:
+		return "strict"
+	}
+	return "unknown"
+}
+
+// checkSpan validates the n bytes starting at addr before an instruction
+// touches any of them, so a faulting instruction leaves no partial
+// writes behind.
+func (c *Chip8) checkSpan(addr, n int, write bool) error {
+	if c.Policy == AccessWrap {
+		return nil
+	}
+	if addr < 0 || addr+n > c.Variant.MemorySize() {
+		return ErrMemoryOutOfBounds
+	}
+	if write && c.Policy == AccessStrict && addr < RomStart {
+		return ErrMemoryOutOfBounds
+	}
+	return nil
+}
+
+// mem returns the memory index for addr once checkSpan has accepted it.
+func (c *Chip8) mem(addr int) int {
+	return addr % c.Variant.MemorySize()
+}
+
+func (c *Chip8) push(pc uint16) error {
+	if int(c.SP) >= len(c.Stack) {
+		if c.Policy != AccessWrap {
+			return ErrStackOverflow
+		}
+		c.SP = 0
+	}
+	c.Stack[c.SP] = pc
+	c.SP++
+	return nil
+}
+
+func (c *Chip8) pop() (uint16, error) {
+	if int(c.SP) > len(c.Stack) {
+		return 0, ErrStackOverflow
+	}
+	if c.SP == 0 {
+		if c.Policy != AccessWrap {
+			return 0, ErrStackUnderflow
+		}
+		c.SP = uint16(len(c.Stack))
+	}
+	c.SP--
+	return c.Stack[c.SP], nil
+}
@@ -0,0 +1,161 @@
+package chip8
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCallSubReturn(t *testing.T) {
+	c := NewChip8(VariantChip8)
+	c.LoadRomBytes([]byte{0x22, 0x04, 0x00, 0x00, 0x00, 0xEE})
+
+	assert.Nil(t, c.Step())
+	assert.Equal(t, uint16(0x204), c.PC)
+	assert.Nil(t, c.Step())
+	assert.Equal(t, uint16(0x202), c.PC)
+	assert.Equal(t, uint16(0), c.SP)
+}
+
+func TestPush_overflow(t *testing.T) {
+	for _, policy := range []AccessPolicy{AccessFault, AccessStrict} {
+		c := &Chip8{Policy: policy, SP: 16}
+		assert.Equal(t, ErrStackOverflow, c.push(0x300), policy.String())
+	}
+
+	c := &Chip8{Policy: AccessWrap, SP: 16}
+	assert.Nil(t, c.push(0x300))
+	assert.Equal(t, uint16(1), c.SP)
+	assert.Equal(t, uint16(0x300), c.Stack[0])
+}
+
+func TestPop_underflow(t *testing.T) {
+	for _, policy := range []AccessPolicy{AccessFault, AccessStrict} {
+		c := &Chip8{Policy: policy}
+		_, err := c.pop()
+		assert.Equal(t, ErrStackUnderflow, err, policy.String())
+	}
+
+	c := &Chip8{Policy: AccessWrap}
+	c.Stack[15] = 0x400
+	pc, err := c.pop()
+	assert.Nil(t, err)
+	assert.Equal(t, uint16(0x400), pc)
+	assert.Equal(t, uint16(15), c.SP)
+}
+
+func TestStack_spPastStack(t *testing.T) {
+	for _, policy := range []AccessPolicy{AccessFault, AccessStrict, AccessWrap} {
+		c := &Chip8{Policy: policy, SP: 0x40}
+		_, err := c.pop()
+		assert.Equal(t, ErrStackOverflow, err, policy.String())
+		assert.Equal(t, uint16(0x40), c.SP)
+	}
+
+	c := &Chip8{SP: 0x40, PC: 0x200}
+	c.Memory[0x200], c.Memory[0x201] = 0x00, 0xEE
+	assertExecError(t, c.Step(), ErrStackOverflow, 0x200, 0x00EE)
+	c.Memory[0x200], c.Memory[0x201] = 0x22, 0x04
+	assertExecError(t, c.Step(), ErrStackOverflow, 0x200, 0x2204)
+}
+
+func TestSetBCD_bounds(t *testing.T) {
+	o := Opcode{Value: 0xF033, Chip8: &Chip8{I: 0xFFE, PC: 0x200}}
+	o.Chip8.V[0] = 123
+
+	assertExecError(t, o.Execute(), ErrMemoryOutOfBounds, 0x200, 0xF033)
+	assert.Equal(t, uint8(0), o.Chip8.Memory[0xFFE])
+	assert.Equal(t, uint16(0x200), o.Chip8.PC)
+
+	o.Chip8.Policy = AccessWrap
+	assert.Nil(t, o.Execute())
+	assert.Equal(t, uint8(1), o.Chip8.Memory[0xFFE])
+	assert.Equal(t, uint8(2), o.Chip8.Memory[0xFFF])
+	assert.Equal(t, uint8(3), o.Chip8.Memory[0x000])
+}
+
+func TestSetBCD_strict(t *testing.T) {
+	o := Opcode{Value: 0xF033, Chip8: &Chip8{I: 0x100, PC: 0x200, Policy: AccessStrict}}
+
+	assertExecError(t, o.Execute(), ErrMemoryOutOfBounds, 0x200, 0xF033)
+
+	o.Chip8.Policy = AccessFault
+	assert.Nil(t, o.Execute())
+}
+
+func TestRegDump_bounds(t *testing.T) {
+	o := Opcode{Value: 0xF355, Chip8: &Chip8{I: 0xFFD, PC: 0x200}}
+	o.Chip8.V = [16]uint8{1, 2, 3, 4}
+
+	assertExecError(t, o.Execute(), ErrMemoryOutOfBounds, 0x200, 0xF355)
+	assert.Equal(t, uint8(0), o.Chip8.Memory[0xFFD])
+
+	o.Chip8.Policy = AccessWrap
+	assert.Nil(t, o.Execute())
+	assert.Equal(t, uint8(3), o.Chip8.Memory[0xFFF])
+	assert.Equal(t, uint8(4), o.Chip8.Memory[0x000])
+}
+
+func TestRegLoad_bounds(t *testing.T) {
+	o := Opcode{Value: 0xF165, Chip8: &Chip8{I: 0xFFF, PC: 0x200}}
+	o.Chip8.Memory[0xFFF] = 7
+	o.Chip8.Memory[0x000] = 8
+
+	assertExecError(t, o.Execute(), ErrMemoryOutOfBounds, 0x200, 0xF165)
+	assert.Equal(t, uint8(0), o.Chip8.V[0])
+
+	o.Chip8.Policy = AccessWrap
+	assert.Nil(t, o.Execute())
+	assert.Equal(t, uint8(7), o.Chip8.V[0])
+	assert.Equal(t, uint8(8), o.Chip8.V[1])
+}
+
+func TestDraw_bounds(t *testing.T) {
+	o := Opcode{Value: 0xD012, Chip8: &Chip8{I: 0xFFF, PC: 0x200}}
+	o.Chip8.Memory[0xFFF] = 0x80
+	o.Chip8.Memory[0x000] = 0x80
+
+	assertExecError(t, o.Execute(), ErrMemoryOutOfBounds, 0x200, 0xD012)
+
+	o.Chip8.Policy = AccessWrap
+	assert.Nil(t, o.Execute())
+	assert.True(t, o.Chip8.Screen.Get(0, 0))
+	assert.True(t, o.Chip8.Screen.Get(1, 0))
+}
+
+func TestDraw_wrapsRows(t *testing.T) {
+	o := Opcode{Value: 0xD012, Chip8: &Chip8{I: 0x300}}
+	o.Chip8.V[1] = uint8(ScreenHeight - 1)
+	o.Chip8.Memory[0x300] = 0x80
+	o.Chip8.Memory[0x301] = 0x80
+
+	assert.Nil(t, o.Execute())
+	assert.True(t, o.Chip8.Screen.Get(ScreenHeight-1, 0))
+	assert.True(t, o.Chip8.Screen.Get(0, 0))
+}
+
+func TestStep_fetchBounds(t *testing.T) {
+	c := NewChip8(VariantChip8)
+	c.PC = 0x0FFF
+	c.Memory[0xFFF] = 0x12
+	c.Memory[0x000] = 0x00
+
+	assertExecError(t, c.Step(), ErrMemoryOutOfBounds, 0x0FFF, 0)
+
+	c.Policy = AccessWrap
+	assert.Nil(t, c.Step())
+	assert.Equal(t, uint16(0x200), c.PC)
+
+	c.Policy = AccessStrict
+	c.PC = 0x0100
+	assertExecError(t, c.Step(), ErrMemoryOutOfBounds, 0x0100, 0)
+}
+
+func TestSetISprite_masksVX(t *testing.T) {
+	o := Opcode{Value: 0xF029, Chip8: &Chip8{}}
+	o.Chip8.V[0] = 0x3A
+
+	o.Execute()
+
+	assert.Equal(t, uint16(0x50+0xA*5), o.Chip8.I)
+}
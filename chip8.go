@@ -1,29 +1,94 @@
 package chip8
 
+import "time"
+
 type Chip8 struct {
 	Screen     Screen
-	Memory     [4096]uint8
+	Variant    Variant
+	Memory     [XOChipMemorySize]uint8
 	V          [16]uint8
 	I          uint16
 	PC         uint16
 	SP         uint16
 	Stack      [16]uint16
-	Key        [16]uint8
+	Key        Keypad
 	DelayTimer uint8
 	SoundTimer uint8
+	KeyWait    KeyWait
+	Policy     AccessPolicy
+	Quirks     Quirks
+	Flags      FlagStore
+	RomHash    RomHash
+	Audio      [16]uint8
+	Pitch      uint8
+	// Random supplies the random bytes of CXNN. NewChip8 seeds a
+	// RandomGo source from the time; set a seeded one to reproduce runs.
+	Random RandomSource
+
+	keyRelease <-chan uint8
+	vblank     vblankState
 }
 
 func (c *Chip8) FetchOpcode() Opcode {
+	pc := int(c.PC)
 	return Opcode{
-		Value: uint16(c.Memory[c.PC])<<8 | uint16(c.Memory[c.PC+1]),
+		Value: uint16(c.Memory[c.mem(pc)])<<8 | uint16(c.Memory[c.mem(pc+1)]),
 		Chip8: c,
 	}
 }
 
-func NewChip8() *Chip8 {
+// Step fetches and executes the instruction at PC.
+func (c *Chip8) Step() error {
+	if err := c.checkSpan(int(c.PC), 2, false); err != nil {
+		return &ExecError{PC: c.PC, Err: err}
+	}
+	if c.Policy == AccessStrict && c.PC < RomStart {
+		return &ExecError{PC: c.PC, Err: ErrMemoryOutOfBounds}
+	}
+	o := c.FetchOpcode()
+	return o.Execute()
+}
+
+func NewChip8(variant Variant) *Chip8 {
 	c := &Chip8{
-		PC: 0x200,
+		Variant: variant,
+		PC:      RomStart,
+		Key:     NewKeypad(),
+		Quirks:  variant.DefaultQuirks(),
+		Pitch:   DefaultPitch,
+		Random:  NewGoRandom(time.Now().UnixNano()),
 	}
 	c.LoadFontSet()
+	c.LoadHiResFontSet()
 	return c
 }
+
+// vblankState tracks a DXYN held back by Quirks.DisplayWait.
+type vblankState uint8
+
+const (
+	vblankNone vblankState = iota
+	vblankWaiting
+	vblankReady
+)
+
+// TickTimers runs once per 60 Hz frame, which is also the vertical
+// blank DXYN waits on.
+func (c *Chip8) TickTimers() {
+	if c.vblank == vblankWaiting {
+		c.vblank = vblankReady
+	}
+	if c.DelayTimer > 0 {
+		c.DelayTimer--
+	}
+	if c.SoundTimer > 0 {
+		c.SoundTimer--
+	}
+}
+
+func (c *Chip8) keypad() Keypad {
+	if c.Key == nil {
+		c.Key = NewKeypad()
+	}
+	return c.Key
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	chip8 "github.com/hermesdt/go-plan8"
+	"github.com/hermesdt/go-plan8/asm"
+)
+
+func asmCommand(args []string) error {
+	fs := flag.NewFlagSet("asm", flag.ExitOnError)
+	out := fs.String("o", "", "output ROM, defaults to the source name with a .ch8 extension")
+	listing := fs.Bool("l", false, "print a listing to stdout")
+	variant := fs.String("variant", "chip8", "instruction set: chip8, schip or xochip")
+	source := fs.Bool("source", false, "read a ROM and print source that assembles back to it")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: go-plan8 asm [flags] file")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	v, err := chip8.LookupVariant(*variant)
+	if err != nil {
+		return err
+	}
+	if *source {
+		rom, err := ioutil.ReadFile(fs.Arg(0))
+		if err != nil {
+			return err
+		}
+		_, err = os.Stdout.Write(asm.Source(rom, v))
+		return err
+	}
+
+	a := &asm.Assembler{Variant: v}
+	p, err := a.AssembleFile(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	if *listing {
+		if err := p.WriteListing(os.Stdout); err != nil {
+			return err
+		}
+	}
+	if *out == "" {
+		*out = strings.TrimSuffix(fs.Arg(0), ".asm") + ".ch8"
+	}
+	return ioutil.WriteFile(*out, p.Bytes, 0644)
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"os"
+
+	chip8 "github.com/hermesdt/go-plan8"
+	"github.com/hermesdt/go-plan8/dap"
+)
+
+func dapCommand(args []string) error {
+	fs := flag.NewFlagSet("dap", flag.ExitOnError)
+	variant := fs.String("variant", "chip8", "instruction set when the launch request names none")
+	addr := fs.String("listen", "", "TCP address to accept clients on instead of using stdio")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: go-plan8 dap [flags]")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if fs.NArg() != 0 {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	v, err := chip8.LookupVariant(*variant)
+	if err != nil {
+		return err
+	}
+	s := &dap.Server{Variant: v}
+	if *addr == "" {
+		return s.ServeConn(struct {
+			io.Reader
+			io.Writer
+		}{os.Stdin, os.Stdout})
+	}
+	l, err := net.Listen("tcp", *addr)
+	if err != nil {
+		return err
+	}
+	defer l.Close()
+	fmt.Fprintf(os.Stderr, "listening for DAP clients on %s\n", l.Addr())
+	return s.Serve(l)
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+
+	chip8 "github.com/hermesdt/go-plan8"
+	"github.com/hermesdt/go-plan8/debugger"
+	"github.com/hermesdt/go-plan8/disasm"
+)
+
+func debugCommand(args []string) error {
+	fs := flag.NewFlagSet("debug", flag.ExitOnError)
+	variant := fs.String("variant", "chip8", "instruction set: chip8, schip or xochip")
+	syntax := fs.String("syntax", "cowgod", "disassembly syntax: cowgod or octo")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: go-plan8 debug [flags] rom.ch8")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	v, err := chip8.LookupVariant(*variant)
+	if err != nil {
+		return err
+	}
+	s, err := disasm.ParseSyntax(*syntax)
+	if err != nil {
+		return err
+	}
+	c := chip8.NewChip8(v)
+	if err := loadProgram(c, fs.Arg(0)); err != nil {
+		return err
+	}
+
+	r := debugger.NewREPL(debugger.New(chip8.NewMachine(c)), os.Stdout)
+	r.Syntax = s
+	// Ctrl-C stops a running command instead of leaving the debugger.
+	interrupts := make(chan os.Signal, 1)
+	signal.Notify(interrupts, os.Interrupt)
+	go func() {
+		for range interrupts {
+			r.Interrupt()
+		}
+	}()
+	return r.Run(os.Stdin)
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	chip8 "github.com/hermesdt/go-plan8"
+	"github.com/hermesdt/go-plan8/disasm"
+)
+
+func disasmCommand(args []string) error {
+	fs := flag.NewFlagSet("disasm", flag.ExitOnError)
+	syntax := fs.String("syntax", "cowgod", "mnemonic syntax: cowgod or octo")
+	variant := fs.String("variant", "chip8", "instruction set: chip8, schip or xochip")
+	linear := fs.Bool("linear", false, "decode every word instead of following control flow")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: go-plan8 disasm [flags] rom.ch8")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	s, err := disasm.ParseSyntax(*syntax)
+	if err != nil {
+		return err
+	}
+	v, err := chip8.LookupVariant(*variant)
+	if err != nil {
+		return err
+	}
+	rom, err := ioutil.ReadFile(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	var ins []disasm.Instruction
+	if *linear {
+		for off := 0; off < len(rom); {
+			in := disasm.Decode(rom[off:], uint16(chip8.RomStart+off), v)
+			ins = append(ins, in)
+			off += len(in.Bytes)
+		}
+	} else {
+		ins = disasm.Disassemble(rom, chip8.RomStart, v)
+	}
+	return disasm.Write(os.Stdout, ins, s)
+}
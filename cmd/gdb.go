@@ -0,0 +1,37 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	chip8 "github.com/hermesdt/go-plan8"
+	"github.com/hermesdt/go-plan8/debugger"
+	"github.com/hermesdt/go-plan8/gdbstub"
+)
+
+func gdbCommand(args []string) error {
+	fs := flag.NewFlagSet("gdb", flag.ExitOnError)
+	variant := fs.String("variant", "chip8", "instruction set: chip8, schip or xochip")
+	addr := fs.String("listen", "localhost:2159", "TCP address to accept gdb connections on")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: go-plan8 gdb [flags] rom.ch8")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	v, err := chip8.LookupVariant(*variant)
+	if err != nil {
+		return err
+	}
+	c := chip8.NewChip8(v)
+	if err := loadProgram(c, fs.Arg(0)); err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "listening for gdb on %s\n", *addr)
+	return gdbstub.ListenAndServe(*addr, debugger.New(chip8.NewMachine(c)))
+}
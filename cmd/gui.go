@@ -0,0 +1,26 @@
+//go:build gui
+// +build gui
+
+package main
+
+import (
+	"fyne.io/fyne/app"
+	chip8 "github.com/hermesdt/go-plan8"
+	"github.com/hermesdt/go-plan8/fyneui"
+)
+
+func init() {
+	frontend = runWindow
+}
+
+func runWindow(path string) error {
+	f := fyneui.New(app.New(), chip8.VariantChip8)
+	if flags := flagStore(); flags != nil {
+		f.Flags = flags
+	}
+	if err := f.Open(path); err != nil {
+		return err
+	}
+	f.Run()
+	return nil
+}
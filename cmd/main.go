@@ -3,34 +3,60 @@ package main
 import (
 	"fmt"
 	"os"
+	"path/filepath"
 
 	chip8 "github.com/hermesdt/go-plan8"
 )
 
-func main() {
-	// app := app.New()
-
-	// w := app.NewWindow("Hello")
-	// w.SetContent(widget.NewVBox(
-	// 	widget.NewLabel("Hello Fyne!"),
-	// 	widget.NewButton("Quit", func() {
-	// 		app.Quit()
-	// 	}),
-	// ))
+// commands are the subcommands selected by the first argument. Without
+// one, the argument is a ROM to run.
+var commands = map[string]func(args []string) error{
+	"asm":       asmCommand,
+	"dap":       dapCommand,
+	"debug":     debugCommand,
+	"disasm":    disasmCommand,
+	"gdb":       gdbCommand,
+	"octo":      octoCommand,
+	"profile":   profileCommand,
+	"replay":    replayCommand,
+	"run":       runCommand,
+	"term":      termCommand,
+	"trace":     traceCommand,
+	"tracediff": tracediffCommand,
+}
 
-	// w.Canvas().SetOnTypedRune(func(r rune) {
-	// 	fmt.Println("read rune", r)
-	// })
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprintln(os.Stderr, "usage: go-plan8 [asm | dap | debug | disasm | gdb | octo | profile | replay | run | term | trace | tracediff] file")
+		os.Exit(2)
+	}
+	if cmd, ok := commands[os.Args[1]]; ok {
+		if err := cmd(os.Args[2:]); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
 
-	// w.ShowAndRun()
+	if err := frontend(os.Args[1]); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
 
-	c := chip8.NewChip8()
-	c.LoadRom(os.Args[1])
+// frontend runs a program interactively. Builds with the gui tag, which
+// needs the OpenGL and X11 development headers, open it in a Fyne
+// window; others draw it in the terminal.
+var frontend = func(path string) error {
+	return termCommand([]string{path})
+}
 
-	for {
-		o := c.FetchOpcode()
-		o.Execute()
-		fmt.Println(c.Screen.Render())
-		// time.Sleep(10 * time.Millisecond)
+// flagStore keeps the RPL user flags in the user's config
+// directory, if there is one.
+func flagStore() chip8.FlagStore {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return nil
 	}
+	return chip8.NewFileFlagStore(filepath.Join(dir, "go-plan8", "flags"))
 }
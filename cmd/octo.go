@@ -0,0 +1,55 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	chip8 "github.com/hermesdt/go-plan8"
+	"github.com/hermesdt/go-plan8/octo"
+)
+
+func octoCommand(args []string) error {
+	fs := flag.NewFlagSet("octo", flag.ExitOnError)
+	out := fs.String("o", "", "output ROM, defaults to the source name with a .ch8 extension")
+	variant := fs.String("variant", "chip8", "instruction set: chip8, schip or xochip")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: go-plan8 octo [flags] program.8o")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	v, err := chip8.LookupVariant(*variant)
+	if err != nil {
+		return err
+	}
+	c := &octo.Compiler{Variant: v}
+	p, err := c.CompileFile(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	if *out == "" {
+		*out = strings.TrimSuffix(fs.Arg(0), ".8o") + ".ch8"
+	}
+	return ioutil.WriteFile(*out, p.Bytes, 0644)
+}
+
+// loadProgram loads a ROM, or compiles and loads Octo source when the
+// file name ends in .8o.
+func loadProgram(c *chip8.Chip8, name string) error {
+	if !strings.HasSuffix(name, ".8o") {
+		return c.LoadRom(name)
+	}
+	oc := &octo.Compiler{Variant: c.Variant}
+	p, err := oc.CompileFile(name)
+	if err != nil {
+		return err
+	}
+	return c.LoadRomBytes(p.Bytes)
+}
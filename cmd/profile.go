@@ -0,0 +1,98 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	chip8 "github.com/hermesdt/go-plan8"
+	"github.com/hermesdt/go-plan8/disasm"
+	"github.com/hermesdt/go-plan8/octo"
+	"github.com/hermesdt/go-plan8/profile"
+)
+
+func profileCommand(args []string) error {
+	fs := flag.NewFlagSet("profile", flag.ExitOnError)
+	variant := fs.String("variant", "chip8", "instruction set: chip8, schip or xochip")
+	n := fs.Int("n", 1000000, "stop after this many instructions")
+	pprof := fs.String("pprof", "", "write a profile for go tool pprof to this file")
+	annotate := fs.Bool("annotate", false, "print the disassembly annotated with execution counts instead of the report")
+	syntax := fs.String("syntax", "cowgod", "disassembly syntax: cowgod or octo")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: go-plan8 profile [flags] rom.ch8")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	v, err := chip8.LookupVariant(*variant)
+	if err != nil {
+		return err
+	}
+	s, err := disasm.ParseSyntax(*syntax)
+	if err != nil {
+		return err
+	}
+	rom, names, err := readProgram(fs.Arg(0), v)
+	if err != nil {
+		return err
+	}
+	c := chip8.NewChip8(v)
+	if err := c.LoadRomBytes(rom); err != nil {
+		return err
+	}
+	p := profile.New(rom, v)
+	p.Names = names
+
+	m := chip8.NewMachine(c)
+	m.Tracer = p
+	if err := m.Emulate(*n); err != nil && !errors.Is(err, chip8.ErrExit) {
+		fmt.Fprintln(os.Stderr, err)
+	}
+
+	if *pprof != "" {
+		file, err := os.Create(*pprof)
+		if err != nil {
+			return err
+		}
+		if err := p.WriteProfile(file, fs.Arg(0)); err != nil {
+			file.Close()
+			return err
+		}
+		if err := file.Close(); err != nil {
+			return err
+		}
+	}
+	if *annotate {
+		return p.WriteAnnotated(os.Stdout, s)
+	}
+	return p.WriteFlat(os.Stdout)
+}
+
+// readProgram reads a ROM, or compiles an Octo program and names its
+// labels.
+func readProgram(name string, v chip8.Variant) ([]byte, map[uint16]string, error) {
+	if !strings.HasSuffix(name, ".8o") {
+		rom, err := ioutil.ReadFile(name)
+		return rom, nil, err
+	}
+	oc := &octo.Compiler{Variant: v}
+	p, err := oc.CompileFile(name)
+	if err != nil {
+		return nil, nil, err
+	}
+	names := map[uint16]string{}
+	for label, addr := range p.Labels {
+		// Of labels on the same address, keep one reproducibly.
+		if old, ok := names[uint16(addr)]; !ok || label < old {
+			names[uint16(addr)] = label
+		}
+	}
+	return p.Bytes, names, nil
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/hermesdt/go-plan8/movie"
+)
+
+func replayCommand(args []string) error {
+	fs := flag.NewFlagSet("replay", flag.ExitOnError)
+	verify := fs.Bool("verify", false, "fail unless the movie ends in the screen and registers recorded in it")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: go-plan8 replay [-verify] movie.c8m")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	file, err := os.Open(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	mv, err := movie.Read(file)
+	file.Close()
+	if err != nil {
+		return err
+	}
+	fmt.Printf("rom %v, %v, %s quirks, %v random source seeded %d\n", mv.RomHash, mv.Variant, mv.Quirks, mv.Random, mv.Seed)
+	fmt.Printf("%d frames, %d instructions, %d key events\n", len(mv.Frames), mv.Cycles(), len(mv.Events))
+	if *verify {
+		if err := movie.Verify(mv); err != nil {
+			return err
+		}
+		fmt.Println("ok")
+		return nil
+	}
+
+	c, err := movie.Play(mv)
+	if err != nil {
+		return err
+	}
+	h := movie.HashState(c)
+	fmt.Printf("screen %x\nregisters %x\n", h.Screen, h.Registers)
+	return nil
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"strings"
+
+	chip8 "github.com/hermesdt/go-plan8"
+	"github.com/hermesdt/go-plan8/runner"
+)
+
+func runCommand(args []string) error {
+	fs := flag.NewFlagSet("run", flag.ExitOnError)
+	headless := fs.Bool("headless", false, "run without a window or terminal and print the final state as JSON")
+	variant := fs.String("variant", "chip8", "instruction set: chip8, schip or xochip")
+	quirks := fs.String("quirks", "", "quirk profile, defaults to the variant's")
+	frames := fs.Uint64("frames", runner.DefaultFrames, "stop after this many frames")
+	ips := fs.Int("ips", chip8.DefaultInstrPerSec, "instructions per second")
+	seed := fs.Int64("seed", 0, "seed for the random numbers of CXNN")
+	rng := fs.String("rng", "go", "random source of CXNN: go, xorshift, vip or replay")
+	replay := fs.String("rng-replay", "", "file with the bytes for -rng replay")
+	vip := fs.String("vip-interpreter", "", "dump of the VIP's 0x000-0x1FF for -rng vip")
+	untilPC := fs.String("until-pc", "", "stop when PC reaches one of these comma separated hex addresses")
+	untilMem := fs.String("until-mem", "", "stop when memory holds one of these comma separated hex values, as 300=FF")
+	loop := fs.Bool("stop-on-loop", true, "stop at a jump to itself")
+	keys := fs.String("keys", "", `key script, as "60:5+ 90:5-" to hold key 5 from frame 60 to 90`)
+	out := fs.String("o", "", "write the JSON to a file instead of stdout")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: go-plan8 run rom.ch8\n       go-plan8 run -headless [flags] rom.ch8")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		fs.Usage()
+		os.Exit(2)
+	}
+	if !*headless {
+		// The window takes none of the other flags.
+		var set []string
+		fs.Visit(func(f *flag.Flag) {
+			if f.Name != "headless" {
+				set = append(set, "-"+f.Name)
+			}
+		})
+		if len(set) > 0 {
+			return fmt.Errorf("flags need -headless: %s", strings.Join(set, " "))
+		}
+		return frontend(fs.Arg(0))
+	}
+
+	v, err := chip8.LookupVariant(*variant)
+	if err != nil {
+		return err
+	}
+	c := chip8.NewChip8(v)
+	if *quirks != "" {
+		if c.Quirks, err = chip8.LookupQuirks(*quirks); err != nil {
+			return err
+		}
+	}
+	if err := loadProgram(c, fs.Arg(0)); err != nil {
+		return err
+	}
+
+	r := runner.New(c)
+	r.Frames = *frames
+	r.InstructionsPerSecond = *ips
+	r.Seed = *seed
+	if r.Random, err = randomSource(*rng, *seed, *replay, *vip); err != nil {
+		return err
+	}
+	r.StopOnLoop = *loop
+	for _, s := range split(*untilPC) {
+		pc, err := strconv.ParseUint(s, 16, 16)
+		if err != nil {
+			return fmt.Errorf("bad -until-pc address %q", s)
+		}
+		r.UntilPC = append(r.UntilPC, uint16(pc))
+	}
+	for _, s := range split(*untilMem) {
+		mc, err := runner.ParseMemoryCondition(s)
+		if err != nil {
+			return err
+		}
+		r.UntilMemory = append(r.UntilMemory, mc)
+	}
+	if r.Script, err = runner.ParseScript(*keys); err != nil {
+		return err
+	}
+
+	var w io.Writer = os.Stdout
+	if *out != "" {
+		file, err := os.Create(*out)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		w = file
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(r.Run())
+}
+
+// randomSource returns the source named kind, seeded with seed or, for
+// replay, returning the bytes of the file at replay. The vip source
+// draws from the interpreter image in the file at vip.
+func randomSource(kind string, seed int64, replay, vip string) (chip8.RandomSource, error) {
+	k, err := chip8.LookupRandom(kind)
+	if err != nil {
+		return nil, err
+	}
+	switch k {
+	case chip8.RandomReplay:
+		if replay == "" {
+			return nil, fmt.Errorf("-rng replay needs -rng-replay")
+		}
+		data, err := ioutil.ReadFile(replay)
+		if err != nil {
+			return nil, err
+		}
+		return chip8.NewReplayRandom(data), nil
+	case chip8.RandomVIP:
+		if vip == "" {
+			return nil, fmt.Errorf("-rng vip needs -vip-interpreter")
+		}
+		image, err := ioutil.ReadFile(vip)
+		if err != nil {
+			return nil, err
+		}
+		return chip8.NewVIPRandom(seed, image)
+	}
+	return chip8.NewRandom(k, seed)
+}
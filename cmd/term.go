@@ -0,0 +1,94 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	chip8 "github.com/hermesdt/go-plan8"
+	"github.com/hermesdt/go-plan8/movie"
+	"github.com/hermesdt/go-plan8/termui"
+)
+
+func termCommand(args []string) error {
+	fs := flag.NewFlagSet("term", flag.ExitOnError)
+	variant := fs.String("variant", "chip8", "instruction set: chip8, schip or xochip")
+	mode := fs.String("mode", "halfblock", "pixels per character: halfblock (1x2) or braille (2x4)")
+	ips := fs.Int("ips", chip8.DefaultInstrPerSec, "instructions per second")
+	timeout := fs.Duration("key-timeout", termui.DefaultKeyTimeout, "release a key when it is not typed again for this long")
+	record := fs.String("record", "", "record the keys typed into this movie file, for replay")
+	rng := fs.String("rng", "go", "random source of CXNN when recording: go or xorshift")
+	seed := fs.Int64("seed", 0, "seed for the random source when recording, 0 to pick one")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: go-plan8 term [flags] rom.ch8")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	v, err := chip8.LookupVariant(*variant)
+	if err != nil {
+		return err
+	}
+	md, err := termui.ParseMode(*mode)
+	if err != nil {
+		return err
+	}
+	c := chip8.NewChip8(v)
+	c.Flags = flagStore()
+	if err := loadProgram(c, fs.Arg(0)); err != nil {
+		return err
+	}
+	m := chip8.NewMachine(c)
+	m.InstructionsPerSecond = *ips
+	var rec *movie.Recorder
+	if *record != "" {
+		kind, err := chip8.LookupRandom(*rng)
+		if err != nil {
+			return err
+		}
+		if *seed == 0 {
+			*seed = time.Now().UnixNano()
+		}
+		if rec, err = movie.NewRecorder(m, kind, *seed); err != nil {
+			return err
+		}
+	}
+
+	restore, err := termui.MakeRaw(os.Stdin)
+	if err != nil {
+		return err
+	}
+	defer restore()
+	f := termui.New(m, os.Stdin, os.Stdout)
+	f.Renderer.Mode = md
+	f.Keys.Timeout = *timeout
+	err = f.Run(context.Background())
+	if rec != nil {
+		if err := writeMovie(rec, *record); err != nil {
+			return err
+		}
+	}
+	return err
+}
+
+func writeMovie(rec *movie.Recorder, path string) error {
+	mv, err := rec.Movie()
+	if err != nil {
+		return err
+	}
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := mv.Write(file); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
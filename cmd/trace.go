@@ -0,0 +1,93 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	chip8 "github.com/hermesdt/go-plan8"
+	"github.com/hermesdt/go-plan8/trace"
+)
+
+func traceCommand(args []string) error {
+	fs := flag.NewFlagSet("trace", flag.ExitOnError)
+	out := fs.String("o", "", "write the trace to a file instead of stdout")
+	format := fs.String("format", "text", "trace format: text, json or binary")
+	variant := fs.String("variant", "chip8", "instruction set: chip8, schip or xochip")
+	n := fs.Int("n", 1000000, "stop after this many instructions")
+	ranges := fs.String("range", "", "only trace addresses in these comma separated hex ranges, as 200-2FF")
+	classes := fs.String("op", "", "only trace these comma separated instruction names or mnemonics, as Draw,CALL")
+	ring := fs.Int("ring", 0, "keep only the last N instructions and write them if one fails")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: go-plan8 trace [flags] rom.ch8")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	v, err := chip8.LookupVariant(*variant)
+	if err != nil {
+		return err
+	}
+	f, err := trace.ParseFormat(*format)
+	if err != nil {
+		return err
+	}
+	var filter trace.Filter
+	for _, s := range split(*ranges) {
+		r, err := trace.ParseRange(s)
+		if err != nil {
+			return err
+		}
+		filter.Ranges = append(filter.Ranges, r)
+	}
+	filter.Classes = split(*classes)
+
+	c := chip8.NewChip8(v)
+	if err := loadProgram(c, fs.Arg(0)); err != nil {
+		return err
+	}
+	var w io.Writer = os.Stdout
+	if *out != "" {
+		file, err := os.Create(*out)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		w = file
+	}
+	tr := trace.New(w, f, v)
+	if *ring > 0 {
+		tr = trace.NewRing(w, f, v, *ring)
+	}
+	tr.Filter = filter
+
+	// Emulated time makes every run of a ROM trace the same.
+	m := chip8.NewMachine(c)
+	m.Tracer = tr
+	err = m.Emulate(*n)
+	if ferr := tr.Flush(); ferr != nil {
+		return ferr
+	}
+	if errors.Is(err, chip8.ErrExit) {
+		return nil
+	}
+	return err
+}
+
+// split splits a comma separated flag, ignoring empty items.
+func split(s string) []string {
+	var items []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	chip8 "github.com/hermesdt/go-plan8"
+	"github.com/hermesdt/go-plan8/tracediff"
+)
+
+var errTracesDiffer = errors.New("traces differ")
+
+func tracediffCommand(args []string) error {
+	fs := flag.NewFlagSet("tracediff", flag.ExitOnError)
+	variant := fs.String("variant", "chip8", "instruction set: chip8, schip or xochip")
+	n := fs.Int("n", 1000000, "compare at most this many instructions")
+	record := fs.String("record", "", "write the canonical trace of the ROM to this file, - for stdout, instead of comparing")
+	history := fs.Int("history", 5, "matching instructions to show before the divergence")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: go-plan8 tracediff [flags] rom.ch8 reference.trace")
+		fmt.Fprintln(fs.Output(), "       go-plan8 tracediff -record file [flags] rom.ch8")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if *record == "" && fs.NArg() != 2 || *record != "" && fs.NArg() != 1 {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	v, err := chip8.LookupVariant(*variant)
+	if err != nil {
+		return err
+	}
+	c := chip8.NewChip8(v)
+	if err := loadProgram(c, fs.Arg(0)); err != nil {
+		return err
+	}
+	m := chip8.NewMachine(c)
+
+	if *record != "" {
+		var w io.Writer = os.Stdout
+		if *record != "-" {
+			f, err := os.Create(*record)
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+			w = f
+		}
+		return tracediff.Record(m, w, *n)
+	}
+
+	ref, err := os.Open(fs.Arg(1))
+	if err != nil {
+		return err
+	}
+	defer ref.Close()
+	d, err := tracediff.Compare(m, ref, *n, *history)
+	if err != nil {
+		return err
+	}
+	if d == nil {
+		fmt.Println("no divergence")
+		return nil
+	}
+	if err := d.Write(os.Stdout); err != nil {
+		return err
+	}
+	return errTracesDiffer
+}
@@ -0,0 +1,111 @@
+package dap
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+var errNoLength = errors.New("dap: message without Content-Length")
+
+// request is a message from the client. Only requests are expected:
+// the server sends no reverse requests for clients to answer.
+type request struct {
+	Seq       int             `json:"seq"`
+	Type      string          `json:"type"`
+	Command   string          `json:"command"`
+	Arguments json.RawMessage `json:"arguments"`
+}
+
+type response struct {
+	Seq        int         `json:"seq"`
+	Type       string      `json:"type"`
+	RequestSeq int         `json:"request_seq"`
+	Success    bool        `json:"success"`
+	Command    string      `json:"command"`
+	Message    string      `json:"message,omitempty"`
+	Body       interface{} `json:"body,omitempty"`
+}
+
+type event struct {
+	Seq   int         `json:"seq"`
+	Type  string      `json:"type"`
+	Event string      `json:"event"`
+	Body  interface{} `json:"body,omitempty"`
+}
+
+// readMessage reads one message framed by a Content-Length header.
+func readMessage(r *bufio.Reader) ([]byte, error) {
+	tp := textproto.NewReader(r)
+	n := -1
+	for {
+		line, err := tp.ReadLine()
+		if err != nil {
+			return nil, err
+		}
+		if line == "" {
+			break
+		}
+		parts := strings.SplitN(line, ":", 2)
+		if len(parts) == 2 && strings.EqualFold(strings.TrimSpace(parts[0]), "Content-Length") {
+			if n, err = strconv.Atoi(strings.TrimSpace(parts[1])); err != nil {
+				return nil, fmt.Errorf("dap: bad Content-Length %q", parts[1])
+			}
+		}
+	}
+	if n < 0 {
+		return nil, errNoLength
+	}
+	body := make([]byte, n)
+	if _, err := io.ReadFull(r, body); err != nil {
+		return nil, err
+	}
+	return body, nil
+}
+
+// writer numbers and frames outgoing messages. Events are sent from the
+// goroutine running the program as well as the one reading requests.
+type writer struct {
+	mu  sync.Mutex
+	w   io.Writer
+	seq int
+}
+
+func (w *writer) respond(req *request, body interface{}) error {
+	return w.write(func(seq int) interface{} {
+		return &response{Seq: seq, Type: "response", RequestSeq: req.Seq, Success: true, Command: req.Command, Body: body}
+	})
+}
+
+func (w *writer) fail(req *request, err error) error {
+	return w.write(func(seq int) interface{} {
+		return &response{Seq: seq, Type: "response", RequestSeq: req.Seq, Command: req.Command, Message: err.Error()}
+	})
+}
+
+func (w *writer) event(name string, body interface{}) error {
+	return w.write(func(seq int) interface{} {
+		return &event{Seq: seq, Type: "event", Event: name, Body: body}
+	})
+}
+
+func (w *writer) write(msg func(seq int) interface{}) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.seq++
+	bs, err := json.Marshal(msg(w.seq))
+	if err != nil {
+		return err
+	}
+	if _, err := fmt.Fprintf(w.w, "Content-Length: %d\r\n\r\n", len(bs)); err != nil {
+		return err
+	}
+	_, err = w.w.Write(bs)
+	return err
+}
@@ -0,0 +1,626 @@
+// Package dap serves the Debug Adapter Protocol, so editors can debug
+// CHIP-8 programs. A launch request names a ROM, an Octo program (.8o)
+// or an assembler source (.asm); breakpoints and stack frames of the
+// latter refer to its source lines through a SourceMap, while any
+// program can be debugged by instruction address.
+//
+// The single thread has the id 1. Stack frames are the instruction at
+// PC followed by the call sites on Chip8.Stack. Each frame has scopes
+// of registers, timers and keys, and memory references are addresses,
+// as in "0x0200".
+package dap
+
+import (
+	"bufio"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	chip8 "github.com/hermesdt/go-plan8"
+	"github.com/hermesdt/go-plan8/asm"
+	"github.com/hermesdt/go-plan8/debugger"
+	"github.com/hermesdt/go-plan8/disasm"
+	"github.com/hermesdt/go-plan8/octo"
+)
+
+const threadID = 1
+
+// Variable references of the scopes.
+const (
+	registersRef = iota + 1
+	timersRef
+	keysRef
+)
+
+var (
+	errNotLaunched = errors.New("no program has been launched")
+	errRunning     = errors.New("the program is running")
+)
+
+// Server debugs one program per connection.
+type Server struct {
+	// Variant is used when a launch request does not name one.
+	Variant chip8.Variant
+}
+
+// Serve accepts connections on l and serves each in its own goroutine.
+func (s *Server) Serve(l net.Listener) error {
+	for {
+		c, err := l.Accept()
+		if err != nil {
+			return err
+		}
+		go func() {
+			defer c.Close()
+			s.ServeConn(c)
+		}()
+	}
+}
+
+// ServeConn serves one client, such as an editor talking over stdio,
+// until it disconnects.
+func (s *Server) ServeConn(rw io.ReadWriter) error {
+	ss := &session{Server: s, w: &writer{w: rw}, sourceBreakpoints: map[string][]int{}}
+	defer ss.halt()
+	r := bufio.NewReader(rw)
+	for {
+		msg, err := readMessage(r)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		var req request
+		if err := json.Unmarshal(msg, &req); err != nil {
+			return fmt.Errorf("dap: %v", err)
+		}
+		if req.Type != "request" {
+			continue
+		}
+		if req.Command == "disconnect" || req.Command == "terminate" {
+			ss.halt()
+			return ss.w.respond(&req, nil)
+		}
+		body, err := ss.handle(&req)
+		if err != nil {
+			ss.after = nil
+			err = ss.w.fail(&req, err)
+		} else {
+			err = ss.w.respond(&req, body)
+		}
+		if err != nil {
+			return err
+		}
+		if ss.after != nil {
+			ss.after()
+			ss.after = nil
+		}
+	}
+}
+
+type session struct {
+	*Server
+	w *writer
+	d *debugger.Debugger
+	// source maps addresses to lines when the program is assembler
+	// source.
+	source      *SourceMap
+	stopOnEntry bool
+
+	sourceBreakpoints      map[string][]int
+	instructionBreakpoints []int
+
+	// after runs once the response to the current request is sent, so
+	// that events follow it.
+	after func()
+
+	// done is closed when the running program stops.
+	done   chan struct{}
+	cancel context.CancelFunc
+	// quiet marks an interruption by the session itself, which the
+	// client is not told about.
+	quiet bool
+	last  debugger.Stop
+}
+
+func (s *session) handle(req *request) (interface{}, error) {
+	if s.d == nil && req.Command != "initialize" && req.Command != "launch" {
+		return nil, errNotLaunched
+	}
+	switch req.Command {
+	case "initialize":
+		return map[string]interface{}{
+			"supportsConfigurationDoneRequest": true,
+			"supportsConditionalBreakpoints":   true,
+			"supportsInstructionBreakpoints":   true,
+			"supportsReadMemoryRequest":        true,
+			"supportsDisassembleRequest":       true,
+			"supportsSteppingGranularity":      true,
+			"supportsEvaluateForHovers":        true,
+			"supportsTerminateRequest":         true,
+		}, nil
+	case "launch":
+		return nil, s.launch(req.Arguments)
+	case "configurationDone":
+		s.after = func() {
+			if s.stopOnEntry {
+				s.stopped("entry", nil, "")
+				return
+			}
+			s.start(s.d.Continue)
+		}
+		return nil, nil
+	case "setBreakpoints":
+		return s.resumeAfter(func() (interface{}, error) { return s.setBreakpoints(req.Arguments) })
+	case "setInstructionBreakpoints":
+		return s.resumeAfter(func() (interface{}, error) { return s.setInstructionBreakpoints(req.Arguments) })
+	case "threads":
+		return map[string]interface{}{
+			"threads": []map[string]interface{}{{"id": threadID, "name": "chip8"}},
+		}, nil
+	case "pause":
+		if s.running() {
+			s.cancel()
+		}
+		return nil, nil
+	case "continue":
+		return s.run(s.d.Continue, map[string]interface{}{"allThreadsContinued": true})
+	case "next":
+		return s.run(s.d.Next, nil)
+	case "stepIn":
+		return s.run(func(ctx context.Context) debugger.Stop { return s.d.Step(ctx, 1) }, nil)
+	case "stepOut":
+		return s.run(s.d.Finish, nil)
+	}
+	// The remaining requests inspect the stopped machine.
+	if s.running() {
+		return nil, errRunning
+	}
+	switch req.Command {
+	case "stackTrace":
+		return s.stackTrace(), nil
+	case "scopes":
+		return map[string]interface{}{"scopes": []map[string]interface{}{
+			{"name": "Registers", "presentationHint": "registers", "variablesReference": registersRef, "expensive": false},
+			{"name": "Timers", "variablesReference": timersRef, "expensive": false},
+			{"name": "Keys", "variablesReference": keysRef, "expensive": false},
+		}}, nil
+	case "variables":
+		return s.variables(req.Arguments)
+	case "evaluate":
+		return s.evaluate(req.Arguments)
+	case "readMemory":
+		return s.readMemory(req.Arguments)
+	case "disassemble":
+		return s.disassemble(req.Arguments)
+	}
+	return nil, fmt.Errorf("unsupported request %q", req.Command)
+}
+
+func (s *session) launch(raw json.RawMessage) error {
+	var args struct {
+		Program     string `json:"program"`
+		Variant     string `json:"variant"`
+		StopOnEntry bool   `json:"stopOnEntry"`
+	}
+	if err := json.Unmarshal(raw, &args); err != nil {
+		return err
+	}
+	if args.Program == "" {
+		return errors.New("launch: no program given")
+	}
+	if s.d != nil {
+		return errors.New("launch: a program is already running")
+	}
+	v := s.Variant
+	if args.Variant != "" {
+		var err error
+		if v, err = chip8.LookupVariant(args.Variant); err != nil {
+			return err
+		}
+	}
+	c := chip8.NewChip8(v)
+	switch filepath.Ext(args.Program) {
+	case ".asm":
+		a := &asm.Assembler{Variant: v}
+		p, err := a.AssembleFile(args.Program)
+		if err != nil {
+			return err
+		}
+		if err := c.LoadRomBytes(p.Bytes); err != nil {
+			return err
+		}
+		s.source = NewSourceMap(p)
+	case ".8o":
+		oc := &octo.Compiler{Variant: v}
+		p, err := oc.CompileFile(args.Program)
+		if err != nil {
+			return err
+		}
+		if err := c.LoadRomBytes(p.Bytes); err != nil {
+			return err
+		}
+	default:
+		if err := c.LoadRom(args.Program); err != nil {
+			return err
+		}
+	}
+	s.d = debugger.New(chip8.NewMachine(c))
+	s.stopOnEntry = args.StopOnEntry
+	s.after = func() { s.w.event("initialized", nil) }
+	return nil
+}
+
+// run starts f once the response, with body, is sent.
+func (s *session) run(f func(context.Context) debugger.Stop, body interface{}) (interface{}, error) {
+	if s.running() {
+		return nil, errRunning
+	}
+	s.after = func() { s.start(f) }
+	return body, nil
+}
+
+// start runs f in the background and reports how it stops.
+func (s *session) start(f func(context.Context) debugger.Stop) {
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	s.done, s.cancel, s.quiet = done, cancel, false
+	go func() {
+		stop := f(ctx)
+		s.last = stop
+		// The session only cancels quietly once it has set quiet.
+		quiet := stop.Reason == debugger.StopInterrupted && s.quiet
+		// Requests answering the event must find the program stopped.
+		close(done)
+		switch stop.Reason {
+		case debugger.StopStep:
+			s.stopped("step", nil, "")
+		case debugger.StopBreakpoint:
+			s.stopped("breakpoint", []int{stop.Breakpoint.ID}, "")
+		case debugger.StopWatchpoint:
+			s.stopped("data breakpoint", nil, stop.String())
+		case debugger.StopInterrupted:
+			if !quiet {
+				s.stopped("pause", nil, "")
+			}
+		case debugger.StopHalted:
+			if errors.Is(stop.Err, chip8.ErrExit) {
+				s.w.event("exited", map[string]interface{}{"exitCode": 0})
+				s.w.event("terminated", nil)
+				return
+			}
+			s.stopped("exception", nil, stop.Err.Error())
+		}
+	}()
+}
+
+func (s *session) stopped(reason string, hits []int, text string) {
+	body := map[string]interface{}{
+		"reason":            reason,
+		"threadId":          threadID,
+		"allThreadsStopped": true,
+	}
+	if len(hits) > 0 {
+		body["hitBreakpointIds"] = hits
+	}
+	if text != "" {
+		body["text"] = text
+		body["description"] = text
+	}
+	s.w.event("stopped", body)
+}
+
+func (s *session) running() bool {
+	if s.done == nil {
+		return false
+	}
+	select {
+	case <-s.done:
+		return false
+	default:
+		return true
+	}
+}
+
+// halt quietly interrupts the running program, reporting whether it
+// was running.
+func (s *session) halt() bool {
+	if !s.running() {
+		return false
+	}
+	s.quiet = true
+	s.cancel()
+	<-s.done
+	return s.last.Reason == debugger.StopInterrupted
+}
+
+// resumeAfter runs f with the program halted, continuing it once the
+// response is sent if it was running.
+func (s *session) resumeAfter(f func() (interface{}, error)) (interface{}, error) {
+	if s.halt() {
+		s.after = func() { s.start(s.d.Continue) }
+	}
+	return f()
+}
+
+type sourceBreakpoint struct {
+	Line      int    `json:"line"`
+	Condition string `json:"condition"`
+}
+
+func (s *session) setBreakpoints(raw json.RawMessage) (interface{}, error) {
+	var args struct {
+		Source struct {
+			Path string `json:"path"`
+		} `json:"source"`
+		Breakpoints []sourceBreakpoint `json:"breakpoints"`
+	}
+	if err := json.Unmarshal(raw, &args); err != nil {
+		return nil, err
+	}
+	path := filepath.Clean(args.Source.Path)
+	for _, id := range s.sourceBreakpoints[path] {
+		s.d.Delete(id)
+	}
+	s.sourceBreakpoints[path] = nil
+	out := []map[string]interface{}{}
+	for _, sb := range args.Breakpoints {
+		bp := map[string]interface{}{"verified": false, "line": sb.Line}
+		out = append(out, bp)
+		if s.source == nil {
+			bp["message"] = "the program has no source map"
+			continue
+		}
+		addr, line, ok := s.source.Address(path, sb.Line)
+		if !ok {
+			bp["message"] = "no code at or after this line"
+			continue
+		}
+		b, err := s.d.Break(int(addr), sb.Condition)
+		if err != nil {
+			bp["message"] = err.Error()
+			continue
+		}
+		s.sourceBreakpoints[path] = append(s.sourceBreakpoints[path], b.ID)
+		bp["verified"], bp["id"], bp["line"] = true, b.ID, line
+		bp["instructionReference"] = reference(addr)
+	}
+	return map[string]interface{}{"breakpoints": out}, nil
+}
+
+func (s *session) setInstructionBreakpoints(raw json.RawMessage) (interface{}, error) {
+	var args struct {
+		Breakpoints []struct {
+			InstructionReference string `json:"instructionReference"`
+			Offset               int    `json:"offset"`
+			Condition            string `json:"condition"`
+		} `json:"breakpoints"`
+	}
+	if err := json.Unmarshal(raw, &args); err != nil {
+		return nil, err
+	}
+	for _, id := range s.instructionBreakpoints {
+		s.d.Delete(id)
+	}
+	s.instructionBreakpoints = nil
+	out := []map[string]interface{}{}
+	for _, ib := range args.Breakpoints {
+		bp := map[string]interface{}{"verified": false}
+		out = append(out, bp)
+		addr, err := s.address(ib.InstructionReference, ib.Offset)
+		if err != nil {
+			bp["message"] = err.Error()
+			continue
+		}
+		b, err := s.d.Break(int(addr), ib.Condition)
+		if err != nil {
+			bp["message"] = err.Error()
+			continue
+		}
+		s.instructionBreakpoints = append(s.instructionBreakpoints, b.ID)
+		bp["verified"], bp["id"] = true, b.ID
+		bp["instructionReference"] = reference(addr)
+	}
+	return map[string]interface{}{"breakpoints": out}, nil
+}
+
+func (s *session) stackTrace() interface{} {
+	c := s.d.Machine.Chip8
+	addrs := []uint16{c.PC}
+	for i := int(c.SP) - 1; i >= 0 && i < len(c.Stack); i-- {
+		// The stack holds the address of each call instruction.
+		addrs = append(addrs, c.Stack[i])
+	}
+	frames := []map[string]interface{}{}
+	for i, addr := range addrs {
+		f := map[string]interface{}{
+			"id":                          i,
+			"name":                        fmt.Sprintf("%#04x", addr),
+			"line":                        0,
+			"column":                      0,
+			"instructionPointerReference": reference(addr),
+		}
+		if s.source != nil {
+			if name, ok := s.source.Symbol(addr); ok {
+				f["name"] = name
+			}
+			if pos, ok := s.source.Lookup(addr); ok {
+				f["source"] = map[string]interface{}{"name": filepath.Base(pos.File), "path": pos.File}
+				f["line"], f["column"] = pos.Line, pos.Col
+			}
+		}
+		frames = append(frames, f)
+	}
+	return map[string]interface{}{"stackFrames": frames, "totalFrames": len(frames)}
+}
+
+func (s *session) variables(raw json.RawMessage) (interface{}, error) {
+	var args struct {
+		VariablesReference int `json:"variablesReference"`
+	}
+	if err := json.Unmarshal(raw, &args); err != nil {
+		return nil, err
+	}
+	c := s.d.Machine.Chip8
+	vars := []map[string]interface{}{}
+	add := func(name, value, memory string) {
+		v := map[string]interface{}{"name": name, "value": value, "variablesReference": 0}
+		if memory != "" {
+			v["memoryReference"] = memory
+		}
+		vars = append(vars, v)
+	}
+	switch args.VariablesReference {
+	case registersRef:
+		for x, v := range c.V {
+			add(fmt.Sprintf("V%X", x), fmt.Sprintf("%#02x", v), "")
+		}
+		add("I", fmt.Sprintf("%#04x", c.I), reference(c.I))
+		add("PC", fmt.Sprintf("%#04x", c.PC), reference(c.PC))
+		add("SP", fmt.Sprint(c.SP), "")
+	case timersRef:
+		add("DT", fmt.Sprint(c.DelayTimer), "")
+		add("ST", fmt.Sprint(c.SoundTimer), "")
+	case keysRef:
+		for k := uint8(0); k < chip8.KeyCount; k++ {
+			state := "up"
+			if c.Key.IsDown(k) {
+				state = "down"
+			}
+			add(fmt.Sprintf("%X", k), state, "")
+		}
+	default:
+		return nil, fmt.Errorf("no variables with reference %d", args.VariablesReference)
+	}
+	return map[string]interface{}{"variables": vars}, nil
+}
+
+func (s *session) evaluate(raw json.RawMessage) (interface{}, error) {
+	var args struct {
+		Expression string `json:"expression"`
+	}
+	if err := json.Unmarshal(raw, &args); err != nil {
+		return nil, err
+	}
+	e, err := debugger.ParseExpr(args.Expression)
+	if err != nil {
+		return nil, err
+	}
+	v := e.Eval(s.d.Machine.Chip8)
+	return map[string]interface{}{
+		"result":             fmt.Sprintf("%d (%#x)", v, v),
+		"variablesReference": 0,
+	}, nil
+}
+
+func (s *session) readMemory(raw json.RawMessage) (interface{}, error) {
+	var args struct {
+		MemoryReference string `json:"memoryReference"`
+		Offset          int    `json:"offset"`
+		Count           int    `json:"count"`
+	}
+	if err := json.Unmarshal(raw, &args); err != nil {
+		return nil, err
+	}
+	if args.Count < 0 {
+		return nil, fmt.Errorf("bad count %d", args.Count)
+	}
+	addr, err := s.address(args.MemoryReference, args.Offset)
+	if err != nil {
+		return nil, err
+	}
+	c := s.d.Machine.Chip8
+	end := int(addr) + args.Count
+	if size := c.Variant.MemorySize(); end > size {
+		end = size
+	}
+	data := c.Memory[addr:end]
+	return map[string]interface{}{
+		"address":         reference(addr),
+		"data":            base64.StdEncoding.EncodeToString(data),
+		"unreadableBytes": args.Count - len(data),
+	}, nil
+}
+
+func (s *session) disassemble(raw json.RawMessage) (interface{}, error) {
+	var args struct {
+		MemoryReference   string `json:"memoryReference"`
+		Offset            int    `json:"offset"`
+		InstructionOffset int    `json:"instructionOffset"`
+		InstructionCount  int    `json:"instructionCount"`
+	}
+	if err := json.Unmarshal(raw, &args); err != nil {
+		return nil, err
+	}
+	base, err := parseReference(args.MemoryReference)
+	if err != nil {
+		return nil, err
+	}
+	c := s.d.Machine.Chip8
+	size := c.Variant.MemorySize()
+	// Instructions are taken to be two bytes wide to find the start.
+	addr := base + args.Offset + 2*args.InstructionOffset
+	out := []map[string]interface{}{}
+	for i := 0; i < args.InstructionCount; i++ {
+		if addr < 0 || addr >= size {
+			out = append(out, map[string]interface{}{
+				"address":          fmt.Sprintf("%#x", addr),
+				"instruction":      "??",
+				"presentationHint": "invalid",
+			})
+			addr += 2
+			continue
+		}
+		in := disasm.Decode(c.Memory[addr:size], uint16(addr), c.Variant)
+		ins := map[string]interface{}{
+			"address":          reference(uint16(addr)),
+			"instructionBytes": fmt.Sprintf("% X", in.Bytes),
+			"instruction":      in.Format(disasm.Cowgod),
+		}
+		if s.source != nil {
+			if name, ok := s.source.Symbol(uint16(addr)); ok {
+				ins["symbol"] = name
+			}
+			if pos, ok := s.source.Lookup(uint16(addr)); ok {
+				ins["location"] = map[string]interface{}{"name": filepath.Base(pos.File), "path": pos.File}
+				ins["line"] = pos.Line
+			}
+		}
+		out = append(out, ins)
+		addr += len(in.Bytes)
+	}
+	return map[string]interface{}{"instructions": out}, nil
+}
+
+// address resolves a memory or instruction reference plus offset.
+func (s *session) address(ref string, offset int) (uint16, error) {
+	base, err := parseReference(ref)
+	if err != nil {
+		return 0, err
+	}
+	addr := base + offset
+	if addr < 0 || addr >= s.d.Machine.Chip8.Variant.MemorySize() {
+		return 0, fmt.Errorf("address %#x is outside memory", addr)
+	}
+	return uint16(addr), nil
+}
+
+func parseReference(ref string) (int, error) {
+	v, err := strconv.ParseInt(strings.TrimSpace(ref), 0, 32)
+	if err != nil {
+		return 0, fmt.Errorf("bad memory reference %q", ref)
+	}
+	return int(v), nil
+}
+
+func reference(addr uint16) string {
+	return fmt.Sprintf("0x%04X", addr)
+}
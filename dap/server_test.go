@@ -0,0 +1,363 @@
+package dap
+
+import (
+	"bufio"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	chip8 "github.com/hermesdt/go-plan8"
+	"github.com/hermesdt/go-plan8/asm"
+	"github.com/stretchr/testify/assert"
+)
+
+const testSource = `start:
+	LD V3, 0
+loop:
+	ADD V3, 4
+	CALL sub
+	LD I, 0x300
+	LD [I], V3
+	JP loop
+; the subroutine
+sub:
+	ADD V1, 1
+	RET
+`
+
+type message struct {
+	Seq        int             `json:"seq"`
+	Type       string          `json:"type"`
+	Event      string          `json:"event"`
+	Command    string          `json:"command"`
+	RequestSeq int             `json:"request_seq"`
+	Success    bool            `json:"success"`
+	Message    string          `json:"message"`
+	Body       json.RawMessage `json:"body"`
+}
+
+// client speaks the protocol the way an editor does.
+type client struct {
+	t      *testing.T
+	conn   net.Conn
+	r      *bufio.Reader
+	seq    int
+	events []*message
+}
+
+func newClient(t *testing.T) *client {
+	server, conn := net.Pipe()
+	go (&Server{Variant: chip8.VariantChip8}).ServeConn(server)
+	conn.SetDeadline(time.Now().Add(10 * time.Second))
+	return &client{t: t, conn: conn, r: bufio.NewReader(conn)}
+}
+
+func (c *client) read() *message {
+	bs, err := readMessage(c.r)
+	assert.Nil(c.t, err)
+	var m message
+	assert.Nil(c.t, json.Unmarshal(bs, &m))
+	return &m
+}
+
+// call sends a request and returns its response, queueing the events
+// that arrive meanwhile.
+func (c *client) call(command string, args interface{}) *message {
+	c.seq++
+	bs, err := json.Marshal(map[string]interface{}{
+		"seq": c.seq, "type": "request", "command": command, "arguments": args,
+	})
+	assert.Nil(c.t, err)
+	fmt.Fprintf(c.conn, "Content-Length: %d\r\n\r\n%s", len(bs), bs)
+	for {
+		m := c.read()
+		if m.Type == "event" {
+			c.events = append(c.events, m)
+			continue
+		}
+		assert.Equal(c.t, c.seq, m.RequestSeq)
+		assert.Equal(c.t, command, m.Command)
+		return m
+	}
+}
+
+// body calls a request that must succeed and decodes its body.
+func (c *client) body(command string, args interface{}, body interface{}) {
+	m := c.call(command, args)
+	assert.True(c.t, m.Success, m.Message)
+	if body != nil {
+		assert.Nil(c.t, json.Unmarshal(m.Body, body))
+	}
+}
+
+// event waits for the named event and decodes its body.
+func (c *client) event(name string, body interface{}) {
+	for {
+		if len(c.events) == 0 {
+			c.events = append(c.events, c.read())
+			continue
+		}
+		m := c.events[0]
+		c.events = c.events[1:]
+		if m.Type == "event" && m.Event == name {
+			if body != nil {
+				assert.Nil(c.t, json.Unmarshal(m.Body, body))
+			}
+			return
+		}
+	}
+}
+
+type stoppedBody struct {
+	Reason           string `json:"reason"`
+	ThreadID         int    `json:"threadId"`
+	HitBreakpointIDs []int  `json:"hitBreakpointIds"`
+}
+
+type stackTraceBody struct {
+	StackFrames []struct {
+		Name   string `json:"name"`
+		Line   int    `json:"line"`
+		Source struct {
+			Path string `json:"path"`
+		} `json:"source"`
+		InstructionPointerReference string `json:"instructionPointerReference"`
+	} `json:"stackFrames"`
+}
+
+type variablesBody struct {
+	Variables []struct {
+		Name            string `json:"name"`
+		Value           string `json:"value"`
+		MemoryReference string `json:"memoryReference"`
+	} `json:"variables"`
+}
+
+type breakpointsBody struct {
+	Breakpoints []struct {
+		ID                   int    `json:"id"`
+		Verified             bool   `json:"verified"`
+		Line                 int    `json:"line"`
+		InstructionReference string `json:"instructionReference"`
+		Message              string `json:"message"`
+	} `json:"breakpoints"`
+}
+
+func writeFile(t *testing.T, name string, data []byte) (string, func()) {
+	dir, err := ioutil.TempDir("", "dap")
+	assert.Nil(t, err)
+	path := filepath.Join(dir, name)
+	assert.Nil(t, ioutil.WriteFile(path, data, 0644))
+	return path, func() { os.RemoveAll(dir) }
+}
+
+func (c *client) launch(args map[string]interface{}) {
+	var caps map[string]bool
+	c.body("initialize", map[string]interface{}{"adapterID": "chip8"}, &caps)
+	assert.True(c.t, caps["supportsConfigurationDoneRequest"])
+	c.body("launch", args, nil)
+	c.event("initialized", nil)
+}
+
+func TestSourceDebugging(t *testing.T) {
+	path, cleanup := writeFile(t, "prog.asm", []byte(testSource))
+	defer cleanup()
+	c := newClient(t)
+	c.launch(map[string]interface{}{"program": path, "stopOnEntry": true})
+
+	var bps breakpointsBody
+	c.body("setBreakpoints", map[string]interface{}{
+		"source":      map[string]string{"path": path},
+		"breakpoints": []map[string]int{{"line": 9}, {"line": 40}},
+	}, &bps)
+	assert.Len(t, bps.Breakpoints, 2)
+	assert.True(t, bps.Breakpoints[0].Verified)
+	assert.Equal(t, 11, bps.Breakpoints[0].Line)
+	assert.Equal(t, "0x020C", bps.Breakpoints[0].InstructionReference)
+	assert.False(t, bps.Breakpoints[1].Verified)
+
+	var stopped stoppedBody
+	c.body("configurationDone", nil, nil)
+	c.event("stopped", &stopped)
+	assert.Equal(t, "entry", stopped.Reason)
+	assert.Equal(t, 1, stopped.ThreadID)
+
+	c.body("continue", map[string]int{"threadId": 1}, nil)
+	c.event("stopped", &stopped)
+	assert.Equal(t, "breakpoint", stopped.Reason)
+	assert.Equal(t, []int{bps.Breakpoints[0].ID}, stopped.HitBreakpointIDs)
+
+	var trace stackTraceBody
+	c.body("stackTrace", map[string]int{"threadId": 1}, &trace)
+	assert.Len(t, trace.StackFrames, 2)
+	assert.Equal(t, "sub", trace.StackFrames[0].Name)
+	assert.Equal(t, 11, trace.StackFrames[0].Line)
+	assert.Equal(t, path, trace.StackFrames[0].Source.Path)
+	assert.Equal(t, "loop+0x2", trace.StackFrames[1].Name)
+	assert.Equal(t, 5, trace.StackFrames[1].Line)
+	assert.Equal(t, "0x0204", trace.StackFrames[1].InstructionPointerReference)
+
+	var scopes struct {
+		Scopes []struct {
+			Name               string `json:"name"`
+			VariablesReference int    `json:"variablesReference"`
+		} `json:"scopes"`
+	}
+	c.body("scopes", map[string]int{"frameId": 0}, &scopes)
+	assert.Len(t, scopes.Scopes, 3)
+	var vars variablesBody
+	c.body("variables", map[string]int{"variablesReference": scopes.Scopes[0].VariablesReference}, &vars)
+	assert.Len(t, vars.Variables, 19)
+	assert.Equal(t, "V3", vars.Variables[3].Name)
+	assert.Equal(t, "0x04", vars.Variables[3].Value)
+	assert.Equal(t, "PC", vars.Variables[17].Name)
+	assert.Equal(t, "0x020c", vars.Variables[17].Value)
+	assert.Equal(t, "0x020C", vars.Variables[17].MemoryReference)
+
+	var eval struct {
+		Result string `json:"result"`
+	}
+	c.body("evaluate", map[string]string{"expression": "V3 * 2"}, &eval)
+	assert.Equal(t, "8 (0x8)", eval.Result)
+
+	c.body("stepOut", map[string]int{"threadId": 1}, nil)
+	c.event("stopped", &stopped)
+	assert.Equal(t, "step", stopped.Reason)
+	c.body("stackTrace", map[string]int{"threadId": 1}, &trace)
+	assert.Len(t, trace.StackFrames, 1)
+	assert.Equal(t, "loop+0x4", trace.StackFrames[0].Name)
+	assert.Equal(t, 6, trace.StackFrames[0].Line)
+
+	// Clearing the breakpoints of the file removes them.
+	c.body("setBreakpoints", map[string]interface{}{"source": map[string]string{"path": path}}, &bps)
+	assert.Empty(t, bps.Breakpoints)
+	c.body("disconnect", nil, nil)
+}
+
+func TestRomDebugging(t *testing.T) {
+	rom, err := asm.Assemble(testSource, chip8.VariantChip8)
+	assert.Nil(t, err)
+	path, cleanup := writeFile(t, "prog.ch8", rom)
+	defer cleanup()
+	c := newClient(t)
+	c.launch(map[string]interface{}{"program": path})
+
+	var bps breakpointsBody
+	c.body("setInstructionBreakpoints", map[string]interface{}{
+		"breakpoints": []map[string]interface{}{{"instructionReference": "0x0200", "offset": 4}},
+	}, &bps)
+	assert.True(t, bps.Breakpoints[0].Verified)
+	assert.Equal(t, "0x0204", bps.Breakpoints[0].InstructionReference)
+
+	var stopped stoppedBody
+	c.body("configurationDone", nil, nil)
+	c.event("stopped", &stopped)
+	assert.Equal(t, "breakpoint", stopped.Reason)
+
+	c.body("next", map[string]int{"threadId": 1}, nil)
+	c.event("stopped", &stopped)
+	assert.Equal(t, "step", stopped.Reason)
+	var trace stackTraceBody
+	c.body("stackTrace", map[string]int{"threadId": 1}, &trace)
+	assert.Equal(t, "0x0206", trace.StackFrames[0].Name)
+	assert.Equal(t, 0, trace.StackFrames[0].Line)
+
+	var mem struct {
+		Address         string `json:"address"`
+		Data            string `json:"data"`
+		UnreadableBytes int    `json:"unreadableBytes"`
+	}
+	c.body("readMemory", map[string]interface{}{"memoryReference": "0x0200", "offset": 2, "count": 4}, &mem)
+	assert.Equal(t, "0x0202", mem.Address)
+	data, err := base64.StdEncoding.DecodeString(mem.Data)
+	assert.Nil(t, err)
+	assert.Equal(t, rom[2:6], data)
+	c.body("readMemory", map[string]interface{}{"memoryReference": "0xFFE", "count": 4}, &mem)
+	assert.Equal(t, 2, mem.UnreadableBytes)
+	bad := c.call("readMemory", map[string]interface{}{"memoryReference": "0x0200", "count": -1})
+	assert.False(t, bad.Success)
+	assert.Equal(t, "bad count -1", bad.Message)
+
+	var dis struct {
+		Instructions []struct {
+			Address     string `json:"address"`
+			Instruction string `json:"instruction"`
+		} `json:"instructions"`
+	}
+	c.body("disassemble", map[string]interface{}{
+		"memoryReference": "0x0206", "instructionOffset": -1, "instructionCount": 3,
+	}, &dis)
+	assert.Len(t, dis.Instructions, 3)
+	assert.Equal(t, "0x0204", dis.Instructions[0].Address)
+	assert.Equal(t, "CALL 0x20C", dis.Instructions[0].Instruction)
+	assert.Equal(t, "LD I, 0x300", dis.Instructions[1].Instruction)
+
+	var vars variablesBody
+	c.body("variables", map[string]int{"variablesReference": keysRef}, &vars)
+	assert.Len(t, vars.Variables, 16)
+	assert.Equal(t, "up", vars.Variables[0].Value)
+	c.body("disconnect", nil, nil)
+}
+
+func TestPauseAndBreakWhileRunning(t *testing.T) {
+	path, cleanup := writeFile(t, "loop.asm", []byte("loop:\n\tADD V0, 1\n\tJP loop\n"))
+	defer cleanup()
+	c := newClient(t)
+	c.launch(map[string]interface{}{"program": path})
+	c.body("configurationDone", nil, nil)
+
+	var stopped stoppedBody
+	c.body("pause", map[string]int{"threadId": 1}, nil)
+	c.event("stopped", &stopped)
+	assert.Equal(t, "pause", stopped.Reason)
+
+	// A breakpoint set while the program runs stops it.
+	c.body("continue", map[string]int{"threadId": 1}, nil)
+	m := c.call("stackTrace", map[string]int{"threadId": 1})
+	assert.False(t, m.Success)
+	assert.Equal(t, errRunning.Error(), m.Message)
+	c.body("setInstructionBreakpoints", map[string]interface{}{
+		"breakpoints": []map[string]string{{"instructionReference": "0x202"}},
+	}, nil)
+	c.event("stopped", &stopped)
+	assert.Equal(t, "breakpoint", stopped.Reason)
+	c.body("disconnect", nil, nil)
+}
+
+func TestExit(t *testing.T) {
+	path, cleanup := writeFile(t, "exit.asm", []byte("\tEXIT\n"))
+	defer cleanup()
+	c := newClient(t)
+	c.launch(map[string]interface{}{"program": path, "variant": "schip"})
+	c.body("configurationDone", nil, nil)
+
+	var exited struct {
+		ExitCode int `json:"exitCode"`
+	}
+	c.event("exited", &exited)
+	assert.Equal(t, 0, exited.ExitCode)
+	c.event("terminated", nil)
+	c.body("disconnect", nil, nil)
+}
+
+func TestErrors(t *testing.T) {
+	c := newClient(t)
+
+	m := c.call("threads", nil)
+	assert.False(t, m.Success)
+	assert.Equal(t, errNotLaunched.Error(), m.Message)
+	m = c.call("launch", map[string]string{"program": "/no/such/rom.ch8"})
+	assert.False(t, m.Success)
+
+	path, cleanup := writeFile(t, "bad.asm", []byte("\tLD V0\n"))
+	defer cleanup()
+	m = c.call("launch", map[string]string{"program": path})
+	assert.False(t, m.Success)
+	assert.Contains(t, m.Message, "bad.asm:1:")
+}
@@ -0,0 +1,104 @@
+package dap
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+
+	"github.com/hermesdt/go-plan8/asm"
+)
+
+// SourceMap relates the addresses of an assembled program to the source
+// lines they came from.
+type SourceMap struct {
+	// lines are the listing lines that produced bytes, by address.
+	lines []asm.Line
+	// labels are the symbols naming the address of a line, by address.
+	labels []label
+}
+
+type label struct {
+	name string
+	addr uint16
+}
+
+func NewSourceMap(p *asm.Program) *SourceMap {
+	m := &SourceMap{}
+	code := map[uint16]bool{}
+	for _, l := range p.Listing {
+		if len(l.Bytes) > 0 {
+			m.lines = append(m.lines, l)
+			code[l.Address] = true
+		}
+	}
+	sort.SliceStable(m.lines, func(i, j int) bool {
+		return m.lines[i].Address < m.lines[j].Address
+	})
+	for name, v := range p.Symbols {
+		if v >= 0 && v <= 0xFFFF && code[uint16(v)] {
+			m.labels = append(m.labels, label{name, uint16(v)})
+		}
+	}
+	sort.Slice(m.labels, func(i, j int) bool {
+		a, b := m.labels[i], m.labels[j]
+		return a.addr < b.addr || a.addr == b.addr && a.name < b.name
+	})
+	return m
+}
+
+// Lookup returns the source position of the line that assembled to
+// addr.
+func (m *SourceMap) Lookup(addr uint16) (asm.Pos, bool) {
+	i := sort.Search(len(m.lines), func(i int) bool {
+		return m.lines[i].Address > addr
+	})
+	if i == 0 {
+		return asm.Pos{}, false
+	}
+	l := m.lines[i-1]
+	if int(addr) >= int(l.Address)+len(l.Bytes) {
+		return asm.Pos{}, false
+	}
+	return l.Pos, true
+}
+
+// Address returns the address of the first line at or after line in
+// file that produced code, and that line.
+func (m *SourceMap) Address(file string, line int) (uint16, int, bool) {
+	file = filepath.Clean(file)
+	var best *asm.Line
+	for i := range m.lines {
+		l := &m.lines[i]
+		if filepath.Clean(l.Pos.File) != file || l.Pos.Line < line {
+			continue
+		}
+		if best == nil || l.Pos.Line < best.Pos.Line {
+			best = l
+		}
+	}
+	if best == nil {
+		return 0, 0, false
+	}
+	return best.Address, best.Pos.Line, true
+}
+
+// Symbol names addr after the closest label at or before it, such as
+// "draw+0x4".
+func (m *SourceMap) Symbol(addr uint16) (string, bool) {
+	i := sort.Search(len(m.labels), func(i int) bool {
+		return m.labels[i].addr > addr
+	})
+	if i == 0 {
+		return "", false
+	}
+	// Prefer the first name of several for the same address.
+	l := m.labels[i-1]
+	for i > 1 && m.labels[i-2].addr == l.addr {
+		i--
+		l = m.labels[i-1]
+	}
+	if l.addr == addr {
+		return l.name, true
+	}
+	return fmt.Sprintf("%s+%#x", l.name, addr-l.addr), true
+}
@@ -0,0 +1,50 @@
+package dap
+
+import (
+	"testing"
+
+	chip8 "github.com/hermesdt/go-plan8"
+	"github.com/hermesdt/go-plan8/asm"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSourceMap(t *testing.T) {
+	a := &asm.Assembler{Variant: chip8.VariantChip8}
+	p, err := a.Assemble("prog.asm", []byte(testSource+"LIMIT = 0x20D\ndata: DB 1, 2, 3\n"))
+	assert.Nil(t, err)
+	m := NewSourceMap(p)
+
+	pos, ok := m.Lookup(0x20C)
+	assert.True(t, ok)
+	assert.Equal(t, asm.Pos{File: "prog.asm", Line: 11, Col: 1}, pos)
+	pos, ok = m.Lookup(0x212)
+	assert.True(t, ok)
+	assert.Equal(t, 14, pos.Line)
+	_, ok = m.Lookup(0x213)
+	assert.False(t, ok)
+
+	addr, line, ok := m.Address("./prog.asm", 8)
+	assert.True(t, ok)
+	assert.Equal(t, uint16(0x20A), addr)
+	assert.Equal(t, 8, line)
+	addr, line, ok = m.Address("prog.asm", 9)
+	assert.True(t, ok)
+	assert.Equal(t, uint16(0x20C), addr)
+	assert.Equal(t, 11, line)
+	_, _, ok = m.Address("other.asm", 1)
+	assert.False(t, ok)
+
+	for addr, want := range map[uint16]string{
+		0x200: "start",
+		0x202: "loop",
+		0x206: "loop+0x4",
+		0x20C: "sub",
+		0x211: "data+0x1",
+	} {
+		name, ok := m.Symbol(addr)
+		assert.True(t, ok)
+		assert.Equal(t, want, name, "%#x", addr)
+	}
+	_, ok = m.Symbol(0x1FF)
+	assert.False(t, ok)
+}
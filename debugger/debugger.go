@@ -0,0 +1,285 @@
+// Package debugger controls a chip8.Machine one instruction at a time,
+// with breakpoints, watchpoints and state dumps. Frontends such as the
+// terminal REPL drive a Debugger and present its stops.
+package debugger
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+
+	chip8 "github.com/hermesdt/go-plan8"
+)
+
+// AnyAddress makes a breakpoint test its condition before every
+// instruction.
+const AnyAddress = -1
+
+// interruptEvery is how many instructions run between checks for a
+// cancelled context.
+const interruptEvery = 1024
+
+var ErrNoSuchPoint = errors.New("debugger: no breakpoint or watchpoint with that id")
+
+// Debugger runs a machine in emulated time, see chip8.Machine.Emulate,
+// so a program behaves the same however it is stepped.
+type Debugger struct {
+	Machine *chip8.Machine
+
+	breakpoints []*Breakpoint
+	watchpoints []*Watchpoint
+	nextID      int
+}
+
+type Breakpoint struct {
+	ID int
+	// Addr is the address the breakpoint stops at, or AnyAddress.
+	Addr int
+	// Cond, when set, must evaluate to non-zero for the breakpoint to
+	// stop.
+	Cond *Expr
+	Hits int
+}
+
+func (b *Breakpoint) String() string {
+	s := fmt.Sprintf("breakpoint %d", b.ID)
+	if b.Addr != AnyAddress {
+		s += fmt.Sprintf(" at %#04x", b.Addr)
+	}
+	if b.Cond != nil {
+		s += fmt.Sprintf(" if %v", b.Cond)
+	}
+	return s
+}
+
+// Watchpoint stops after an instruction changes Len bytes of memory
+// from Addr or, for WatchI, the I register.
+type Watchpoint struct {
+	ID   int
+	Kind WatchKind
+	Addr uint16
+	Len  int
+	Hits int
+
+	last []byte
+}
+
+type WatchKind int
+
+const (
+	WatchMemory WatchKind = iota
+	WatchI
+)
+
+func (w *Watchpoint) String() string {
+	if w.Kind == WatchI {
+		return fmt.Sprintf("watchpoint %d on I", w.ID)
+	}
+	return fmt.Sprintf("watchpoint %d on %#04x-%#04x", w.ID, w.Addr, int(w.Addr)+w.Len-1)
+}
+
+func (w *Watchpoint) read(c *chip8.Chip8) []byte {
+	if w.Kind == WatchI {
+		return []byte{byte(c.I >> 8), byte(c.I)}
+	}
+	bs := make([]byte, w.Len)
+	for i := range bs {
+		bs[i] = c.Memory[uint16(int(w.Addr)+i)]
+	}
+	return bs
+}
+
+type StopReason int
+
+const (
+	// StopStep means the requested steps completed.
+	StopStep StopReason = iota
+	StopBreakpoint
+	StopWatchpoint
+	// StopHalted means an instruction failed and the machine halted.
+	StopHalted
+	// StopInterrupted means the context was cancelled.
+	StopInterrupted
+)
+
+// Stop describes why execution stopped.
+type Stop struct {
+	Reason     StopReason
+	PC         uint16
+	Breakpoint *Breakpoint
+	Watchpoint *Watchpoint
+	// Old and New are the watched bytes before and after the change.
+	Old, New []byte
+	Err      error
+}
+
+func (s Stop) String() string {
+	switch s.Reason {
+	case StopBreakpoint:
+		return fmt.Sprintf("hit %v", s.Breakpoint)
+	case StopWatchpoint:
+		return fmt.Sprintf("hit %v: % X -> % X", s.Watchpoint, s.Old, s.New)
+	case StopHalted:
+		return fmt.Sprintf("halted: %v", s.Err)
+	case StopInterrupted:
+		return fmt.Sprintf("interrupted at %#04x", s.PC)
+	}
+	return fmt.Sprintf("stopped at %#04x", s.PC)
+}
+
+func New(m *chip8.Machine) *Debugger {
+	return &Debugger{Machine: m, nextID: 1}
+}
+
+// Break adds a breakpoint at addr, or AnyAddress, that stops when cond
+// holds. An empty cond always holds.
+func (d *Debugger) Break(addr int, cond string) (*Breakpoint, error) {
+	b := &Breakpoint{Addr: addr}
+	if cond != "" {
+		e, err := ParseExpr(cond)
+		if err != nil {
+			return nil, err
+		}
+		b.Cond = e
+	}
+	b.ID = d.id()
+	d.breakpoints = append(d.breakpoints, b)
+	return b, nil
+}
+
+// Watch adds a watchpoint on n bytes of memory starting at addr.
+func (d *Debugger) Watch(addr uint16, n int) (*Watchpoint, error) {
+	if n <= 0 {
+		return nil, fmt.Errorf("debugger: cannot watch %d bytes", n)
+	}
+	w := &Watchpoint{ID: d.id(), Kind: WatchMemory, Addr: addr, Len: n}
+	d.watchpoints = append(d.watchpoints, w)
+	return w, nil
+}
+
+// WatchI adds a watchpoint on the I register.
+func (d *Debugger) WatchI() *Watchpoint {
+	w := &Watchpoint{ID: d.id(), Kind: WatchI, Len: 2}
+	d.watchpoints = append(d.watchpoints, w)
+	return w
+}
+
+func (d *Debugger) id() int {
+	id := d.nextID
+	d.nextID++
+	return id
+}
+
+// Delete removes the breakpoint or watchpoint with the given id.
+func (d *Debugger) Delete(id int) error {
+	for i, b := range d.breakpoints {
+		if b.ID == id {
+			d.breakpoints = append(d.breakpoints[:i], d.breakpoints[i+1:]...)
+			return nil
+		}
+	}
+	for i, w := range d.watchpoints {
+		if w.ID == id {
+			d.watchpoints = append(d.watchpoints[:i], d.watchpoints[i+1:]...)
+			return nil
+		}
+	}
+	return ErrNoSuchPoint
+}
+
+func (d *Debugger) Breakpoints() []*Breakpoint {
+	return append([]*Breakpoint(nil), d.breakpoints...)
+}
+
+func (d *Debugger) Watchpoints() []*Watchpoint {
+	return append([]*Watchpoint(nil), d.watchpoints...)
+}
+
+// Step executes n instructions, stopping early at breakpoints and
+// watchpoints.
+func (d *Debugger) Step(ctx context.Context, n int) Stop {
+	left := n
+	return d.run(ctx, func(*chip8.Chip8) bool {
+		left--
+		return left <= 0
+	})
+}
+
+// Next steps over the instruction at PC, running a subroutine it calls
+// until it returns.
+func (d *Debugger) Next(ctx context.Context) Stop {
+	c := d.Machine.Chip8
+	op := chip8.Decode(c.FetchOpcode().Value, c.Variant)
+	if op == nil || op.Name != "CallSub" {
+		return d.Step(ctx, 1)
+	}
+	ret, sp := c.PC+2, c.SP
+	return d.run(ctx, func(c *chip8.Chip8) bool {
+		return c.PC == ret && c.SP == sp
+	})
+}
+
+// Finish runs until the current subroutine returns. At the top level
+// it runs like Continue.
+func (d *Debugger) Finish(ctx context.Context) Stop {
+	sp := d.Machine.Chip8.SP
+	return d.run(ctx, func(c *chip8.Chip8) bool {
+		return c.SP < sp
+	})
+}
+
+// Continue runs until a breakpoint or watchpoint triggers, the machine
+// halts or ctx is cancelled.
+func (d *Debugger) Continue(ctx context.Context) Stop {
+	return d.run(ctx, nil)
+}
+
+// run executes instructions until done reports true after one of them.
+// Breakpoints are not tested before the first instruction, so running
+// again from a breakpoint moves past it.
+func (d *Debugger) run(ctx context.Context, done func(c *chip8.Chip8) bool) Stop {
+	c := d.Machine.Chip8
+	for _, w := range d.watchpoints {
+		w.last = w.read(c)
+	}
+	for i := 0; ; i++ {
+		if i > 0 {
+			if b := d.hit(c); b != nil {
+				return Stop{Reason: StopBreakpoint, PC: c.PC, Breakpoint: b}
+			}
+		}
+		if i%interruptEvery == 0 && ctx.Err() != nil {
+			return Stop{Reason: StopInterrupted, PC: c.PC, Err: ctx.Err()}
+		}
+		if err := d.Machine.Emulate(1); err != nil {
+			return Stop{Reason: StopHalted, PC: c.PC, Err: err}
+		}
+		for _, w := range d.watchpoints {
+			now := w.read(c)
+			if !bytes.Equal(now, w.last) {
+				w.Hits++
+				old := w.last
+				w.last = now
+				return Stop{Reason: StopWatchpoint, PC: c.PC, Watchpoint: w, Old: old, New: now}
+			}
+		}
+		if done != nil && done(c) {
+			return Stop{Reason: StopStep, PC: c.PC}
+		}
+	}
+}
+
+func (d *Debugger) hit(c *chip8.Chip8) *Breakpoint {
+	for _, b := range d.breakpoints {
+		if b.Addr != AnyAddress && b.Addr != int(c.PC) {
+			continue
+		}
+		if b.Cond != nil && b.Cond.Eval(c) == 0 {
+			continue
+		}
+		b.Hits++
+		return b
+	}
+	return nil
+}
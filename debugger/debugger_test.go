@@ -0,0 +1,152 @@
+package debugger
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	chip8 "github.com/hermesdt/go-plan8"
+	"github.com/hermesdt/go-plan8/asm"
+	"github.com/stretchr/testify/assert"
+)
+
+const testProgram = `
+	start:
+		LD V3, 0          ; 200
+	loop:
+		ADD V3, 4         ; 202
+		CALL sub          ; 204
+		LD I, 0x300       ; 206
+		LD [I], V3        ; 208
+		JP loop           ; 20A
+	sub:
+		ADD V1, 1         ; 20C
+		RET               ; 20E
+`
+
+func newDebugger(t *testing.T, src string) *Debugger {
+	rom, err := asm.Assemble(src, chip8.VariantChip8)
+	assert.Nil(t, err)
+	c := chip8.NewChip8(chip8.VariantChip8)
+	assert.Nil(t, c.LoadRomBytes(rom))
+	return New(chip8.NewMachine(c))
+}
+
+func TestStep(t *testing.T) {
+	d := newDebugger(t, testProgram)
+
+	s := d.Step(context.Background(), 3)
+
+	assert.Equal(t, StopStep, s.Reason)
+	assert.Equal(t, uint16(0x20C), s.PC)
+	assert.Equal(t, uint64(3), d.Machine.Cycles)
+}
+
+func TestNextAndFinish(t *testing.T) {
+	d := newDebugger(t, testProgram)
+	ctx := context.Background()
+	d.Step(ctx, 2)
+
+	s := d.Next(ctx)
+	assert.Equal(t, StopStep, s.Reason)
+	assert.Equal(t, uint16(0x206), s.PC)
+	assert.Equal(t, uint8(1), d.Machine.Chip8.V[1])
+
+	d.Step(ctx, 5)
+	assert.Equal(t, uint16(0x20C), d.Machine.Chip8.PC)
+	s = d.Finish(ctx)
+	assert.Equal(t, uint16(0x206), s.PC)
+	assert.Equal(t, uint16(0), d.Machine.Chip8.SP)
+}
+
+func TestBreakpoint(t *testing.T) {
+	d := newDebugger(t, testProgram)
+	ctx := context.Background()
+	b, err := d.Break(0x20C, "V3 == 0x10")
+	assert.Nil(t, err)
+
+	s := d.Continue(ctx)
+	assert.Equal(t, StopBreakpoint, s.Reason)
+	assert.Equal(t, b, s.Breakpoint)
+	assert.Equal(t, uint8(0x10), d.Machine.Chip8.V[3])
+	assert.Equal(t, 1, b.Hits)
+
+	// Continuing moves past the breakpoint instead of stopping again.
+	assert.Nil(t, d.Delete(b.ID))
+	_, err = d.Break(AnyAddress, "V1 == 6")
+	assert.Nil(t, err)
+	s = d.Continue(ctx)
+	assert.Equal(t, StopBreakpoint, s.Reason)
+	assert.Equal(t, uint16(0x20E), s.PC)
+}
+
+func TestWatchpoint(t *testing.T) {
+	d := newDebugger(t, testProgram)
+	ctx := context.Background()
+
+	wi := d.WatchI()
+	s := d.Continue(ctx)
+	assert.Equal(t, StopWatchpoint, s.Reason)
+	assert.Equal(t, wi, s.Watchpoint)
+	assert.Equal(t, []byte{0x03, 0x00}, s.New)
+	assert.Nil(t, d.Delete(wi.ID))
+
+	w, err := d.Watch(0x2FF, 4)
+	assert.Nil(t, err)
+	s = d.Continue(ctx)
+	assert.Equal(t, StopWatchpoint, s.Reason)
+	assert.Equal(t, w, s.Watchpoint)
+	assert.Equal(t, []byte{0, 0, 0, 0}, s.Old)
+	assert.Equal(t, []byte{0, 0, 1, 0}, s.New)
+	assert.Equal(t, uint16(0x20A), s.PC)
+	assert.Equal(t, "hit watchpoint 2 on 0x02ff-0x0302: 00 00 00 00 -> 00 00 01 00", s.String())
+}
+
+func TestHaltAndInterrupt(t *testing.T) {
+	d := newDebugger(t, "RET")
+	s := d.Continue(context.Background())
+	assert.Equal(t, StopHalted, s.Reason)
+	assert.Equal(t, chip8.ErrStackUnderflow, unwrap(s.Err))
+
+	d = newDebugger(t, testProgram)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	s = d.Continue(ctx)
+	assert.Equal(t, StopInterrupted, s.Reason)
+}
+
+func unwrap(err error) error {
+	if e, ok := err.(*chip8.ExecError); ok {
+		return e.Err
+	}
+	return err
+}
+
+func TestDumps(t *testing.T) {
+	d := newDebugger(t, testProgram)
+	d.Step(context.Background(), 3)
+	_, err := d.Break(0x20E, "")
+	assert.Nil(t, err)
+
+	var buf bytes.Buffer
+	d.WriteRegisters(&buf)
+	assert.Equal(t, ""+
+		"V0=00 V1=00 V2=00 V3=04 V4=00 V5=00 V6=00 V7=00\n"+
+		"V8=00 V9=00 VA=00 VB=00 VC=00 VD=00 VE=00 VF=00\n"+
+		"I=0000 PC=020C SP=1 DT=00 ST=00 cycles=3\n", buf.String())
+
+	buf.Reset()
+	d.WriteStack(&buf)
+	assert.Equal(t, "#0 0204\n", buf.String())
+
+	buf.Reset()
+	d.WriteMemory(&buf, 0x200, 4)
+	assert.Equal(t, "0200  63 00 73 04\n", buf.String())
+
+	buf.Reset()
+	d.WriteDisasm(&buf, 0x20A, 3, 0)
+	assert.Equal(t, ""+
+		"    020A  1202      JP 0x202\n"+
+		"=>  020C  7101      ADD V1, 0x01\n"+
+		"  * 020E  00EE      RET\n", buf.String())
+}
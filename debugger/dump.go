@@ -0,0 +1,81 @@
+package debugger
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/hermesdt/go-plan8/disasm"
+)
+
+// WriteRegisters prints the registers, timers and cycle count.
+func (d *Debugger) WriteRegisters(w io.Writer) error {
+	c := d.Machine.Chip8
+	for row := 0; row < 2; row++ {
+		for x := row * 8; x < row*8+8; x++ {
+			if x%8 > 0 {
+				fmt.Fprint(w, " ")
+			}
+			fmt.Fprintf(w, "V%X=%02X", x, c.V[x])
+		}
+		fmt.Fprintln(w)
+	}
+	_, err := fmt.Fprintf(w, "I=%04X PC=%04X SP=%X DT=%02X ST=%02X cycles=%d\n",
+		c.I, c.PC, c.SP, c.DelayTimer, c.SoundTimer, d.Machine.Cycles)
+	return err
+}
+
+// WriteStack prints the return addresses on the stack, innermost
+// first.
+func (d *Debugger) WriteStack(w io.Writer) error {
+	c := d.Machine.Chip8
+	if c.SP == 0 {
+		_, err := fmt.Fprintln(w, "stack is empty")
+		return err
+	}
+	for i := int(c.SP) - 1; i >= 0 && i < len(c.Stack); i-- {
+		if _, err := fmt.Fprintf(w, "#%d %04X\n", int(c.SP)-1-i, c.Stack[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WriteMemory hex dumps n bytes from addr, 16 to a line.
+func (d *Debugger) WriteMemory(w io.Writer, addr uint16, n int) error {
+	c := d.Machine.Chip8
+	for off := 0; off < n; off += 16 {
+		line := fmt.Sprintf("%04X ", uint16(int(addr)+off))
+		for i := off; i < off+16 && i < n; i++ {
+			line += fmt.Sprintf(" %02X", c.Memory[uint16(int(addr)+i)])
+		}
+		if _, err := fmt.Fprintln(w, line); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WriteDisasm decodes n instructions from addr, marking the one at PC
+// with "=>" and those with breakpoints with "*".
+func (d *Debugger) WriteDisasm(w io.Writer, addr uint16, n int, s disasm.Syntax) error {
+	c := d.Machine.Chip8
+	size := c.Variant.MemorySize()
+	for i := 0; i < n && int(addr) < size; i++ {
+		in := disasm.Decode(c.Memory[addr:size], addr, c.Variant)
+		mark := "  "
+		if addr == c.PC {
+			mark = "=>"
+		}
+		bp := " "
+		for _, b := range d.breakpoints {
+			if b.Addr == int(addr) {
+				bp = "*"
+			}
+		}
+		if _, err := fmt.Fprintf(w, "%s%s %04X  %-9X %s\n", mark, bp, addr, in.Bytes, in.Format(s)); err != nil {
+			return err
+		}
+		addr += uint16(len(in.Bytes))
+	}
+	return nil
+}
@@ -0,0 +1,260 @@
+package debugger
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	chip8 "github.com/hermesdt/go-plan8"
+)
+
+// Expr is an expression over the machine state, such as "V3 == 0x10"
+// or "[I] != 0 && DT == 0". It may use the registers V0-VF, I, PC, SP,
+// DT and ST, numbers, [addr] for the memory byte at addr, and the C
+// operators with their usual precedence. Division by zero yields 0.
+type Expr struct {
+	src  string
+	eval func(c *chip8.Chip8) int64
+}
+
+func ParseExpr(src string) (*Expr, error) {
+	toks, err := lexExpr(src)
+	if err != nil {
+		return nil, err
+	}
+	p := &exprParser{src: src, toks: toks}
+	eval, err := p.binary(0)
+	if err != nil {
+		return nil, err
+	}
+	if len(p.toks) > 0 {
+		return nil, p.errorf(p.toks[0], "unexpected %q", p.toks[0].text)
+	}
+	return &Expr{src: src, eval: eval}, nil
+}
+
+func (e *Expr) Eval(c *chip8.Chip8) int64 {
+	return e.eval(c)
+}
+
+func (e *Expr) String() string {
+	return e.src
+}
+
+type exprToken struct {
+	text string
+	col  int
+}
+
+var exprPuncts = []string{"||", "&&", "==", "!=", "<=", ">=", "<<", ">>", "<", ">", "+", "-", "*", "/", "%", "&", "|", "^", "!", "~", "(", ")", "[", "]"}
+
+func lexExpr(src string) ([]exprToken, error) {
+	var toks []exprToken
+	for i := 0; i < len(src); {
+		c := src[i]
+		if c == ' ' || c == '\t' {
+			i++
+			continue
+		}
+		start := i
+		if isWord(c) {
+			for i < len(src) && isWord(src[i]) {
+				i++
+			}
+			toks = append(toks, exprToken{src[start:i], start + 1})
+			continue
+		}
+		matched := false
+		for _, p := range exprPuncts {
+			if strings.HasPrefix(src[i:], p) {
+				toks = append(toks, exprToken{p, start + 1})
+				i += len(p)
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return nil, fmt.Errorf("debugger: unexpected %q at column %d", c, start+1)
+		}
+	}
+	return toks, nil
+}
+
+func isWord(c byte) bool {
+	return c == '_' || c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+type evalFunc func(c *chip8.Chip8) int64
+
+// Binary operators by precedence, loosest first.
+var exprLevels = [][]string{
+	{"||"},
+	{"&&"},
+	{"==", "!=", "<", "<=", ">", ">="},
+	{"|"},
+	{"^"},
+	{"&"},
+	{"<<", ">>"},
+	{"+", "-"},
+	{"*", "/", "%"},
+}
+
+type exprParser struct {
+	src  string
+	toks []exprToken
+}
+
+func (p *exprParser) errorf(t exprToken, format string, args ...interface{}) error {
+	return fmt.Errorf("debugger: %s at column %d", fmt.Sprintf(format, args...), t.col)
+}
+
+func (p *exprParser) binary(level int) (evalFunc, error) {
+	if level == len(exprLevels) {
+		return p.unary()
+	}
+	l, err := p.binary(level + 1)
+	if err != nil {
+		return nil, err
+	}
+	for len(p.toks) > 0 && contains(exprLevels[level], p.toks[0].text) {
+		op := p.toks[0].text
+		p.toks = p.toks[1:]
+		r, err := p.binary(level + 1)
+		if err != nil {
+			return nil, err
+		}
+		l = binaryOp(op, l, r)
+	}
+	return l, nil
+}
+
+func binaryOp(op string, l, r evalFunc) evalFunc {
+	f := map[string]func(a, b int64) int64{
+		"||": func(a, b int64) int64 { return b2i(a != 0 || b != 0) },
+		"&&": func(a, b int64) int64 { return b2i(a != 0 && b != 0) },
+		"==": func(a, b int64) int64 { return b2i(a == b) },
+		"!=": func(a, b int64) int64 { return b2i(a != b) },
+		"<":  func(a, b int64) int64 { return b2i(a < b) },
+		"<=": func(a, b int64) int64 { return b2i(a <= b) },
+		">":  func(a, b int64) int64 { return b2i(a > b) },
+		">=": func(a, b int64) int64 { return b2i(a >= b) },
+		"|":  func(a, b int64) int64 { return a | b },
+		"^":  func(a, b int64) int64 { return a ^ b },
+		"&":  func(a, b int64) int64 { return a & b },
+		"<<": func(a, b int64) int64 { return a << uint64(b) },
+		">>": func(a, b int64) int64 { return a >> uint64(b) },
+		"+":  func(a, b int64) int64 { return a + b },
+		"-":  func(a, b int64) int64 { return a - b },
+		"*":  func(a, b int64) int64 { return a * b },
+		"/": func(a, b int64) int64 {
+			if b == 0 {
+				return 0
+			}
+			return a / b
+		},
+		"%": func(a, b int64) int64 {
+			if b == 0 {
+				return 0
+			}
+			return a % b
+		},
+	}[op]
+	return func(c *chip8.Chip8) int64 {
+		return f(l(c), r(c))
+	}
+}
+
+func (p *exprParser) unary() (evalFunc, error) {
+	if len(p.toks) == 0 {
+		return nil, fmt.Errorf("debugger: incomplete expression %q", p.src)
+	}
+	t := p.toks[0]
+	p.toks = p.toks[1:]
+	switch t.text {
+	case "-", "!", "~":
+		v, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		switch t.text {
+		case "-":
+			return func(c *chip8.Chip8) int64 { return -v(c) }, nil
+		case "!":
+			return func(c *chip8.Chip8) int64 { return b2i(v(c) == 0) }, nil
+		}
+		return func(c *chip8.Chip8) int64 { return ^v(c) }, nil
+	case "(", "[":
+		v, err := p.binary(0)
+		if err != nil {
+			return nil, err
+		}
+		closing := map[string]string{"(": ")", "[": "]"}[t.text]
+		if len(p.toks) == 0 || p.toks[0].text != closing {
+			return nil, p.errorf(t, "unbalanced %s", t.text)
+		}
+		p.toks = p.toks[1:]
+		if t.text == "(" {
+			return v, nil
+		}
+		return func(c *chip8.Chip8) int64 {
+			return int64(c.Memory[uint16(v(c))])
+		}, nil
+	}
+	if r := register(t.text); r != nil {
+		return r, nil
+	}
+	if v, err := parseNumber(t.text); err == nil {
+		return func(*chip8.Chip8) int64 { return v }, nil
+	}
+	return nil, p.errorf(t, "unexpected %q", t.text)
+}
+
+func register(name string) evalFunc {
+	switch strings.ToUpper(name) {
+	case "I":
+		return func(c *chip8.Chip8) int64 { return int64(c.I) }
+	case "PC":
+		return func(c *chip8.Chip8) int64 { return int64(c.PC) }
+	case "SP":
+		return func(c *chip8.Chip8) int64 { return int64(c.SP) }
+	case "DT":
+		return func(c *chip8.Chip8) int64 { return int64(c.DelayTimer) }
+	case "ST":
+		return func(c *chip8.Chip8) int64 { return int64(c.SoundTimer) }
+	}
+	if len(name) == 2 && (name[0] == 'V' || name[0] == 'v') {
+		if x, err := strconv.ParseUint(name[1:], 16, 8); err == nil {
+			return func(c *chip8.Chip8) int64 { return int64(c.V[x]) }
+		}
+	}
+	return nil
+}
+
+// parseNumber reads decimal, 0x hex and 0b binary numbers. A leading
+// zero does not mean octal, so 0200 is two hundred.
+func parseNumber(s string) (int64, error) {
+	lower := strings.ToLower(s)
+	switch {
+	case strings.HasPrefix(lower, "0x"):
+		return strconv.ParseInt(s[2:], 16, 64)
+	case strings.HasPrefix(lower, "0b"):
+		return strconv.ParseInt(s[2:], 2, 64)
+	}
+	return strconv.ParseInt(s, 10, 64)
+}
+
+func b2i(b bool) int64 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+func contains(list []string, s string) bool {
+	for _, e := range list {
+		if e == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,54 @@
+package debugger
+
+import (
+	"testing"
+
+	chip8 "github.com/hermesdt/go-plan8"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExpr(t *testing.T) {
+	c := chip8.NewChip8(chip8.VariantChip8)
+	c.V[3] = 0x10
+	c.V[0xF] = 1
+	c.I = 0x300
+	c.Memory[0x301] = 7
+	c.DelayTimer = 2
+
+	cases := map[string]int64{
+		"V3 == 0x10":           1,
+		"v3 != 16":             0,
+		"VF + 2 * 3":           7,
+		"(VF + 2) * 3":         9,
+		"[I + 1]":              7,
+		"I > 0x2FF && DT == 2": 1,
+		"PC < 0x200 || !DT":    0,
+		"~0 & 0xF0 | 1 << 1":   0xF2,
+		"-V3 / 0":              0,
+		"0200":                 200,
+		"0b101 % 3":            2,
+	}
+	for src, want := range cases {
+		e, err := ParseExpr(src)
+		if assert.Nil(t, err, src) {
+			assert.Equal(t, want, e.Eval(c), src)
+		}
+	}
+}
+
+func TestExpr_errors(t *testing.T) {
+	cases := map[string]string{
+		"V3 ==":     `debugger: incomplete expression "V3 =="`,
+		"V3 = 1":    `debugger: unexpected '=' at column 4`,
+		"(V3 + 1":   `debugger: unbalanced ( at column 1`,
+		"VG":        `debugger: unexpected "VG" at column 1`,
+		"V1 V2":     `debugger: unexpected "V2" at column 4`,
+		"V1 == $10": `debugger: unexpected '$' at column 7`,
+	}
+	for src, want := range cases {
+		_, err := ParseExpr(src)
+		if assert.NotNil(t, err, src) {
+			assert.Equal(t, want, err.Error(), src)
+		}
+	}
+}
@@ -0,0 +1,283 @@
+package debugger
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/hermesdt/go-plan8/disasm"
+)
+
+// ErrQuit is returned by Exec for the quit command.
+var ErrQuit = errors.New("debugger: quit")
+
+// REPL is a line oriented command interface to a Debugger, in the
+// spirit of gdb. An empty line repeats the previous command.
+type REPL struct {
+	Debugger *Debugger
+	Out      io.Writer
+	Syntax   disasm.Syntax
+
+	last   string
+	mu     sync.Mutex
+	cancel context.CancelFunc
+}
+
+func NewREPL(d *Debugger, out io.Writer) *REPL {
+	return &REPL{Debugger: d, Out: out}
+}
+
+const replHelp = `step [n]              execute n instructions (s)
+next                  step over subroutine calls (n)
+finish                run until the current subroutine returns
+continue              run until a breakpoint or watchpoint (c)
+break ADDR [if COND]  stop at ADDR, optionally only when COND holds (b)
+break if COND         stop before any instruction where COND holds
+watch ADDR [LEN]      stop when LEN bytes from ADDR change
+watch I               stop when I changes
+delete ID             remove a breakpoint or watchpoint (d)
+info                  list breakpoints and watchpoints
+regs                  print the registers (r)
+stack                 print the call stack
+x ADDR [LEN]          dump memory
+list [ADDR]           disassemble around PC or from ADDR (l)
+print EXPR            evaluate an expression (p)
+press KEY, release KEY  change the state of a key
+screen                print the screen
+quit                  leave the debugger (q)
+Expressions use V0-VF, I, PC, SP, DT, ST, [ADDR] for memory and C operators.
+`
+
+// Run reads commands from in until it ends or quit is entered.
+func (r *REPL) Run(in io.Reader) error {
+	scanner := bufio.NewScanner(in)
+	for {
+		fmt.Fprint(r.Out, "(chip8) ")
+		if !scanner.Scan() {
+			fmt.Fprintln(r.Out)
+			return scanner.Err()
+		}
+		err := r.Exec(scanner.Text())
+		if err == ErrQuit {
+			return nil
+		}
+		if err != nil {
+			fmt.Fprintln(r.Out, err)
+		}
+	}
+}
+
+// Interrupt stops the command that is running, if any. It may be
+// called from another goroutine, for example on SIGINT.
+func (r *REPL) Interrupt() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.cancel != nil {
+		r.cancel()
+	}
+}
+
+// Exec runs one command line.
+func (r *REPL) Exec(line string) error {
+	line = strings.TrimSpace(line)
+	if line == "" {
+		line = r.last
+	}
+	r.last = line
+	fields := strings.Fields(line)
+	if len(fields) == 0 {
+		return nil
+	}
+	cmd, args := fields[0], fields[1:]
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	r.mu.Lock()
+	r.cancel = cancel
+	r.mu.Unlock()
+	defer func() {
+		r.mu.Lock()
+		r.cancel = nil
+		r.mu.Unlock()
+	}()
+
+	d := r.Debugger
+	switch cmd {
+	case "step", "s":
+		n := 1
+		if len(args) > 0 {
+			v, err := r.eval(strings.Join(args, " "))
+			if err != nil {
+				return err
+			}
+			n = int(v)
+		}
+		return r.stopped(d.Step(ctx, n))
+	case "next", "n":
+		return r.stopped(d.Next(ctx))
+	case "finish":
+		return r.stopped(d.Finish(ctx))
+	case "continue", "c":
+		return r.stopped(d.Continue(ctx))
+	case "break", "b":
+		return r.breakCmd(args)
+	case "watch":
+		return r.watchCmd(args)
+	case "delete", "d":
+		if len(args) != 1 {
+			return errors.New("usage: delete ID")
+		}
+		id, err := strconv.Atoi(args[0])
+		if err != nil {
+			return err
+		}
+		return d.Delete(id)
+	case "info":
+		for _, b := range d.Breakpoints() {
+			fmt.Fprintf(r.Out, "%v, hit %d times\n", b, b.Hits)
+		}
+		for _, w := range d.Watchpoints() {
+			fmt.Fprintf(r.Out, "%v, hit %d times\n", w, w.Hits)
+		}
+		return nil
+	case "regs", "r":
+		return d.WriteRegisters(r.Out)
+	case "stack":
+		return d.WriteStack(r.Out)
+	case "x":
+		if len(args) == 0 || len(args) > 2 {
+			return errors.New("usage: x ADDR [LEN]")
+		}
+		addr, err := r.eval(args[0])
+		if err != nil {
+			return err
+		}
+		n := int64(16)
+		if len(args) == 2 {
+			if n, err = r.eval(args[1]); err != nil {
+				return err
+			}
+		}
+		return d.WriteMemory(r.Out, uint16(addr), int(n))
+	case "list", "l":
+		addr := int64(d.Machine.Chip8.PC) - 8
+		if len(args) > 0 {
+			v, err := r.eval(strings.Join(args, " "))
+			if err != nil {
+				return err
+			}
+			addr = v
+		}
+		if addr < 0 {
+			addr = 0
+		}
+		return d.WriteDisasm(r.Out, uint16(addr), 10, r.Syntax)
+	case "print", "p":
+		v, err := r.eval(strings.Join(args, " "))
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintf(r.Out, "%d (%#x)\n", v, v)
+		return err
+	case "press", "release":
+		if len(args) != 1 {
+			return fmt.Errorf("usage: %s KEY", cmd)
+		}
+		k, err := strconv.ParseUint(args[0], 16, 4)
+		if err != nil {
+			return fmt.Errorf("%s: key must be a hex digit", cmd)
+		}
+		if cmd == "press" {
+			d.Machine.Chip8.Key.Press(uint8(k))
+		} else {
+			d.Machine.Chip8.Key.Release(uint8(k))
+		}
+		return nil
+	case "screen":
+		_, err := fmt.Fprintln(r.Out, d.Machine.Chip8.Screen.Render())
+		return err
+	case "help", "h":
+		_, err := fmt.Fprint(r.Out, replHelp)
+		return err
+	case "quit", "q":
+		return ErrQuit
+	}
+	return fmt.Errorf("unknown command %q, try help", cmd)
+}
+
+func (r *REPL) eval(src string) (int64, error) {
+	e, err := ParseExpr(src)
+	if err != nil {
+		return 0, err
+	}
+	return e.Eval(r.Debugger.Machine.Chip8), nil
+}
+
+// stopped reports a stop and the instruction execution stopped at.
+func (r *REPL) stopped(s Stop) error {
+	if s.Reason != StopStep {
+		fmt.Fprintln(r.Out, s)
+	}
+	return r.Debugger.WriteDisasm(r.Out, r.Debugger.Machine.Chip8.PC, 1, r.Syntax)
+}
+
+func (r *REPL) breakCmd(args []string) error {
+	if len(args) == 0 {
+		return errors.New("usage: break ADDR [if COND]")
+	}
+	addr, cond := AnyAddress, ""
+	where := args
+	for i, a := range args {
+		if a == "if" {
+			where, cond = args[:i], strings.Join(args[i+1:], " ")
+			break
+		}
+	}
+	if len(where) > 0 {
+		v, err := r.eval(strings.Join(where, " "))
+		if err != nil {
+			return err
+		}
+		addr = int(v)
+	}
+	if addr == AnyAddress && cond == "" {
+		return errors.New("usage: break ADDR [if COND]")
+	}
+	b, err := r.Debugger.Break(addr, cond)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(r.Out, b)
+	return err
+}
+
+func (r *REPL) watchCmd(args []string) error {
+	if len(args) == 1 && strings.EqualFold(args[0], "I") {
+		_, err := fmt.Fprintln(r.Out, r.Debugger.WatchI())
+		return err
+	}
+	if len(args) == 0 || len(args) > 2 {
+		return errors.New("usage: watch ADDR [LEN] | watch I")
+	}
+	addr, err := r.eval(args[0])
+	if err != nil {
+		return err
+	}
+	n := int64(1)
+	if len(args) == 2 {
+		if n, err = r.eval(args[1]); err != nil {
+			return err
+		}
+	}
+	w, err := r.Debugger.Watch(uint16(addr), int(n))
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(r.Out, w)
+	return err
+}
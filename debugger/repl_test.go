@@ -0,0 +1,46 @@
+package debugger
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestREPL(t *testing.T) {
+	d := newDebugger(t, testProgram)
+	var out bytes.Buffer
+	r := NewREPL(d, &out)
+
+	in := strings.Join([]string{
+		"break 0x20C if V3 == 8",
+		"c",
+		"p V3",
+		"",
+		"watch I",
+		"info",
+		"delete 1",
+		"n",
+		"s 2",
+		"bogus",
+		"q",
+		"regs",
+	}, "\n")
+	assert.Nil(t, r.Run(strings.NewReader(in)))
+
+	assert.Equal(t, ""+
+		"(chip8) breakpoint 1 at 0x020c if V3 == 8\n"+
+		"(chip8) hit breakpoint 1 at 0x020c if V3 == 8\n"+
+		"=>* 020C  7101      ADD V1, 0x01\n"+
+		"(chip8) 8 (0x8)\n"+
+		"(chip8) 8 (0x8)\n"+
+		"(chip8) watchpoint 2 on I\n"+
+		"(chip8) breakpoint 1 at 0x020c if V3 == 8, hit 1 times\n"+
+		"watchpoint 2 on I, hit 0 times\n"+
+		"(chip8) "+
+		"(chip8) =>  020E  00EE      RET\n"+
+		"(chip8) =>  0208  F355      LD [I], V3\n"+
+		"(chip8) unknown command \"bogus\", try help\n"+
+		"(chip8) ", out.String())
+}
@@ -0,0 +1,170 @@
+// Package disasm turns CHIP-8 program bytes back into instructions,
+// using the decoding table that chip8.Opcode.Execute runs on.
+package disasm
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	chip8 "github.com/hermesdt/go-plan8"
+)
+
+// dataPerLine is how many unreachable bytes are grouped into one data
+// instruction.
+const dataPerLine = 8
+
+// Instruction is either a decoded instruction or, when Op is nil, a run
+// of bytes that no code path reaches.
+type Instruction struct {
+	Address uint16
+	// Word is the first opcode word, or the first data byte.
+	Word     uint16
+	Bytes    []byte
+	Op       *chip8.Instruction
+	Mnemonic string
+	Operands []string
+}
+
+func (i Instruction) IsData() bool {
+	return i.Op == nil
+}
+
+// Format renders the instruction in the given syntax.
+func (i Instruction) Format(s Syntax) string {
+	if i.IsData() {
+		return s.data(i.Bytes)
+	}
+	return s.expand(templates[i.Op.Name][s], i.Bytes)
+}
+
+func newInstruction(addr uint16, bs []byte, op *chip8.Instruction) Instruction {
+	in := Instruction{Address: addr, Bytes: bs, Op: op}
+	if op == nil {
+		in.Word = uint16(bs[0])
+		in.Mnemonic = "DB"
+		for _, b := range bs {
+			in.Operands = append(in.Operands, fmt.Sprintf("0x%02X", b))
+		}
+		return in
+	}
+	in.Word = uint16(bs[0])<<8 | uint16(bs[1])
+	parts := strings.SplitN(Cowgod.expand(templates[op.Name][Cowgod], bs), " ", 2)
+	in.Mnemonic = parts[0]
+	if len(parts) > 1 {
+		in.Operands = strings.Split(parts[1], ", ")
+	}
+	return in
+}
+
+// Decode decodes the single instruction at the start of bs, linearly,
+// returning a data instruction for bytes the variant cannot execute.
+func Decode(bs []byte, addr uint16, variant chip8.Variant) Instruction {
+	if len(bs) < 2 {
+		return newInstruction(addr, bs, nil)
+	}
+	op := chip8.Decode(uint16(bs[0])<<8|uint16(bs[1]), variant)
+	if op == nil || op.Len() > len(bs) {
+		return newInstruction(addr, bs[:2], nil)
+	}
+	return newInstruction(addr, bs[:op.Len()], op)
+}
+
+// Disassemble follows every code path from the start of rom, loaded at
+// origin, and returns its instructions in address order. Bytes that are
+// never reached, or that would overlap an instruction already found,
+// are returned as data.
+func Disassemble(rom []byte, origin uint16, variant chip8.Variant) []Instruction {
+	code := make([]*chip8.Instruction, len(rom))
+	claimed := make([]bool, len(rom))
+
+	decode := func(off int) *chip8.Instruction {
+		if off < 0 || off+1 >= len(rom) {
+			return nil
+		}
+		op := chip8.Decode(uint16(rom[off])<<8|uint16(rom[off+1]), variant)
+		if op == nil || off+op.Len() > len(rom) {
+			return nil
+		}
+		return op
+	}
+
+	pending := []int{0}
+	for len(pending) > 0 {
+		off := pending[len(pending)-1]
+		pending = pending[:len(pending)-1]
+
+		for {
+			op := decode(off)
+			if op == nil || code[off] != nil || overlaps(claimed, off, op.Len()) {
+				break
+			}
+			code[off] = op
+			for i := 0; i < op.Len(); i++ {
+				claimed[off+i] = true
+			}
+			next := off + op.Len()
+			target := int(uint16(rom[off])<<8|uint16(rom[off+1]))&0x0FFF - int(origin)
+
+			switch op.Name {
+			case "Jump":
+				pending = append(pending, target)
+				next = -1
+			case "CallSub":
+				pending = append(pending, target)
+			case "Return", "Exit", "JumpPlusV0", "Call":
+				next = -1
+			case "SkipEq", "SkipNeq", "SkipEqVY", "SkipNeqVY", "SkipKeyPressed", "SkipNotKeyPressed":
+				skipped := 2
+				if after := decode(next); after != nil {
+					skipped = after.Len()
+				}
+				pending = append(pending, next+skipped)
+			}
+			if next < 0 {
+				break
+			}
+			off = next
+		}
+	}
+
+	out := []Instruction{}
+	for off := 0; off < len(rom); {
+		addr := origin + uint16(off)
+		if op := code[off]; op != nil {
+			out = append(out, newInstruction(addr, rom[off:off+op.Len()], op))
+			off += op.Len()
+			continue
+		}
+		end := off + 1
+		for end < len(rom) && end-off < dataPerLine && code[end] == nil {
+			end++
+		}
+		out = append(out, newInstruction(addr, rom[off:end], nil))
+		off = end
+	}
+	return out
+}
+
+func overlaps(claimed []bool, off, n int) bool {
+	for i := off; i < off+n; i++ {
+		if claimed[i] {
+			return true
+		}
+	}
+	return false
+}
+
+// Write prints one line per instruction: address, raw bytes and text.
+func Write(w io.Writer, ins []Instruction, s Syntax) error {
+	for _, in := range ins {
+		raw := fmt.Sprintf("%X", in.Bytes)
+		if len(raw) > 8 {
+			raw = raw[:8] + "+"
+		}
+		if _, err := fmt.Fprintf(w, "%04X  %-9s %s\n", in.Address, raw, in.Format(s)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
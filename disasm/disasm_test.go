@@ -0,0 +1,119 @@
+package disasm
+
+import (
+	"bytes"
+	"testing"
+
+	chip8 "github.com/hermesdt/go-plan8"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDecode(t *testing.T) {
+	in := Decode([]byte{0x8A, 0xB4}, 0x200, chip8.VariantChip8)
+
+	assert.Equal(t, uint16(0x200), in.Address)
+	assert.Equal(t, uint16(0x8AB4), in.Word)
+	assert.Equal(t, "AddVY", in.Op.Name)
+	assert.Equal(t, "ADD", in.Mnemonic)
+	assert.Equal(t, []string{"VA", "VB"}, in.Operands)
+	assert.Equal(t, "ADD VA, VB", in.Format(Cowgod))
+	assert.Equal(t, "va += vb", in.Format(Octo))
+}
+
+func TestDecode_formats(t *testing.T) {
+	cases := []struct {
+		bs     []byte
+		cowgod string
+		octo   string
+	}{
+		{[]byte{0x00, 0xE0}, "CLS", "clear"},
+		{[]byte{0x12, 0x34}, "JP 0x234", "jump 0x234"},
+		{[]byte{0x3A, 0x07}, "SE VA, 0x07", "if va != 0x07 then"},
+		{[]byte{0xD1, 0x25}, "DRW V1, V2, 5", "sprite v1 v2 5"},
+		{[]byte{0xF3, 0x65}, "LD V3, [I]", "load v3"},
+		{[]byte{0xE4, 0xA1}, "SKNP V4", "if v4 key then"},
+		{[]byte{0x00, 0xC4}, "SCD 4", "scroll-down 4"},
+		{[]byte{0xF0, 0x00, 0x12, 0x34}, "LD I, LONG 0x1234", "i := long 0x1234"},
+		{[]byte{0xF2, 0x01}, "PLANE 2", "plane 2"},
+		{[]byte{0x51, 0x42}, "SAVE V1, V4", "save v1 - v4"},
+	}
+	for _, c := range cases {
+		in := Decode(c.bs, 0x200, chip8.VariantXOChip)
+		assert.Equal(t, c.cowgod, in.Format(Cowgod))
+		assert.Equal(t, c.octo, in.Format(Octo))
+	}
+}
+
+func TestDecode_variant(t *testing.T) {
+	in := Decode([]byte{0x00, 0xFF}, 0x200, chip8.VariantChip8)
+	assert.Equal(t, "SYS 0x0FF", in.Format(Cowgod))
+
+	in = Decode([]byte{0xF0, 0x00, 0x12, 0x34}, 0x200, chip8.VariantSChip)
+	assert.True(t, in.IsData())
+	assert.Equal(t, "DB 0xF0, 0x00", in.Format(Cowgod))
+}
+
+func TestDisassemble_recursiveDescent(t *testing.T) {
+	rom := []byte{
+		0x22, 0x08, // 200: CALL 208
+		0x12, 0x0C, // 202: JP 20C
+		0xFF, 0xAA, // 204: data
+		0x55, 0x66, // 206: data
+		0x30, 0x01, // 208: SE V0, 1
+		0x00, 0xEE, // 20A: RET
+		0x12, 0x0C, // 20C: JP 20C
+		0x00, 0xEE, // 20E: unreachable
+		0x01,
+	}
+
+	ins := Disassemble(rom, 0x200, chip8.VariantChip8)
+
+	var buf bytes.Buffer
+	assert.Nil(t, Write(&buf, ins, Cowgod))
+	assert.Equal(t, ""+
+		"0200  2208      CALL 0x208\n"+
+		"0202  120C      JP 0x20C\n"+
+		"0204  FFAA5566  DB 0xFF, 0xAA, 0x55, 0x66\n"+
+		"0208  3001      SE V0, 0x01\n"+
+		"020A  00EE      RET\n"+
+		"020C  120C      JP 0x20C\n"+
+		"020E  00EE01    DB 0x00, 0xEE, 0x01\n", buf.String())
+}
+
+func TestDisassemble_skipsLongLoad(t *testing.T) {
+	rom := []byte{
+		0x40, 0x00, // 200: SNE V0, 0
+		0xF0, 0x00, 0x02, 0x0A, // 202: LD I, 0x020A
+		0x00, 0xE0, // 206: CLS, only reached by skipping all 4 bytes
+		0x00, 0xFD, // 208: EXIT
+	}
+
+	ins := Disassemble(rom, 0x200, chip8.VariantXOChip)
+
+	assert.Equal(t, 4, len(ins))
+	assert.Equal(t, "i := long 0x020A", ins[1].Format(Octo))
+	assert.Equal(t, "clear", ins[2].Format(Octo))
+	assert.Equal(t, "exit", ins[3].Format(Octo))
+}
+
+func TestDisassemble_overlapIsData(t *testing.T) {
+	rom := []byte{
+		0x12, 0x03, // 200: JP 203, into the middle of this word
+		0x00,
+	}
+
+	ins := Disassemble(rom, 0x200, chip8.VariantChip8)
+
+	assert.Equal(t, 2, len(ins))
+	assert.Equal(t, "JP 0x203", ins[0].Format(Cowgod))
+	assert.True(t, ins[1].IsData())
+}
+
+func TestParseSyntax(t *testing.T) {
+	s, err := ParseSyntax("Octo")
+	assert.Nil(t, err)
+	assert.Equal(t, Octo, s)
+
+	_, err = ParseSyntax("intel")
+	assert.NotNil(t, err)
+}
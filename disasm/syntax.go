@@ -0,0 +1,137 @@
+package disasm
+
+import (
+	"fmt"
+	"strings"
+
+	chip8 "github.com/hermesdt/go-plan8"
+)
+
+type Syntax int
+
+const (
+	// Cowgod is the mnemonic syntax of Cowgod's Chip-8 technical
+	// reference, extended with the usual SUPER-CHIP and XO-CHIP names.
+	Cowgod Syntax = iota
+	// Octo is the statement syntax of the Octo assembler.
+	Octo
+)
+
+func (s Syntax) String() string {
+	if s == Octo {
+		return "octo"
+	}
+	return "cowgod"
+}
+
+func ParseSyntax(name string) (Syntax, error) {
+	switch strings.ToLower(name) {
+	case "cowgod":
+		return Cowgod, nil
+	case "octo":
+		return Octo, nil
+	}
+	return 0, fmt.Errorf("disasm: unknown syntax %q", name)
+}
+
+// templates maps chip8.Instruction names to their Cowgod and Octo forms.
+// %X and %Y are registers, %N, %NN and %NNN the low nibble, byte and
+// twelve bits of the opcode, %P the plane mask in the X nibble, %L the
+// XO-CHIP long address and %W the raw opcode bytes.
+var templates = map[string][2]string{
+	"DispClr":           {"CLS", "clear"},
+	"Return":            {"RET", "return"},
+	"ScrollDown":        {"SCD %N", "scroll-down %N"},
+	"ScrollUp":          {"SCU %N", "scroll-up %N"},
+	"ScrollRight":       {"SCR", "scroll-right"},
+	"ScrollLeft":        {"SCL", "scroll-left"},
+	"Exit":              {"EXIT", "exit"},
+	"LowRes":            {"LOW", "lores"},
+	"HighRes":           {"HIGH", "hires"},
+	"Call":              {"SYS %NNN", "%W"},
+	"Jump":              {"JP %NNN", "jump %NNN"},
+	"CallSub":           {"CALL %NNN", ":call %NNN"},
+	"SkipEq":            {"SE %X, %NN", "if %X != %NN then"},
+	"SkipNeq":           {"SNE %X, %NN", "if %X == %NN then"},
+	"SaveRange":         {"SAVE %X, %Y", "save %X - %Y"},
+	"LoadRange":         {"LOAD %X, %Y", "load %X - %Y"},
+	"SkipEqVY":          {"SE %X, %Y", "if %X != %Y then"},
+	"Set":               {"LD %X, %NN", "%X := %NN"},
+	"Add":               {"ADD %X, %NN", "%X += %NN"},
+	"SetVY":             {"LD %X, %Y", "%X := %Y"},
+	"OrVY":              {"OR %X, %Y", "%X |= %Y"},
+	"AndVY":             {"AND %X, %Y", "%X &= %Y"},
+	"XorVY":             {"XOR %X, %Y", "%X ^= %Y"},
+	"AddVY":             {"ADD %X, %Y", "%X += %Y"},
+	"SubVY":             {"SUB %X, %Y", "%X -= %Y"},
+	"ShiftRight":        {"SHR %X, %Y", "%X >>= %Y"},
+	"VYSub":             {"SUBN %X, %Y", "%X =- %Y"},
+	"ShiftLeft":         {"SHL %X, %Y", "%X <<= %Y"},
+	"SkipNeqVY":         {"SNE %X, %Y", "if %X == %Y then"},
+	"SetI":              {"LD I, %NNN", "i := %NNN"},
+	"JumpPlusV0":        {"JP V0, %NNN", "jump0 %NNN"},
+	"SetRandomMask":     {"RND %X, %NN", "%X := random %NN"},
+	"Draw":              {"DRW %X, %Y, %N", "sprite %X %Y %N"},
+	"SkipKeyPressed":    {"SKP %X", "if %X -key then"},
+	"SkipNotKeyPressed": {"SKNP %X", "if %X key then"},
+	"SetILong":          {"LD I, LONG %L", "i := long %L"},
+	"SelectPlanes":      {"PLANE %P", "plane %P"},
+	"LoadAudio":         {"AUDIO", "audio"},
+	"SetFromDelay":      {"LD %X, DT", "%X := delay"},
+	"ReadKey":           {"LD %X, K", "%X := key"},
+	"SetDelay":          {"LD DT, %X", "delay := %X"},
+	"SetSound":          {"LD ST, %X", "buzzer := %X"},
+	"AddI":              {"ADD I, %X", "i += %X"},
+	"SetISprite":        {"LD F, %X", "i := hex %X"},
+	"SetIBigSprite":     {"LD HF, %X", "i := bighex %X"},
+	"SetBCD":            {"LD B, %X", "bcd %X"},
+	"SetPitch":          {"PITCH %X", "pitch := %X"},
+	"RegDump":           {"LD [I], %X", "save %X"},
+	"RegLoad":           {"LD %X, [I]", "load %X"},
+	"SaveFlags":         {"LD R, %X", "saveflags %X"},
+	"LoadFlags":         {"LD %X, R", "loadflags %X"},
+}
+
+// Template returns the form op is written in, with the placeholders
+// described above.
+func Template(op *chip8.Instruction, s Syntax) string {
+	return templates[op.Name][s]
+}
+
+func (s Syntax) register(r uint16) string {
+	if s == Octo {
+		return fmt.Sprintf("v%x", r)
+	}
+	return fmt.Sprintf("V%X", r)
+}
+
+// expand fills in a template for the instruction starting with bs.
+func (s Syntax) expand(template string, bs []byte) string {
+	w := uint16(bs[0])<<8 | uint16(bs[1])
+	var long uint16
+	if len(bs) >= 4 {
+		long = uint16(bs[2])<<8 | uint16(bs[3])
+	}
+	r := strings.NewReplacer(
+		"%NNN", fmt.Sprintf("0x%03X", w&0x0FFF),
+		"%NN", fmt.Sprintf("0x%02X", w&0x00FF),
+		"%N", fmt.Sprintf("%d", w&0x000F),
+		"%X", s.register(w>>8&0xF),
+		"%Y", s.register(w>>4&0xF),
+		"%P", fmt.Sprintf("%d", w>>8&0xF),
+		"%L", fmt.Sprintf("0x%04X", long),
+		"%W", s.data(bs[:2]),
+	)
+	return r.Replace(template)
+}
+
+func (s Syntax) data(bs []byte) string {
+	parts := []string{}
+	for _, b := range bs {
+		parts = append(parts, fmt.Sprintf("0x%02X", b))
+	}
+	if s == Octo {
+		return strings.Join(parts, " ")
+	}
+	return "DB " + strings.Join(parts, ", ")
+}
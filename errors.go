@@ -0,0 +1,37 @@
+package chip8
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	ErrUnknownOpcode     = errors.New("chip8: unknown opcode")
+	ErrStackOverflow     = errors.New("chip8: stack overflow")
+	ErrStackUnderflow    = errors.New("chip8: stack underflow")
+	ErrMemoryOutOfBounds = errors.New("chip8: memory access out of bounds")
+	ErrRomTooLarge       = errors.New("chip8: rom too large")
+	// ErrExit is returned once a program executes the SUPER-CHIP 00FD
+	// exit instruction.
+	ErrExit = errors.New("chip8: program exited")
+)
+
+// ExecError is returned when an instruction cannot be executed. PC is
+// the address of the faulting instruction and is left unchanged.
+type ExecError struct {
+	PC     uint16
+	Opcode uint16
+	Err    error
+}
+
+func (e *ExecError) Error() string {
+	return fmt.Sprintf("%v (pc=%#04x opcode=%#04x)", e.Err, e.PC, e.Opcode)
+}
+
+func (e *ExecError) Unwrap() error {
+	return e.Err
+}
+
+func (o *Opcode) fault(err error) error {
+	return &ExecError{PC: o.Chip8.PC, Opcode: o.Value, Err: err}
+}
@@ -0,0 +1,89 @@
+package chip8
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func assertExecError(t *testing.T, err error, target error, pc, opcode uint16) {
+	var execErr *ExecError
+	if assert.True(t, errors.As(err, &execErr), "%v", err) {
+		assert.True(t, errors.Is(err, target))
+		assert.Equal(t, pc, execErr.PC)
+		assert.Equal(t, opcode, execErr.Opcode)
+	}
+}
+
+func TestExecute_unknownOpcode(t *testing.T) {
+	o := Opcode{
+		Value: 0xE0FF,
+		Chip8: &Chip8{PC: 0x0300},
+	}
+
+	err := o.Execute()
+
+	assertExecError(t, err, ErrUnknownOpcode, 0x0300, 0xE0FF)
+	assert.Equal(t, "chip8: unknown opcode (pc=0x0300 opcode=0xe0ff)", err.Error())
+	assert.Equal(t, uint16(0x0300), o.Chip8.PC)
+}
+
+func TestExecute_machineCall(t *testing.T) {
+	o := Opcode{
+		Value: 0x0123,
+		Chip8: &Chip8{PC: 0x0202},
+	}
+
+	assertExecError(t, o.Execute(), ErrUnknownOpcode, 0x0202, 0x0123)
+}
+
+func TestExecute_stackOverflow(t *testing.T) {
+	o := Opcode{
+		Value: 0x2400,
+		Chip8: &Chip8{PC: 0x0210, SP: 16},
+	}
+
+	assertExecError(t, o.Execute(), ErrStackOverflow, 0x0210, 0x2400)
+	assert.Equal(t, uint16(16), o.Chip8.SP)
+}
+
+func TestExecute_stackUnderflow(t *testing.T) {
+	o := Opcode{
+		Value: 0x00EE,
+		Chip8: &Chip8{PC: 0x0210},
+	}
+
+	assertExecError(t, o.Execute(), ErrStackUnderflow, 0x0210, 0x00EE)
+	assert.Equal(t, uint16(0), o.Chip8.SP)
+}
+
+func TestStep_pcOutOfBounds(t *testing.T) {
+	c := NewChip8(VariantChip8)
+	c.PC = 0x0FFF
+
+	assertExecError(t, c.Step(), ErrMemoryOutOfBounds, 0x0FFF, 0)
+}
+
+func TestLoadRomBytes_tooLarge(t *testing.T) {
+	c := NewChip8(VariantChip8)
+
+	err := c.LoadRomBytes(make([]byte, 4096-RomStart+1))
+
+	assert.True(t, errors.Is(err, ErrRomTooLarge))
+}
+
+func TestMachine_haltsOnError(t *testing.T) {
+	c := NewChip8(VariantChip8)
+	c.Memory[0x200] = 0x00
+	c.Memory[0x201] = 0xEE
+	m := NewMachine(c)
+
+	err := m.Step(5)
+
+	assertExecError(t, err, ErrStackUnderflow, 0x0200, 0x00EE)
+	assert.True(t, m.Halted())
+	assert.Equal(t, err, m.Err())
+	assert.Equal(t, err, m.Step(1))
+	assert.Equal(t, uint64(0), m.Cycles)
+}
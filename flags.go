@@ -0,0 +1,99 @@
+package chip8
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+const FlagCount = 16
+
+// FlagStore keeps the HP48 RPL user flags written by FX75 and read by
+// FX85, separately for every ROM.
+type FlagStore interface {
+	LoadFlags(rom RomHash) ([FlagCount]uint8, error)
+	SaveFlags(rom RomHash, flags [FlagCount]uint8) error
+}
+
+type MemoryFlagStore struct {
+	mu    sync.Mutex
+	flags map[RomHash][FlagCount]uint8
+}
+
+func NewMemoryFlagStore() *MemoryFlagStore {
+	return &MemoryFlagStore{flags: map[RomHash][FlagCount]uint8{}}
+}
+
+func (s *MemoryFlagStore) LoadFlags(rom RomHash) ([FlagCount]uint8, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.flags[rom], nil
+}
+
+func (s *MemoryFlagStore) SaveFlags(rom RomHash, flags [FlagCount]uint8) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.flags[rom] = flags
+	return nil
+}
+
+// FileFlagStore keeps the flags of each ROM in Dir/<rom hash>.rpl so
+// they survive restarts.
+type FileFlagStore struct {
+	Dir string
+}
+
+func NewFileFlagStore(dir string) *FileFlagStore {
+	return &FileFlagStore{Dir: dir}
+}
+
+func (s *FileFlagStore) path(rom RomHash) string {
+	return filepath.Join(s.Dir, rom.String()+".rpl")
+}
+
+func (s *FileFlagStore) LoadFlags(rom RomHash) ([FlagCount]uint8, error) {
+	var flags [FlagCount]uint8
+	bs, err := ioutil.ReadFile(s.path(rom))
+	if os.IsNotExist(err) {
+		return flags, nil
+	}
+	if err != nil {
+		return flags, err
+	}
+	if len(bs) != FlagCount {
+		return flags, fmt.Errorf("chip8: %s: corrupt flags file", s.path(rom))
+	}
+	copy(flags[:], bs)
+	return flags, nil
+}
+
+// SaveFlags writes to a temporary file first so a crash never leaves a
+// truncated flags file behind.
+func (s *FileFlagStore) SaveFlags(rom RomHash, flags [FlagCount]uint8) error {
+	if err := os.MkdirAll(s.Dir, 0755); err != nil {
+		return err
+	}
+	tmp, err := ioutil.TempFile(s.Dir, "flags")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(flags[:]); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), s.path(rom))
+}
+
+func (c *Chip8) flagStore() FlagStore {
+	if c.Flags == nil {
+		c.Flags = NewMemoryFlagStore()
+	}
+	return c.Flags
+}
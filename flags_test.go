@@ -0,0 +1,73 @@
+package chip8
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFileFlagStore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "flags")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	rom := []byte{0x00, 0xE0, 0x12, 0x00}
+	c := NewChip8(VariantSChip)
+	c.Flags = NewFileFlagStore(dir)
+	c.LoadRomBytes(rom)
+	c.V = [16]uint8{9, 8, 7}
+	o := Opcode{Value: 0xF275, Chip8: c}
+	assert.Nil(t, o.Execute())
+
+	c = NewChip8(VariantSChip)
+	c.Flags = NewFileFlagStore(dir)
+	c.LoadRomBytes(rom)
+	o = Opcode{Value: 0xF285, Chip8: c}
+	assert.Nil(t, o.Execute())
+	assert.Equal(t, []uint8{9, 8, 7, 0}, c.V[:4])
+
+	other := NewChip8(VariantSChip)
+	other.Flags = NewFileFlagStore(dir)
+	other.LoadRomBytes([]byte{0x12, 0x00})
+	o = Opcode{Value: 0xF285, Chip8: other}
+	assert.Nil(t, o.Execute())
+	assert.Equal(t, []uint8{0, 0, 0}, other.V[:3])
+}
+
+func TestFileFlagStore_keepsHigherFlags(t *testing.T) {
+	dir, err := ioutil.TempDir("", "flags")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	store := NewFileFlagStore(dir)
+	var hash RomHash
+
+	assert.Nil(t, store.SaveFlags(hash, [FlagCount]uint8{1, 2, 3, 4}))
+	c := &Chip8{Variant: VariantSChip, Flags: store}
+	c.V[0] = 0xAA
+	o := Opcode{Value: 0xF075, Chip8: c}
+	assert.Nil(t, o.Execute())
+
+	flags, err := store.LoadFlags(hash)
+	assert.Nil(t, err)
+	assert.Equal(t, []uint8{0xAA, 2, 3, 4}, flags[:4])
+}
+
+func TestFileFlagStore_corrupt(t *testing.T) {
+	dir, err := ioutil.TempDir("", "flags")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	var hash RomHash
+	ioutil.WriteFile(filepath.Join(dir, hash.String()+".rpl"), []byte{1}, 0644)
+
+	c := &Chip8{Variant: VariantSChip, Flags: NewFileFlagStore(dir), PC: 0x300}
+	o := Opcode{Value: 0xF085, Chip8: c}
+
+	err = o.Execute()
+	var execErr *ExecError
+	assert.True(t, errors.As(err, &execErr))
+	assert.Equal(t, uint16(0x300), c.PC)
+}
@@ -45,3 +45,13 @@ func TestFontSet_1(t *testing.T) {
 			"00100000\n"+
 			"01110000")
 }
+
+func TestHiResFontSet_8(t *testing.T) {
+	chip8 := Chip8{}
+	chip8.LoadHiResFontSet()
+	digit_offset := 8*10 + HiResFontSetStart
+
+	assert.Equal(t, chip8.Memory[digit_offset:digit_offset+10], []uint8{
+		0x3C, 0x7E, 0xC3, 0xC3, 0x7E, 0x7E, 0xC3, 0xC3, 0x7E, 0x3C,
+	})
+}
@@ -0,0 +1,91 @@
+// Package fyneui is the Fyne desktop frontend: a window that shows the
+// screen on a scalable raster, turns keyboard events into keypad
+// presses, and has buttons to open a ROM, reset, pause, change the
+// speed and quirks, and save or load state.
+//
+// The display and input adapters work with any fyne.App, so they run
+// headless with fyne.io/fyne/test.
+package fyneui
+
+import (
+	"image"
+	"image/color"
+	"sync"
+
+	"fyne.io/fyne/canvas"
+	chip8 "github.com/hermesdt/go-plan8"
+)
+
+// DefaultPalette colours the pixel values of chip8.Screen.Pixel: off,
+// plane 1, plane 2 and both planes.
+var DefaultPalette = [1 << chip8.Planes]color.RGBA{
+	{0x00, 0x00, 0x00, 0xFF},
+	{0xFF, 0xFF, 0xFF, 0xFF},
+	{0xAA, 0xAA, 0xAA, 0xFF},
+	{0x55, 0x55, 0x55, 0xFF},
+}
+
+// Display shows a copy of the screen taken by Update, scaled to
+// whatever size its raster is laid out at.
+type Display struct {
+	Raster  *canvas.Raster
+	Palette [1 << chip8.Planes]color.RGBA
+
+	mu            sync.Mutex
+	width, height int
+	px            []uint8
+}
+
+func NewDisplay() *Display {
+	d := &Display{
+		Palette: DefaultPalette,
+		width:   chip8.ScreenWidth,
+		height:  chip8.ScreenHeight,
+		px:      make([]uint8, chip8.ScreenWidth*chip8.ScreenHeight),
+	}
+	d.Raster = canvas.NewRaster(d.Image)
+	return d
+}
+
+// Update copies the screen, reporting whether it changed since the
+// last copy. The screen must not change during the call.
+func (d *Display) Update(s *chip8.Screen) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	w, h := s.Width(), s.Height()
+	changed := w != d.width || h != d.height
+	if changed {
+		d.width, d.height = w, h
+		d.px = make([]uint8, w*h)
+	}
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			v := s.Pixel(y, x)
+			if d.px[y*w+x] != v {
+				d.px[y*w+x] = v
+				changed = true
+			}
+		}
+	}
+	return changed
+}
+
+// Image renders the screen at w by h pixels, scaling each CHIP-8 pixel
+// to a block. It is the raster's generator.
+func (d *Display) Image(w, h int) image.Image {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	if w <= 0 || h <= 0 {
+		return img
+	}
+	for y := 0; y < h; y++ {
+		row := y * d.height / h * d.width
+		for x := 0; x < w; x++ {
+			c := d.Palette[d.px[row+x*d.width/w]]
+			i := img.PixOffset(x, y)
+			img.Pix[i], img.Pix[i+1], img.Pix[i+2], img.Pix[i+3] = c.R, c.G, c.B, c.A
+		}
+	}
+	return img
+}
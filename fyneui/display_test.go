@@ -0,0 +1,34 @@
+package fyneui
+
+import (
+	"image/color"
+	"testing"
+
+	chip8 "github.com/hermesdt/go-plan8"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDisplayImage(t *testing.T) {
+	d := NewDisplay()
+	var s chip8.Screen
+	s.Set(0, 0, true)
+	s.SetPlane(1, 31, 63, true)
+
+	assert.True(t, d.Update(&s))
+	assert.False(t, d.Update(&s))
+
+	// Each pixel scales to a 4x4 block.
+	img := d.Image(256, 128)
+	assert.Equal(t, color.RGBA{0xFF, 0xFF, 0xFF, 0xFF}, img.At(0, 0))
+	assert.Equal(t, color.RGBA{0xFF, 0xFF, 0xFF, 0xFF}, img.At(3, 3))
+	assert.Equal(t, color.RGBA{0x00, 0x00, 0x00, 0xFF}, img.At(4, 0))
+	assert.Equal(t, color.RGBA{0xAA, 0xAA, 0xAA, 0xFF}, img.At(255, 127))
+	assert.Equal(t, color.RGBA{0x00, 0x00, 0x00, 0xFF}, img.At(251, 127))
+
+	// Switching resolution is a change, even to a blank screen.
+	s.SetHiRes(true)
+	assert.True(t, d.Update(&s))
+	img = d.Image(128, 64)
+	assert.Equal(t, color.RGBA{0x00, 0x00, 0x00, 0xFF}, img.At(0, 0))
+	assert.Equal(t, 0, d.Image(0, 0).Bounds().Dx())
+}
@@ -0,0 +1,355 @@
+package fyneui
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"fyne.io/fyne"
+	"fyne.io/fyne/canvas"
+	"fyne.io/fyne/dialog"
+	"fyne.io/fyne/layout"
+	"fyne.io/fyne/widget"
+	chip8 "github.com/hermesdt/go-plan8"
+	"github.com/hermesdt/go-plan8/octo"
+)
+
+// Speeds are the instructions per second the speed menu offers.
+var Speeds = []int{350, 500, chip8.DefaultInstrPerSec, 1000, 1500, 3000}
+
+// Scale is the initial size of a CHIP-8 pixel, in Fyne units.
+const Scale = 8
+
+var ErrNoProgram = errors.New("fyneui: no program open")
+
+// Frontend is a window running one program at a time.
+type Frontend struct {
+	Window  fyne.Window
+	Display *Display
+	Input   *Input
+	Variant chip8.Variant
+	// Flags, when set, keeps the RPL user flags of every
+	// program opened.
+	Flags chip8.FlagStore
+	// Clock drives the programs opened.
+	Clock chip8.Clock
+
+	mu      sync.Mutex
+	machine *chip8.Machine
+	keypad  *chip8.MemoryKeypad
+	path    string
+	rom     []byte
+	quirks  chip8.Quirks
+	speed   int
+	err     error
+
+	status *widget.Label
+	pause  *widget.Button
+}
+
+// New lays out a window of a, which shows nothing until a program is
+// opened.
+func New(a fyne.App, variant chip8.Variant) *Frontend {
+	f := &Frontend{
+		Window:  a.NewWindow("go-plan8"),
+		Display: NewDisplay(),
+		Variant: variant,
+		Clock:   chip8.SystemClock,
+		keypad:  chip8.NewKeypad(),
+		quirks:  variant.DefaultQuirks(),
+		speed:   chip8.DefaultInstrPerSec,
+		status:  widget.NewLabel(""),
+	}
+	f.Input = NewInput(f.keypad)
+	f.Display.Raster.SetMinSize(fyne.NewSize(chip8.ScreenWidth*Scale, chip8.ScreenHeight*Scale))
+
+	f.pause = widget.NewButton("Pause", f.TogglePause)
+	menu := widget.NewHBox(
+		widget.NewButton("Open…", f.showOpen),
+		widget.NewButton("Reset", func() { f.report(f.Reset()) }),
+		f.pause,
+		widget.NewButton("Speed…", f.showSpeed),
+		widget.NewButton("Quirks…", f.showQuirks),
+		widget.NewButton("Save state", func() { f.report(f.SaveState()) }),
+		widget.NewButton("Load state", func() { f.report(f.LoadState()) }),
+	)
+	f.Window.SetContent(fyne.NewContainerWithLayout(
+		layout.NewBorderLayout(menu, f.status, nil, nil),
+		menu, f.status, f.Display.Raster,
+	))
+	f.Input.Attach(f.Window.Canvas())
+	return f
+}
+
+// Open loads a ROM, or compiles an Octo program, and starts it.
+func (f *Frontend) Open(path string) error {
+	var rom []byte
+	var err error
+	if strings.HasSuffix(path, ".8o") {
+		var p *octo.Program
+		p, err = (&octo.Compiler{Variant: f.Variant}).CompileFile(path)
+		if p != nil {
+			rom = p.Bytes
+		}
+	} else {
+		rom, err = ioutil.ReadFile(path)
+	}
+	if err != nil {
+		return err
+	}
+	return f.Load(path, rom)
+}
+
+// Load starts rom, naming it after path for the title and state file.
+func (f *Frontend) Load(path string, rom []byte) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	old, oldRom := f.path, f.rom
+	f.path, f.rom = path, rom
+	if err := f.reset(); err != nil {
+		f.path, f.rom = old, oldRom
+		return err
+	}
+	f.Window.SetTitle("go-plan8 - " + filepath.Base(path))
+	return nil
+}
+
+// Reset starts the program over.
+func (f *Frontend) Reset() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.rom == nil {
+		return ErrNoProgram
+	}
+	return f.reset()
+}
+
+func (f *Frontend) reset() error {
+	c := chip8.NewChip8(f.Variant)
+	c.Key = f.keypad
+	c.Quirks = f.quirks
+	if f.Flags != nil {
+		c.Flags = f.Flags
+	}
+	if err := c.LoadRomBytes(f.rom); err != nil {
+		return err
+	}
+	paused := f.machine != nil && f.machine.Paused()
+	f.machine = chip8.NewMachine(c)
+	f.machine.Clock = f.Clock
+	f.machine.InstructionsPerSecond = f.speed
+	if paused {
+		f.machine.Pause()
+	}
+	f.err = nil
+	f.refresh()
+	f.setStatus()
+	return nil
+}
+
+func (f *Frontend) TogglePause() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.machine == nil {
+		return
+	}
+	if f.machine.Paused() {
+		f.machine.Resume()
+		f.pause.SetText("Pause")
+	} else {
+		f.machine.Pause()
+		f.pause.SetText("Resume")
+	}
+	f.setStatus()
+}
+
+// Paused reports whether the program is paused, or there is none.
+func (f *Frontend) Paused() bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.machine == nil || f.machine.Paused()
+}
+
+// SetSpeed sets the instructions run per second, now and after resets.
+func (f *Frontend) SetSpeed(ips int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.speed = ips
+	if f.machine != nil {
+		f.machine.InstructionsPerSecond = ips
+	}
+	f.setStatus()
+}
+
+// SetQuirks switches to a profile of chip8.QuirkProfileNames, now and
+// after resets.
+func (f *Frontend) SetQuirks(profile string) error {
+	q, err := chip8.LookupQuirks(profile)
+	if err != nil {
+		return err
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.quirks = q
+	if f.machine != nil {
+		f.machine.Chip8.Quirks = q
+	}
+	f.setStatus()
+	return nil
+}
+
+// StatePath is where the state of the open program is saved, next to
+// it.
+func (f *Frontend) StatePath() string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.path + ".state"
+}
+
+func (f *Frontend) SaveState() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.machine == nil {
+		return ErrNoProgram
+	}
+	file, err := os.Create(f.path + ".state")
+	if err != nil {
+		return err
+	}
+	if err := f.machine.SaveState(file); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+func (f *Frontend) LoadState() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.machine == nil {
+		return ErrNoProgram
+	}
+	file, err := os.Open(f.path + ".state")
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	if err := f.machine.LoadState(file); err != nil {
+		return err
+	}
+	// The state holds the quirks it was saved with.
+	f.quirks = f.machine.Chip8.Quirks
+	f.err = nil
+	f.refresh()
+	f.setStatus()
+	return nil
+}
+
+// Frame catches the program up with the clock, releases keys typed
+// long enough and redraws the screen if it changed. Run calls it
+// TimerFrequency times a second.
+func (f *Frontend) Frame() {
+	f.Input.Frame()
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.machine == nil || f.err != nil {
+		return
+	}
+	if err := f.machine.Update(); err != nil {
+		f.err = err
+		f.setStatus()
+	}
+	f.refresh()
+}
+
+// refresh redraws the screen if it changed. The machine must not be
+// running.
+func (f *Frontend) refresh() {
+	if f.Display.Update(&f.machine.Chip8.Screen) {
+		canvas.Refresh(f.Display.Raster)
+	}
+}
+
+func (f *Frontend) setStatus() {
+	if f.machine == nil {
+		f.status.SetText("")
+		return
+	}
+	s := fmt.Sprintf("%s  %d instructions/s  quirks: %s", f.Variant, f.speed, f.quirks.ProfileName())
+	if f.machine.Paused() {
+		s += "  paused"
+	}
+	if f.err != nil {
+		s += "  halted: " + f.err.Error()
+	}
+	f.status.SetText(s)
+}
+
+// Run shows the window and runs the program until the window closes.
+func (f *Frontend) Run() {
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(time.Second / chip8.TimerFrequency)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				f.Frame()
+			}
+		}
+	}()
+	f.Window.ShowAndRun()
+	close(done)
+}
+
+func (f *Frontend) report(err error) {
+	if err != nil {
+		dialog.ShowError(err, f.Window)
+	}
+}
+
+func (f *Frontend) showOpen() {
+	entry := widget.NewEntry()
+	entry.SetPlaceHolder("path to a .ch8 ROM or .8o program")
+	dialog.ShowCustomConfirm("Open", "Open", "Cancel", entry, func(ok bool) {
+		if ok && entry.Text != "" {
+			f.report(f.Open(entry.Text))
+		}
+	}, f.Window)
+}
+
+func (f *Frontend) showSpeed() {
+	var options []string
+	for _, ips := range Speeds {
+		options = append(options, strconv.Itoa(ips))
+	}
+	radio := widget.NewRadio(options, func(s string) {
+		if ips, err := strconv.Atoi(s); err == nil {
+			f.SetSpeed(ips)
+		}
+	})
+	f.mu.Lock()
+	selected := strconv.Itoa(f.speed)
+	f.mu.Unlock()
+	radio.SetSelected(selected)
+	dialog.ShowCustom("Instructions per second", "Close", radio, f.Window)
+}
+
+func (f *Frontend) showQuirks() {
+	radio := widget.NewRadio(chip8.QuirkProfileNames(), func(s string) {
+		f.report(f.SetQuirks(s))
+	})
+	f.mu.Lock()
+	selected := f.quirks.ProfileName()
+	f.mu.Unlock()
+	radio.SetSelected(selected)
+	dialog.ShowCustom("Quirks", "Close", radio, f.Window)
+}
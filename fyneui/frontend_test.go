@@ -0,0 +1,143 @@
+package fyneui
+
+import (
+	"image/color"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"fyne.io/fyne/test"
+	chip8 "github.com/hermesdt/go-plan8"
+	"github.com/hermesdt/go-plan8/asm"
+	"github.com/stretchr/testify/assert"
+)
+
+// testProgram draws 0, then the digit of every key typed.
+const testProgram = `
+	LD V1, 0
+	LD V0, 0
+loop:
+	CLS
+	LD F, V0
+	DRW V1, V1, 5
+	LD V0, K
+	JP loop
+`
+
+var (
+	white = color.RGBA{0xFF, 0xFF, 0xFF, 0xFF}
+	black = color.RGBA{0x00, 0x00, 0x00, 0xFF}
+)
+
+func newFrontend(t *testing.T) (*Frontend, *chip8.FakeClock, string) {
+	dir, err := ioutil.TempDir("", "fyneui")
+	assert.Nil(t, err)
+	rom, err := asm.Assemble(testProgram, chip8.VariantChip8)
+	assert.Nil(t, err)
+	path := filepath.Join(dir, "test.ch8")
+	assert.Nil(t, ioutil.WriteFile(path, rom, 0644))
+
+	f := New(test.NewApp(), chip8.VariantChip8)
+	clock := chip8.NewFakeClock(time.Unix(0, 0))
+	f.Clock = clock
+	assert.Nil(t, f.Open(path))
+	return f, clock, dir
+}
+
+// frames runs n frames of emulated time.
+func frames(f *Frontend, clock *chip8.FakeClock, n int) {
+	for i := 0; i < n; i++ {
+		f.Frame()
+		clock.Advance(time.Second / chip8.TimerFrequency)
+	}
+	f.Frame()
+}
+
+func TestFrontendRuns(t *testing.T) {
+	f, clock, dir := newFrontend(t)
+	defer os.RemoveAll(dir)
+
+	assert.Equal(t, "go-plan8 - test.ch8", f.Window.Title())
+	assert.Equal(t, "chip8  700 instructions/s  quirks: modern", f.status.Text)
+	frames(f, clock, 10)
+
+	// The top of the 0 glyph is 4 pixels wide, scaled 8 times.
+	img := f.Display.Image(chip8.ScreenWidth*Scale, chip8.ScreenHeight*Scale)
+	assert.Equal(t, white, img.At(0, 0))
+	assert.Equal(t, white, img.At(4*Scale-1, 0))
+	assert.Equal(t, black, img.At(4*Scale, 0))
+
+	// Typing 1 draws its glyph, whose top is 2 pixels wide, once the
+	// key is released.
+	f.Window.Canvas().OnTypedRune()('1')
+	frames(f, clock, DefaultHoldFrames+2)
+	img = f.Display.Image(chip8.ScreenWidth, chip8.ScreenHeight)
+	assert.Equal(t, black, img.At(0, 0))
+	assert.Equal(t, white, img.At(2, 0))
+	assert.Equal(t, black, img.At(4, 0))
+}
+
+func TestFrontendControls(t *testing.T) {
+	f, clock, dir := newFrontend(t)
+	defer os.RemoveAll(dir)
+	frames(f, clock, 5)
+
+	f.TogglePause()
+	assert.True(t, f.Paused())
+	assert.Equal(t, "Resume", f.pause.Text)
+	assert.Contains(t, f.status.Text, "paused")
+	cycles := f.machine.Cycles
+	frames(f, clock, 5)
+	assert.Equal(t, cycles, f.machine.Cycles)
+	f.TogglePause()
+	assert.False(t, f.Paused())
+
+	f.SetSpeed(1500)
+	assert.Equal(t, 1500, f.machine.InstructionsPerSecond)
+	assert.NotNil(t, f.SetQuirks("nope"))
+	assert.Nil(t, f.SetQuirks("vip"))
+	assert.Equal(t, chip8.QuirksVIP, f.machine.Chip8.Quirks)
+	assert.Equal(t, "chip8  1500 instructions/s  quirks: vip", f.status.Text)
+
+	// Resets keep the settings.
+	assert.Nil(t, f.Reset())
+	assert.Equal(t, uint64(0), f.machine.Cycles)
+	assert.Equal(t, 1500, f.machine.InstructionsPerSecond)
+	assert.Equal(t, chip8.QuirksVIP, f.machine.Chip8.Quirks)
+}
+
+func TestFrontendState(t *testing.T) {
+	f, clock, dir := newFrontend(t)
+	defer os.RemoveAll(dir)
+	frames(f, clock, 5)
+
+	assert.Equal(t, filepath.Join(dir, "test.ch8.state"), f.StatePath())
+	assert.NotNil(t, f.LoadState())
+	assert.Nil(t, f.SaveState())
+	pc := f.machine.Chip8.PC
+	v := f.machine.Chip8.V
+
+	assert.Nil(t, f.Reset())
+	assert.Nil(t, f.LoadState())
+	assert.Equal(t, pc, f.machine.Chip8.PC)
+	assert.Equal(t, v, f.machine.Chip8.V)
+}
+
+func TestFrontendErrors(t *testing.T) {
+	f := New(test.NewApp(), chip8.VariantChip8)
+	assert.Equal(t, ErrNoProgram, f.Reset())
+	assert.Equal(t, ErrNoProgram, f.SaveState())
+	assert.True(t, f.Paused())
+	f.Frame()
+	assert.NotNil(t, f.Open("does-not-exist.ch8"))
+
+	// A program that fails halts with the error in the status line.
+	f.Clock = chip8.NewFakeClock(time.Unix(0, 0))
+	assert.Nil(t, f.Load("ret.ch8", []byte{0x00, 0xEE}))
+	f.Frame()
+	f.Clock.(*chip8.FakeClock).Advance(time.Second)
+	f.Frame()
+	assert.Contains(t, f.status.Text, "halted: chip8: stack underflow")
+}
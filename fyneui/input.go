@@ -0,0 +1,98 @@
+package fyneui
+
+import (
+	"strings"
+	"sync"
+
+	"fyne.io/fyne"
+	"fyne.io/fyne/driver/desktop"
+	chip8 "github.com/hermesdt/go-plan8"
+)
+
+// DefaultKeymap lays the hex keypad out on the left of a QWERTY
+// keyboard:
+//
+//	1 2 3 C      1 2 3 4
+//	4 5 6 D  <-  Q W E R
+//	7 8 9 E      A S D F
+//	A 0 B F      Z X C V
+var DefaultKeymap = map[fyne.KeyName]uint8{
+	fyne.Key1: 0x1, fyne.Key2: 0x2, fyne.Key3: 0x3, fyne.Key4: 0xC,
+	fyne.KeyQ: 0x4, fyne.KeyW: 0x5, fyne.KeyE: 0x6, fyne.KeyR: 0xD,
+	fyne.KeyA: 0x7, fyne.KeyS: 0x8, fyne.KeyD: 0x9, fyne.KeyF: 0xE,
+	fyne.KeyZ: 0xA, fyne.KeyX: 0x0, fyne.KeyC: 0xB, fyne.KeyV: 0xF,
+}
+
+// DefaultHoldFrames is how long a typed rune holds its key down.
+const DefaultHoldFrames = 6
+
+// Input presses keypad keys for keyboard events. Desktop canvases
+// report key up and down; others only typed runes, which hold their key
+// down for HoldFrames calls of Frame.
+type Input struct {
+	Keypad     chip8.Keypad
+	Keymap     map[fyne.KeyName]uint8
+	HoldFrames int
+
+	mu   sync.Mutex
+	held map[uint8]int
+}
+
+func NewInput(k chip8.Keypad) *Input {
+	return &Input{
+		Keypad:     k,
+		Keymap:     DefaultKeymap,
+		HoldFrames: DefaultHoldFrames,
+		held:       map[uint8]int{},
+	}
+}
+
+// Attach sets the canvas' keyboard handlers.
+func (in *Input) Attach(c fyne.Canvas) {
+	if dc, ok := c.(desktop.Canvas); ok {
+		dc.SetOnKeyDown(in.KeyDown)
+		dc.SetOnKeyUp(in.KeyUp)
+		return
+	}
+	c.SetOnTypedRune(in.TypedRune)
+}
+
+func (in *Input) KeyDown(ev *fyne.KeyEvent) {
+	if key, ok := in.Keymap[ev.Name]; ok {
+		in.Keypad.Press(key)
+	}
+}
+
+func (in *Input) KeyUp(ev *fyne.KeyEvent) {
+	if key, ok := in.Keymap[ev.Name]; ok {
+		in.Keypad.Release(key)
+	}
+}
+
+// TypedRune presses the key of r, case-insensitively, until it has
+// been held for HoldFrames frames.
+func (in *Input) TypedRune(r rune) {
+	key, ok := in.Keymap[fyne.KeyName(strings.ToUpper(string(r)))]
+	if !ok {
+		return
+	}
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	in.held[key] = in.HoldFrames
+	in.Keypad.Press(key)
+}
+
+// Frame counts down the keys held by typed runes and releases those
+// whose time is up.
+func (in *Input) Frame() {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	for key, n := range in.held {
+		if n <= 1 {
+			delete(in.held, key)
+			in.Keypad.Release(key)
+		} else {
+			in.held[key] = n - 1
+		}
+	}
+}
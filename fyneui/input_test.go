@@ -0,0 +1,37 @@
+package fyneui
+
+import (
+	"testing"
+
+	"fyne.io/fyne"
+	"fyne.io/fyne/test"
+	chip8 "github.com/hermesdt/go-plan8"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInputKeys(t *testing.T) {
+	k := chip8.NewKeypad()
+	in := NewInput(k)
+
+	in.KeyDown(&fyne.KeyEvent{Name: fyne.KeyQ})
+	in.KeyDown(&fyne.KeyEvent{Name: fyne.KeyF1})
+	assert.True(t, k.IsDown(0x4))
+	in.KeyUp(&fyne.KeyEvent{Name: fyne.KeyQ})
+	assert.False(t, k.IsDown(0x4))
+}
+
+func TestInputTypedRunes(t *testing.T) {
+	k := chip8.NewKeypad()
+	in := NewInput(k)
+	in.HoldFrames = 2
+	c := test.NewCanvas()
+	in.Attach(c)
+
+	c.OnTypedRune()('v')
+	c.OnTypedRune()('?')
+	assert.True(t, k.IsDown(0xF))
+	in.Frame()
+	assert.True(t, k.IsDown(0xF))
+	in.Frame()
+	assert.False(t, k.IsDown(0xF))
+}
@@ -0,0 +1,157 @@
+package gdbstub
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"sync"
+)
+
+// interruptByte is sent by gdb, outside any packet, to stop a running
+// target.
+const interruptByte = 0x03
+
+var errChecksum = errors.New("gdbstub: bad packet checksum")
+
+// event is something read from the client: a packet or an interrupt.
+type event struct {
+	packet    string
+	interrupt bool
+	err       error
+}
+
+// conn frames packets as $data#checksum and acknowledges them until no
+// ack mode is negotiated.
+type conn struct {
+	rw     io.ReadWriter
+	events chan event
+	done   chan struct{}
+
+	mu    sync.Mutex
+	noAck bool
+}
+
+func newConn(rw io.ReadWriter) *conn {
+	c := &conn{rw: rw, events: make(chan event), done: make(chan struct{})}
+	go c.read()
+	return c
+}
+
+// close stops delivering events; the connection itself belongs to the
+// caller.
+func (c *conn) close() {
+	close(c.done)
+}
+
+// read parses the client's byte stream into events until it fails or
+// the conn is closed.
+func (c *conn) read() {
+	r := bufio.NewReader(c.rw)
+	for {
+		var e event
+		b, err := r.ReadByte()
+		switch {
+		case err != nil:
+			e.err = err
+		case b == interruptByte:
+			e.interrupt = true
+		case b == '$':
+			e.packet, e.err = readPacket(r)
+			if e.err == errChecksum {
+				c.ack('-')
+				continue
+			}
+			if e.err == nil {
+				c.ack('+')
+			}
+		default:
+			// Acks from the client, '+' and '-', are ignored: replies
+			// are never retransmitted.
+			continue
+		}
+		select {
+		case c.events <- e:
+		case <-c.done:
+			return
+		}
+		if e.err != nil {
+			return
+		}
+	}
+}
+
+// readPacket reads the rest of a packet after its '$'.
+func readPacket(r *bufio.Reader) (string, error) {
+	data, err := r.ReadString('#')
+	if err != nil {
+		return "", err
+	}
+	data = data[:len(data)-1]
+	var sum [2]byte
+	if _, err := io.ReadFull(r, sum[:]); err != nil {
+		return "", err
+	}
+	want, err := strconv.ParseUint(string(sum[:]), 16, 8)
+	if err != nil || uint8(want) != checksum(data) {
+		return "", errChecksum
+	}
+	return unescape(data), nil
+}
+
+func (c *conn) ack(b byte) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.noAck {
+		c.rw.Write([]byte{b})
+	}
+}
+
+func (c *conn) setNoAck() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.noAck = true
+}
+
+func (c *conn) send(data string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	_, err := fmt.Fprintf(c.rw, "$%s#%02x", data, checksum(data))
+	return err
+}
+
+func checksum(data string) uint8 {
+	var sum uint8
+	for i := 0; i < len(data); i++ {
+		sum += data[i]
+	}
+	return sum
+}
+
+// escape protects the bytes that frame packets in binary data.
+func escape(data string) string {
+	var out []byte
+	for i := 0; i < len(data); i++ {
+		switch b := data[i]; b {
+		case '#', '$', '}', '*':
+			out = append(out, '}', b^0x20)
+		default:
+			out = append(out, b)
+		}
+	}
+	return string(out)
+}
+
+func unescape(data string) string {
+	var out []byte
+	for i := 0; i < len(data); i++ {
+		if data[i] == '}' && i+1 < len(data) {
+			i++
+			out = append(out, data[i]^0x20)
+			continue
+		}
+		out = append(out, data[i])
+	}
+	return string(out)
+}
@@ -0,0 +1,65 @@
+package gdbstub
+
+import (
+	"fmt"
+	"strings"
+)
+
+// registerSizes are the sizes in bytes of V0-VF, I, PC, SP, DT and ST.
+var registerSizes = []int{
+	1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1,
+	2, 2, 1, 1, 1,
+}
+
+const registerBytes = 16 + 2 + 2 + 1 + 1 + 1
+
+func registerOffset(n int) int {
+	off := 0
+	for _, size := range registerSizes[:n] {
+		off += size
+	}
+	return off
+}
+
+func (s *Server) registers() []byte {
+	c := s.Debugger.Machine.Chip8
+	bs := append([]byte(nil), c.V[:]...)
+	bs = append(bs, byte(c.I>>8), byte(c.I), byte(c.PC>>8), byte(c.PC))
+	return append(bs, byte(c.SP), c.DelayTimer, c.SoundTimer)
+}
+
+// setRegisters reports false, changing nothing, for an SP past the
+// stack.
+func (s *Server) setRegisters(bs []byte) bool {
+	c := s.Debugger.Machine.Chip8
+	if int(bs[20]) > len(c.Stack) {
+		return false
+	}
+	copy(c.V[:], bs)
+	c.I = uint16(bs[16])<<8 | uint16(bs[17])
+	c.PC = uint16(bs[18])<<8 | uint16(bs[19])
+	c.SP = uint16(bs[20])
+	c.DelayTimer, c.SoundTimer = bs[21], bs[22]
+	return true
+}
+
+var targetXML = func() string {
+	var b strings.Builder
+	b.WriteString(`<?xml version="1.0"?>
+<!DOCTYPE target SYSTEM "gdb-target.dtd">
+<target version="1.0">
+  <feature name="org.go-plan8.chip8">
+`)
+	for x := 0; x < 16; x++ {
+		fmt.Fprintf(&b, "    <reg name=\"v%x\" bitsize=\"8\" type=\"uint8\"/>\n", x)
+	}
+	b.WriteString(`    <reg name="i" bitsize="16" type="data_ptr"/>
+    <reg name="pc" bitsize="16" type="code_ptr"/>
+    <reg name="sp" bitsize="8" type="uint8"/>
+    <reg name="dt" bitsize="8" type="uint8"/>
+    <reg name="st" bitsize="8" type="uint8"/>
+  </feature>
+</target>
+`)
+	return b.String()
+}()
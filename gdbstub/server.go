@@ -0,0 +1,325 @@
+// Package gdbstub serves a chip8 machine over the GDB remote serial
+// protocol, so gdb and other tools speaking it can inspect and control
+// a running program.
+//
+// Registers are numbered V0-VF (0-15, 8 bits), I (16, 16 bits), PC
+// (17, 16 bits), SP (18, 8 bits), DT (19, 8 bits) and ST (20, 8 bits),
+// in big endian order as CHIP-8 stores words. Memory is the variant's
+// address space. Software breakpoints, single-step, continue and
+// interrupting a running target are supported; the target description
+// is served as target.xml.
+package gdbstub
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+
+	chip8 "github.com/hermesdt/go-plan8"
+	"github.com/hermesdt/go-plan8/debugger"
+)
+
+// Stop signals reported to the client.
+const (
+	sigInt  = 2
+	sigIll  = 4
+	sigTrap = 5
+)
+
+type Server struct {
+	Debugger *debugger.Debugger
+
+	breakpoints map[uint16]int
+}
+
+func New(d *debugger.Debugger) *Server {
+	return &Server{Debugger: d, breakpoints: map[uint16]int{}}
+}
+
+// ListenAndServe listens on the TCP address addr and serves d.
+func ListenAndServe(addr string, d *debugger.Debugger) error {
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	defer l.Close()
+	return New(d).Serve(l)
+}
+
+// Serve accepts connections on l and serves them one at a time, since
+// they all control the same machine.
+func (s *Server) Serve(l net.Listener) error {
+	for {
+		c, err := l.Accept()
+		if err != nil {
+			return err
+		}
+		err = s.ServeConn(c)
+		c.Close()
+		if err != nil && err != io.EOF {
+			return err
+		}
+	}
+}
+
+// errDetach ends a session at the client's request.
+var errDetach = errors.New("gdbstub: detached")
+
+// ServeConn serves one client until it detaches or disconnects.
+func (s *Server) ServeConn(rw io.ReadWriter) error {
+	c := newConn(rw)
+	defer c.close()
+	for e := range c.events {
+		if e.err != nil {
+			return e.err
+		}
+		if e.interrupt {
+			// The target only runs inside continue, so a late
+			// interrupt has nothing to stop.
+			continue
+		}
+		reply, err := s.handle(c, e.packet)
+		if err == errDetach {
+			c.send("OK")
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if err := c.send(reply); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// handle answers one packet. An empty reply tells the client the
+// packet is not supported.
+func (s *Server) handle(c *conn, p string) (string, error) {
+	m := s.Debugger.Machine.Chip8
+	if p == "" {
+		return "", nil
+	}
+	switch p[0] {
+	case '?':
+		return stopReply(debugger.Stop{Reason: debugger.StopStep}), nil
+	case 'g':
+		return hex.EncodeToString(s.registers()), nil
+	case 'G':
+		bs, err := hex.DecodeString(p[1:])
+		if err != nil || len(bs) != registerBytes {
+			return "E01", nil
+		}
+		if !s.setRegisters(bs) {
+			return "E01", nil
+		}
+		return "OK", nil
+	case 'p':
+		n, err := strconv.ParseUint(p[1:], 16, 8)
+		if err != nil || n >= uint64(len(registerSizes)) {
+			return "E01", nil
+		}
+		off := registerOffset(int(n))
+		return hex.EncodeToString(s.registers()[off : off+registerSizes[n]]), nil
+	case 'P':
+		parts := strings.SplitN(p[1:], "=", 2)
+		n, err := strconv.ParseUint(parts[0], 16, 8)
+		if err != nil || len(parts) != 2 || n >= uint64(len(registerSizes)) {
+			return "E01", nil
+		}
+		v, err := hex.DecodeString(parts[1])
+		if err != nil || len(v) != registerSizes[n] {
+			return "E01", nil
+		}
+		regs := s.registers()
+		copy(regs[registerOffset(int(n)):], v)
+		if !s.setRegisters(regs) {
+			return "E01", nil
+		}
+		return "OK", nil
+	case 'm':
+		addr, n, ok := s.span(p[1:])
+		if !ok {
+			return "E01", nil
+		}
+		return hex.EncodeToString(m.Memory[addr : addr+n]), nil
+	case 'M':
+		parts := strings.SplitN(p[1:], ":", 2)
+		addr, n, ok := s.span(parts[0])
+		if !ok || len(parts) != 2 {
+			return "E01", nil
+		}
+		bs, err := hex.DecodeString(parts[1])
+		if err != nil || len(bs) != n {
+			return "E01", nil
+		}
+		copy(m.Memory[addr:], bs)
+		return "OK", nil
+	case 'Z', 'z':
+		return s.breakpoint(p), nil
+	case 's':
+		if !s.resumeAt(p[1:]) {
+			return "E01", nil
+		}
+		return stopReply(s.Debugger.Step(context.Background(), 1)), nil
+	case 'c':
+		if !s.resumeAt(p[1:]) {
+			return "E01", nil
+		}
+		stop, err := s.cont(c)
+		return stopReply(stop), err
+	case 'H':
+		return "OK", nil
+	case 'D':
+		return "", errDetach
+	case 'k':
+		return "", io.EOF
+	case 'q', 'Q':
+		return s.query(c, p), nil
+	}
+	return "", nil
+}
+
+// cont continues until the program stops, the client interrupts or
+// the connection fails.
+func (s *Server) cont(c *conn) (debugger.Stop, error) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	done := make(chan debugger.Stop, 1)
+	go func() {
+		done <- s.Debugger.Continue(ctx)
+	}()
+	var err error
+	for {
+		select {
+		case stop := <-done:
+			return stop, err
+		case e := <-c.events:
+			// Clients send nothing but interrupts while the target
+			// runs.
+			if e.err != nil {
+				err = e.err
+			}
+			if e.interrupt || e.err != nil {
+				cancel()
+			}
+		}
+	}
+}
+
+// resumeAt moves PC to the optional address of s and c packets.
+func (s *Server) resumeAt(addr string) bool {
+	if addr == "" {
+		return true
+	}
+	pc, err := strconv.ParseUint(addr, 16, 16)
+	if err != nil {
+		return false
+	}
+	s.Debugger.Machine.Chip8.PC = uint16(pc)
+	return true
+}
+
+// span parses "addr,length" and checks it lies within memory.
+func (s *Server) span(arg string) (int, int, bool) {
+	parts := strings.SplitN(arg, ",", 2)
+	if len(parts) != 2 {
+		return 0, 0, false
+	}
+	addr, err1 := strconv.ParseUint(parts[0], 16, 32)
+	n, err2 := strconv.ParseUint(parts[1], 16, 32)
+	size := uint64(s.Debugger.Machine.Chip8.Variant.MemorySize())
+	if err1 != nil || err2 != nil || addr > size || n > size-addr {
+		return 0, 0, false
+	}
+	return int(addr), int(n), true
+}
+
+// breakpoint handles Z0/Z1 and z0/z1 packets. Hardware breakpoints are
+// the same as software ones here.
+func (s *Server) breakpoint(p string) string {
+	parts := strings.Split(p[1:], ",")
+	if len(parts) < 2 || (parts[0] != "0" && parts[0] != "1") {
+		return ""
+	}
+	addr, err := strconv.ParseUint(parts[1], 16, 16)
+	if err != nil {
+		return "E01"
+	}
+	id, set := s.breakpoints[uint16(addr)]
+	if p[0] == 'z' {
+		if set {
+			s.Debugger.Delete(id)
+			delete(s.breakpoints, uint16(addr))
+		}
+		return "OK"
+	}
+	if !set {
+		b, err := s.Debugger.Break(int(addr), "")
+		if err != nil {
+			return "E01"
+		}
+		s.breakpoints[uint16(addr)] = b.ID
+	}
+	return "OK"
+}
+
+func (s *Server) query(c *conn, p string) string {
+	switch {
+	case strings.HasPrefix(p, "qSupported"):
+		return "PacketSize=4000;qXfer:features:read+;QStartNoAckMode+"
+	case p == "QStartNoAckMode":
+		c.setNoAck()
+		return "OK"
+	case p == "qAttached":
+		return "1"
+	case p == "qC":
+		return "QC1"
+	case p == "qfThreadInfo":
+		return "m1"
+	case p == "qsThreadInfo":
+		return "l"
+	case strings.HasPrefix(p, "qXfer:features:read:target.xml:"):
+		return xfer(targetXML, strings.TrimPrefix(p, "qXfer:features:read:target.xml:"))
+	}
+	return ""
+}
+
+// xfer returns the window "offset,length" of doc.
+func xfer(doc, window string) string {
+	parts := strings.SplitN(window, ",", 2)
+	if len(parts) != 2 {
+		return "E01"
+	}
+	off, err1 := strconv.ParseUint(parts[0], 16, 32)
+	n, err2 := strconv.ParseUint(parts[1], 16, 32)
+	if err1 != nil || err2 != nil {
+		return "E01"
+	}
+	if off >= uint64(len(doc)) {
+		return "l"
+	}
+	doc = doc[off:]
+	if uint64(len(doc)) <= n {
+		return "l" + escape(doc)
+	}
+	return "m" + escape(doc[:n])
+}
+
+func stopReply(stop debugger.Stop) string {
+	switch stop.Reason {
+	case debugger.StopInterrupted:
+		return fmt.Sprintf("S%02x", sigInt)
+	case debugger.StopHalted:
+		if errors.Is(stop.Err, chip8.ErrExit) {
+			return "W00"
+		}
+		return fmt.Sprintf("S%02x", sigIll)
+	}
+	return fmt.Sprintf("S%02x", sigTrap)
+}
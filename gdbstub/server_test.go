@@ -0,0 +1,219 @@
+package gdbstub
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	chip8 "github.com/hermesdt/go-plan8"
+	"github.com/hermesdt/go-plan8/asm"
+	"github.com/hermesdt/go-plan8/debugger"
+	"github.com/stretchr/testify/assert"
+)
+
+const testProgram = `
+	start:
+		LD V3, 0          ; 200
+	loop:
+		ADD V3, 4         ; 202
+		CALL sub          ; 204
+		LD I, 0x300       ; 206
+		LD [I], V3        ; 208
+		JP loop           ; 20A
+	sub:
+		ADD V1, 1         ; 20C
+		RET               ; 20E
+`
+
+// client speaks the protocol the way gdb does.
+type client struct {
+	t     *testing.T
+	l     net.Listener
+	conn  net.Conn
+	r     *bufio.Reader
+	noAck bool
+}
+
+func dial(t *testing.T, src string) (*client, *debugger.Debugger) {
+	rom, err := asm.Assemble(src, chip8.VariantChip8)
+	assert.Nil(t, err)
+	c := chip8.NewChip8(chip8.VariantChip8)
+	assert.Nil(t, c.LoadRomBytes(rom))
+	d := debugger.New(chip8.NewMachine(c))
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	go New(d).Serve(l)
+
+	conn, err := net.Dial("tcp", l.Addr().String())
+	assert.Nil(t, err)
+	conn.SetDeadline(time.Now().Add(10 * time.Second))
+	return &client{t: t, l: l, conn: conn, r: bufio.NewReader(conn)}, d
+}
+
+func (c *client) close() {
+	c.conn.Close()
+	c.l.Close()
+}
+
+func (c *client) send(packet string) {
+	fmt.Fprintf(c.conn, "$%s#%02x", packet, checksum(packet))
+	if !c.noAck {
+		b, err := c.r.ReadByte()
+		assert.Nil(c.t, err)
+		assert.Equal(c.t, byte('+'), b)
+	}
+}
+
+func (c *client) recv() string {
+	_, err := c.r.ReadString('$')
+	assert.Nil(c.t, err)
+	data, err := c.r.ReadString('#')
+	assert.Nil(c.t, err)
+	data = data[:len(data)-1]
+	sum := make([]byte, 2)
+	_, err = io.ReadFull(c.r, sum)
+	assert.Nil(c.t, err)
+	assert.Equal(c.t, fmt.Sprintf("%02x", checksum(data)), string(sum))
+	if !c.noAck {
+		c.conn.Write([]byte{'+'})
+	}
+	return unescape(data)
+}
+
+func (c *client) call(packet string) string {
+	c.send(packet)
+	return c.recv()
+}
+
+func TestHandshake(t *testing.T) {
+	c, _ := dial(t, testProgram)
+	defer c.close()
+
+	assert.Contains(t, c.call("qSupported:multiprocess+;swbreak+"), "QStartNoAckMode+")
+	assert.Equal(t, "OK", c.call("QStartNoAckMode"))
+	c.noAck = true
+	assert.Equal(t, "S05", c.call("?"))
+	assert.Equal(t, "", c.call("vMustReplyEmpty"))
+
+	var xml string
+	for off := 0; ; off += 0x100 {
+		chunk := c.call(fmt.Sprintf("qXfer:features:read:target.xml:%x,100", off))
+		xml += chunk[1:]
+		if chunk[0] == 'l' {
+			break
+		}
+		assert.Equal(t, byte('m'), chunk[0])
+	}
+	assert.Equal(t, targetXML, xml)
+	assert.Equal(t, len(registerSizes), strings.Count(xml, "<reg "))
+}
+
+func TestRegisters(t *testing.T) {
+	c, d := dial(t, testProgram)
+	defer c.close()
+	m := d.Machine.Chip8
+	m.V[0], m.V[0xF] = 0x12, 0x34
+	m.I, m.DelayTimer, m.SoundTimer = 0xABC, 7, 8
+
+	regs := c.call("g")
+
+	assert.Equal(t, "12"+strings.Repeat("00", 14)+"34"+"0abc"+"0200"+"00"+"07"+"08", regs)
+
+	assert.Equal(t, "OK", c.call("P11=020c"))
+	assert.Equal(t, uint16(0x20C), m.PC)
+	assert.Equal(t, "020c", c.call("p11"))
+	assert.Equal(t, "OK", c.call("P5=ff"))
+	assert.Equal(t, uint8(0xFF), m.V[5])
+	assert.Equal(t, "E01", c.call("P5=ffff"))
+	assert.Equal(t, "E01", c.call("p15"))
+
+	regs = strings.Repeat("01", 16) + "0300" + "0206" + "02" + "03" + "04"
+	assert.Equal(t, "OK", c.call("G"+regs))
+	assert.Equal(t, regs, c.call("g"))
+	assert.Equal(t, uint8(1), m.V[9])
+	assert.Equal(t, uint16(0x300), m.I)
+	assert.Equal(t, uint16(2), m.SP)
+
+	assert.Equal(t, "E01", c.call("P12=40"))
+	assert.Equal(t, "E01", c.call("G"+strings.Repeat("01", 16)+"0300"+"0206"+"11"+"03"+"04"))
+	assert.Equal(t, uint16(2), m.SP)
+	assert.Equal(t, "OK", c.call("P12=10"))
+	assert.Equal(t, uint16(16), m.SP)
+}
+
+func TestMemory(t *testing.T) {
+	c, d := dial(t, testProgram)
+	defer c.close()
+
+	assert.Equal(t, "6300", c.call("m200,2"))
+	assert.Equal(t, "OK", c.call("M300,3:010203"))
+	assert.Equal(t, []byte{1, 2, 3}, d.Machine.Chip8.Memory[0x300:0x303])
+	assert.Equal(t, "00", c.call("mfff,1"))
+	assert.Equal(t, "E01", c.call("mfff,2"))
+	assert.Equal(t, "E01", c.call("M300,2:01"))
+}
+
+func TestStepAndBreakpoints(t *testing.T) {
+	c, d := dial(t, testProgram)
+	defer c.close()
+	m := d.Machine.Chip8
+
+	assert.Equal(t, "S05", c.call("s"))
+	assert.Equal(t, uint16(0x202), m.PC)
+	assert.Equal(t, "0202", c.call("p11"))
+
+	assert.Equal(t, "OK", c.call("Z0,20c,2"))
+	assert.Equal(t, "S05", c.call("c"))
+	assert.Equal(t, uint16(0x20C), m.PC)
+	assert.Equal(t, uint8(4), m.V[3])
+
+	// Continuing from a breakpoint does not stop at it again at once.
+	assert.Equal(t, "S05", c.call("c"))
+	assert.Equal(t, uint16(0x20C), m.PC)
+	assert.Equal(t, uint8(8), m.V[3])
+
+	assert.Equal(t, "OK", c.call("z0,20c,2"))
+	assert.Equal(t, "OK", c.call("Z0,208,2"))
+	assert.Equal(t, "S05", c.call("c"))
+	assert.Equal(t, uint16(0x208), m.PC)
+	assert.Equal(t, "", c.call("Z2,300,1"))
+
+	assert.Equal(t, "S05", c.call("s20a"))
+	assert.Equal(t, uint16(0x202), m.PC)
+}
+
+func TestInterruptAndFault(t *testing.T) {
+	c, d := dial(t, "loop: JP loop")
+	defer c.close()
+
+	c.send("c")
+	c.conn.Write([]byte{interruptByte})
+	assert.Equal(t, "S02", c.recv())
+	assert.Equal(t, uint16(0x200), d.Machine.Chip8.PC)
+
+	assert.Equal(t, "OK", c.call("M200,2:00ee"))
+	assert.Equal(t, "S04", c.call("c"))
+}
+
+func TestDetach(t *testing.T) {
+	c, _ := dial(t, testProgram)
+	defer c.close()
+	assert.Equal(t, "OK", c.call("D"))
+	_, err := c.r.ReadByte()
+	assert.NotNil(t, err)
+}
+
+func TestBadChecksum(t *testing.T) {
+	c, _ := dial(t, testProgram)
+	defer c.close()
+	fmt.Fprint(c.conn, "$g#00")
+	b, err := c.r.ReadByte()
+	assert.Nil(t, err)
+	assert.Equal(t, byte('-'), b)
+	assert.Equal(t, "S05", c.call("?"))
+}
@@ -1,6 +1,6 @@
 module github.com/hermesdt/go-plan8
 
-go 1.12
+go 1.13
 
 require (
 	fyne.io/fyne v1.0.1
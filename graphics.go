@@ -1,33 +1,112 @@
 package chip8
 
 import (
+	"strconv"
 	"strings"
 )
 
 const (
 	ScreenWidth  int = 64
 	ScreenHeight int = 32
+	HiResWidth   int = 128
+	HiResHeight  int = 64
+	// Planes is the number of XO-CHIP bitplanes. A pixel's colour is
+	// the bitmask of the planes it is lit in.
+	Planes int = 2
 )
 
 var b2i = map[bool]uint8{false: 0, true: 1}
 
+// Screen is 64x32 in the default low resolution mode and 128x64 in
+// the SUPER-CHIP high resolution mode. It has two XO-CHIP bitplanes;
+// Set, Get, GetByte and DrawByte work on the first one, while drawing,
+// clearing and scrolling from opcodes only affect the selected planes.
 type Screen struct {
-	px [ScreenWidth * ScreenHeight]bool
+	px    [Planes][HiResWidth * HiResHeight]bool
+	hires bool
+	// selected holds the plane mask with planeSelected set, so that
+	// the zero value means plane 1 only.
+	selected uint8
+}
+
+const planeSelected = 0x80
+
+func (s *Screen) Width() int {
+	if s.hires {
+		return HiResWidth
+	}
+	return ScreenWidth
+}
+
+func (s *Screen) Height() int {
+	if s.hires {
+		return HiResHeight
+	}
+	return ScreenHeight
+}
+
+func (s *Screen) HiRes() bool {
+	return s.hires
+}
+
+// SetHiRes switches resolution, clearing every plane.
+func (s *Screen) SetHiRes(hires bool) {
+	s.hires = hires
+	s.px = [Planes][HiResWidth * HiResHeight]bool{}
+}
+
+// SelectPlanes sets the bitmask of planes affected by drawing,
+// clearing and scrolling.
+func (s *Screen) SelectPlanes(mask uint8) {
+	s.selected = mask&(1<<uint(Planes)-1) | planeSelected
+}
+
+func (s *Screen) SelectedPlanes() uint8 {
+	if s.selected == 0 {
+		return 1
+	}
+	return s.selected &^ planeSelected
+}
+
+func (s *Screen) eachPlane(f func(p int)) {
+	mask := s.SelectedPlanes()
+	for p := 0; p < Planes; p++ {
+		if mask&(1<<uint(p)) != 0 {
+			f(p)
+		}
+	}
 }
 
 func (s *Screen) Set(y, x int, value bool) {
-	s.px[y*ScreenWidth+x] = value
+	s.SetPlane(0, y, x, value)
 }
 
 func (s *Screen) Get(y, x int) bool {
-	return s.px[y*ScreenWidth+x]
+	return s.GetPlane(0, y, x)
+}
+
+func (s *Screen) SetPlane(p, y, x int, value bool) {
+	s.px[p][y*s.Width()+x] = value
+}
+
+func (s *Screen) GetPlane(p, y, x int) bool {
+	return s.px[p][y*s.Width()+x]
+}
+
+// Pixel returns the colour index at (y, x), from 0 to 1<<Planes-1.
+func (s *Screen) Pixel(y, x int) uint8 {
+	var c uint8
+	for p := 0; p < Planes; p++ {
+		c |= b2i[s.GetPlane(p, y, x)] << uint(p)
+	}
+	return c
 }
 
 func (s *Screen) GetByte(y, x int) byte {
 	var b byte = 0
 	for i := 0; i < 8; i++ {
 		row := y
-		col := (x + i) % ScreenWidth
+		col := (x + i) % s.Width()
 
 		bit := b2i[s.Get(row, col)]
 		b |= bit << (7 - uint(i))
@@ -36,15 +115,22 @@ func (s *Screen) GetByte(y, x int) byte {
 }
 
 func (s *Screen) DrawByte(y, x int, b byte) bool {
+	return s.drawByte(0, y, x, b, false)
+}
+
+func (s *Screen) drawByte(p, y, x int, b byte, clip bool) bool {
 	collision := false
 	for i := 0; i < 8; i++ {
+		if clip && x+i >= s.Width() {
+			break
+		}
 		row := y
-		col := (x + i) % ScreenWidth
+		col := (x + i) % s.Width()
 		mask := byte(0x80 >> uint(i))
 		value := b2i[(b&mask)>>(7-uint(i)) == 1]
-		oldValue := b2i[s.Get(row, col)]
+		oldValue := b2i[s.GetPlane(p, row, col)]
 
-		s.Set(row, col, oldValue^value == 1)
+		s.SetPlane(p, row, col, oldValue^value == 1)
 		if oldValue^value == 0 && oldValue == 1 {
 			collision = collision || true
 		}
@@ -53,24 +139,53 @@ func (s *Screen) DrawByte(y, x int, b byte) bool {
 }
 
 func (s *Screen) Clear() {
-	for y := 0; y < ScreenHeight; y++ {
-		for x := 0; x < ScreenWidth; x++ {
-			s.Set(y, x, false)
+	s.eachPlane(func(p int) {
+		s.px[p] = [HiResWidth * HiResHeight]bool{}
+	})
+}
+
+// scroll moves every pixel of the selected planes by dy rows and dx
+// columns, filling the uncovered area with blank pixels.
+func (s *Screen) scroll(dy, dx int) {
+	w, h := s.Width(), s.Height()
+	s.eachPlane(func(p int) {
+		var moved [HiResWidth * HiResHeight]bool
+		for y := 0; y < h; y++ {
+			for x := 0; x < w; x++ {
+				sy, sx := y-dy, x-dx
+				if sy < 0 || sy >= h || sx < 0 || sx >= w {
+					continue
+				}
+				moved[y*w+x] = s.px[p][sy*w+sx]
+			}
 		}
-	}
+		s.px[p] = moved
+	})
+}
+
+func (s *Screen) ScrollDown(n int) {
+	s.scroll(n, 0)
 }
 
+func (s *Screen) ScrollUp(n int) {
+	s.scroll(-n, 0)
+}
+
+func (s *Screen) ScrollLeft(n int) {
+	s.scroll(0, -n)
+}
+
+func (s *Screen) ScrollRight(n int) {
+	s.scroll(0, n)
+}
+
+// Render returns one line per row with the colour index of each pixel.
 func (s *Screen) Render() string {
 	lines := []string{}
-	for row := 0; row < ScreenHeight; row++ {
+	for row := 0; row < s.Height(); row++ {
 		line := []string{}
-		for col := 0; col < ScreenWidth; col++ {
-			if s.Get(row, col) {
-				line = append(line, "1")
-			} else {
-				line = append(line, "0")
-			}
-
+		for col := 0; col < s.Width(); col++ {
+			line = append(line, strconv.Itoa(int(s.Pixel(row, col))))
 		}
 		lines = append(lines, strings.Join(line, ""))
 	}
@@ -18,3 +18,34 @@ func TestScreenDraw(t *testing.T) {
 	assert.Equal(t, screen.GetByte(2, 8), byte(0xc3))
 	assert.Equal(t, screen.GetByte(3, 8), byte(0xff))
 }
+
+func TestScreenHiRes(t *testing.T) {
+	var screen Screen
+	screen.Set(1, 1, true)
+
+	screen.SetHiRes(true)
+
+	assert.Equal(t, HiResWidth, screen.Width())
+	assert.Equal(t, HiResHeight, screen.Height())
+	assert.False(t, screen.Get(1, 1))
+	screen.Set(HiResHeight-1, HiResWidth-1, true)
+	assert.True(t, screen.Get(HiResHeight-1, HiResWidth-1))
+}
+
+func TestScreenScroll(t *testing.T) {
+	var screen Screen
+	screen.DrawByte(0, 0, 0x81)
+
+	screen.ScrollDown(2)
+	assert.Equal(t, byte(0x00), screen.GetByte(0, 0))
+	assert.Equal(t, byte(0x81), screen.GetByte(2, 0))
+
+	screen.ScrollRight(4)
+	assert.Equal(t, byte(0x08), screen.GetByte(2, 0))
+	assert.Equal(t, byte(0x10), screen.GetByte(2, 8))
+
+	screen.ScrollLeft(4)
+	screen.ScrollLeft(4)
+	assert.Equal(t, byte(0x10), screen.GetByte(2, 0))
+	assert.Equal(t, byte(0x00), screen.GetByte(2, ScreenWidth-8))
+}
@@ -0,0 +1,113 @@
+package chip8
+
+// Instruction describes one entry of the decoding table shared by
+// Opcode.Execute and the disassembler. Name is the Opcode method that
+// executes it.
+type Instruction struct {
+	Name    string
+	Pattern uint16
+	Mask    uint16
+	Variant Variant
+	// Size is the instruction length in bytes, 2 unless set.
+	Size int
+	exec func(o *Opcode) error
+}
+
+// instructions is searched in order and the first entry whose masked
+// pattern matches wins, so specific encodings come before the catch-all
+// ones sharing their top nibble.
+var instructions = []Instruction{
+	{Name: "DispClr", Pattern: 0x00E0, Mask: 0xFFFF, Variant: VariantChip8, exec: noError((*Opcode).DispClr)},
+	{Name: "Return", Pattern: 0x00EE, Mask: 0xFFFF, Variant: VariantChip8, exec: (*Opcode).Return},
+	{Name: "ScrollDown", Pattern: 0x00C0, Mask: 0xFFF0, Variant: VariantSChip, exec: noError((*Opcode).ScrollDown)},
+	{Name: "ScrollUp", Pattern: 0x00D0, Mask: 0xFFF0, Variant: VariantXOChip, exec: noError((*Opcode).ScrollUp)},
+	{Name: "ScrollRight", Pattern: 0x00FB, Mask: 0xFFFF, Variant: VariantSChip, exec: noError((*Opcode).ScrollRight)},
+	{Name: "ScrollLeft", Pattern: 0x00FC, Mask: 0xFFFF, Variant: VariantSChip, exec: noError((*Opcode).ScrollLeft)},
+	{Name: "Exit", Pattern: 0x00FD, Mask: 0xFFFF, Variant: VariantSChip, exec: (*Opcode).Exit},
+	{Name: "LowRes", Pattern: 0x00FE, Mask: 0xFFFF, Variant: VariantSChip, exec: noError((*Opcode).LowRes)},
+	{Name: "HighRes", Pattern: 0x00FF, Mask: 0xFFFF, Variant: VariantSChip, exec: noError((*Opcode).HighRes)},
+	{Name: "Call", Pattern: 0x0000, Mask: 0xF000, Variant: VariantChip8, exec: (*Opcode).Call},
+	{Name: "Jump", Pattern: 0x1000, Mask: 0xF000, Variant: VariantChip8, exec: noError((*Opcode).Jump)},
+	{Name: "CallSub", Pattern: 0x2000, Mask: 0xF000, Variant: VariantChip8, exec: (*Opcode).CallSub},
+	{Name: "SkipEq", Pattern: 0x3000, Mask: 0xF000, Variant: VariantChip8, exec: noError((*Opcode).SkipEq)},
+	{Name: "SkipNeq", Pattern: 0x4000, Mask: 0xF000, Variant: VariantChip8, exec: noError((*Opcode).SkipNeq)},
+	{Name: "SaveRange", Pattern: 0x5002, Mask: 0xF00F, Variant: VariantXOChip, exec: (*Opcode).SaveRange},
+	{Name: "LoadRange", Pattern: 0x5003, Mask: 0xF00F, Variant: VariantXOChip, exec: (*Opcode).LoadRange},
+	{Name: "SkipEqVY", Pattern: 0x5000, Mask: 0xF000, Variant: VariantChip8, exec: noError((*Opcode).SkipEqVY)},
+	{Name: "Set", Pattern: 0x6000, Mask: 0xF000, Variant: VariantChip8, exec: noError((*Opcode).Set)},
+	{Name: "Add", Pattern: 0x7000, Mask: 0xF000, Variant: VariantChip8, exec: noError((*Opcode).Add)},
+	{Name: "SetVY", Pattern: 0x8000, Mask: 0xF00F, Variant: VariantChip8, exec: noError((*Opcode).SetVY)},
+	{Name: "OrVY", Pattern: 0x8001, Mask: 0xF00F, Variant: VariantChip8, exec: noError((*Opcode).OrVY)},
+	{Name: "AndVY", Pattern: 0x8002, Mask: 0xF00F, Variant: VariantChip8, exec: noError((*Opcode).AndVY)},
+	{Name: "XorVY", Pattern: 0x8003, Mask: 0xF00F, Variant: VariantChip8, exec: noError((*Opcode).XorVY)},
+	{Name: "AddVY", Pattern: 0x8004, Mask: 0xF00F, Variant: VariantChip8, exec: noError((*Opcode).AddVY)},
+	{Name: "SubVY", Pattern: 0x8005, Mask: 0xF00F, Variant: VariantChip8, exec: noError((*Opcode).SubVY)},
+	{Name: "ShiftRight", Pattern: 0x8006, Mask: 0xF00F, Variant: VariantChip8, exec: noError((*Opcode).ShiftRight)},
+	{Name: "VYSub", Pattern: 0x8007, Mask: 0xF00F, Variant: VariantChip8, exec: noError((*Opcode).VYSub)},
+	{Name: "ShiftLeft", Pattern: 0x800E, Mask: 0xF00F, Variant: VariantChip8, exec: noError((*Opcode).ShiftLeft)},
+	{Name: "SkipNeqVY", Pattern: 0x9000, Mask: 0xF000, Variant: VariantChip8, exec: noError((*Opcode).SkipNeqVY)},
+	{Name: "SetI", Pattern: 0xA000, Mask: 0xF000, Variant: VariantChip8, exec: noError((*Opcode).SetI)},
+	{Name: "JumpPlusV0", Pattern: 0xB000, Mask: 0xF000, Variant: VariantChip8, exec: noError((*Opcode).JumpPlusV0)},
+	{Name: "SetRandomMask", Pattern: 0xC000, Mask: 0xF000, Variant: VariantChip8, exec: noError((*Opcode).SetRandomMask)},
+	{Name: "Draw", Pattern: 0xD000, Mask: 0xF000, Variant: VariantChip8, exec: (*Opcode).Draw},
+	{Name: "SkipKeyPressed", Pattern: 0xE09E, Mask: 0xF0FF, Variant: VariantChip8, exec: noError((*Opcode).SkipKeyPressed)},
+	{Name: "SkipNotKeyPressed", Pattern: 0xE0A1, Mask: 0xF0FF, Variant: VariantChip8, exec: noError((*Opcode).SkipNotKeyPressed)},
+	{Name: "SetILong", Pattern: 0xF000, Mask: 0xFFFF, Variant: VariantXOChip, Size: 4, exec: (*Opcode).SetILong},
+	{Name: "SelectPlanes", Pattern: 0xF001, Mask: 0xF0FF, Variant: VariantXOChip, exec: noError((*Opcode).SelectPlanes)},
+	{Name: "LoadAudio", Pattern: 0xF002, Mask: 0xFFFF, Variant: VariantXOChip, exec: (*Opcode).LoadAudio},
+	{Name: "SetFromDelay", Pattern: 0xF007, Mask: 0xF0FF, Variant: VariantChip8, exec: noError((*Opcode).SetFromDelay)},
+	{Name: "ReadKey", Pattern: 0xF00A, Mask: 0xF0FF, Variant: VariantChip8, exec: noError((*Opcode).ReadKey)},
+	{Name: "SetDelay", Pattern: 0xF015, Mask: 0xF0FF, Variant: VariantChip8, exec: noError((*Opcode).SetDelay)},
+	{Name: "SetSound", Pattern: 0xF018, Mask: 0xF0FF, Variant: VariantChip8, exec: noError((*Opcode).SetSound)},
+	{Name: "AddI", Pattern: 0xF01E, Mask: 0xF0FF, Variant: VariantChip8, exec: noError((*Opcode).AddI)},
+	{Name: "SetISprite", Pattern: 0xF029, Mask: 0xF0FF, Variant: VariantChip8, exec: noError((*Opcode).SetISprite)},
+	{Name: "SetIBigSprite", Pattern: 0xF030, Mask: 0xF0FF, Variant: VariantSChip, exec: noError((*Opcode).SetIBigSprite)},
+	{Name: "SetBCD", Pattern: 0xF033, Mask: 0xF0FF, Variant: VariantChip8, exec: (*Opcode).SetBCD},
+	{Name: "SetPitch", Pattern: 0xF03A, Mask: 0xF0FF, Variant: VariantXOChip, exec: noError((*Opcode).SetPitch)},
+	{Name: "RegDump", Pattern: 0xF055, Mask: 0xF0FF, Variant: VariantChip8, exec: (*Opcode).RegDump},
+	{Name: "RegLoad", Pattern: 0xF065, Mask: 0xF0FF, Variant: VariantChip8, exec: (*Opcode).RegLoad},
+	{Name: "SaveFlags", Pattern: 0xF075, Mask: 0xF0FF, Variant: VariantSChip, exec: (*Opcode).SaveFlags},
+	{Name: "LoadFlags", Pattern: 0xF085, Mask: 0xF0FF, Variant: VariantSChip, exec: (*Opcode).LoadFlags},
+}
+
+// byNibble holds the entries of instructions under the top nibble of
+// their pattern, in table order. Every mask covers that nibble.
+var byNibble [16][]*Instruction
+
+func init() {
+	for i := range instructions {
+		in := &instructions[i]
+		byNibble[in.Pattern>>12] = append(byNibble[in.Pattern>>12], in)
+	}
+}
+
+// Instructions returns a copy of the decoding table, in the order
+// Decode searches it.
+func Instructions() []Instruction {
+	return append([]Instruction(nil), instructions...)
+}
+
+func noError(f func(o *Opcode)) func(o *Opcode) error {
+	return func(o *Opcode) error {
+		f(o)
+		return nil
+	}
+}
+
+func (i *Instruction) Len() int {
+	if i.Size == 0 {
+		return 2
+	}
+	return i.Size
+}
+
+// Decode returns the instruction a variant executes for value, or nil
+// if it has none.
+func Decode(value uint16, variant Variant) *Instruction {
+	for _, in := range byNibble[value>>12] {
+		if value&in.Mask == in.Pattern && variant >= in.Variant {
+			return in
+		}
+	}
+	return nil
+}
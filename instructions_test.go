@@ -0,0 +1,37 @@
+package chip8
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDecode(t *testing.T) {
+	// Decode must find the same entry as a search of the whole table.
+	for _, variant := range []Variant{VariantChip8, VariantSChip, VariantXOChip} {
+		for v := 0; v <= 0xFFFF; v++ {
+			var want *Instruction
+			for i := range instructions {
+				in := &instructions[i]
+				if uint16(v)&in.Mask == in.Pattern && variant >= in.Variant {
+					want = in
+					break
+				}
+			}
+			if got := Decode(uint16(v), variant); got != want {
+				t.Fatalf("Decode(%04X, %v) = %v, want %v", v, variant, got, want)
+			}
+		}
+	}
+	assert.Equal(t, "SaveRange", Decode(0x5122, VariantXOChip).Name)
+	assert.Equal(t, "SkipEqVY", Decode(0x5122, VariantSChip).Name)
+	assert.Nil(t, Decode(0xF075, VariantChip8))
+}
+
+func TestInstructions_copy(t *testing.T) {
+	table := Instructions()
+	assert.Len(t, table, len(instructions))
+	table[0].Name = "Changed"
+
+	assert.Equal(t, "DispClr", Decode(0x00E0, VariantChip8).Name)
+}
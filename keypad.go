@@ -0,0 +1,58 @@
+package chip8
+
+import "sync"
+
+const KeyCount = 16
+
+// Keypad is the 16-key hex keypad. Frontends report events through
+// Press and Release from any goroutine while the CPU reads it. Keys
+// outside 0x0-0xF are masked to their low nibble.
+type Keypad interface {
+	Press(key uint8)
+	Release(key uint8)
+	IsDown(key uint8) bool
+	// NextRelease returns a channel that receives the next key to be
+	// released after the call.
+	NextRelease() <-chan uint8
+}
+
+type MemoryKeypad struct {
+	mu      sync.Mutex
+	down    [KeyCount]bool
+	waiters []chan uint8
+}
+
+func NewKeypad() *MemoryKeypad {
+	return &MemoryKeypad{}
+}
+
+func (k *MemoryKeypad) Press(key uint8) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	k.down[key&0xF] = true
+}
+
+func (k *MemoryKeypad) Release(key uint8) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	key &= 0xF
+	k.down[key] = false
+	for _, ch := range k.waiters {
+		ch <- key
+	}
+	k.waiters = nil
+}
+
+func (k *MemoryKeypad) IsDown(key uint8) bool {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	return k.down[key&0xF]
+}
+
+func (k *MemoryKeypad) NextRelease() <-chan uint8 {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	ch := make(chan uint8, 1)
+	k.waiters = append(k.waiters, ch)
+	return ch
+}
@@ -0,0 +1,56 @@
+package chip8
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMemoryKeypad(t *testing.T) {
+	k := NewKeypad()
+	k.Press(0x3)
+	assert.True(t, k.IsDown(0x3))
+	assert.True(t, k.IsDown(0x13))
+
+	k.Release(0x3)
+	assert.False(t, k.IsDown(0x3))
+}
+
+func TestMemoryKeypad_nextRelease(t *testing.T) {
+	k := NewKeypad()
+	k.Press(0xA)
+	k.Release(0xA)
+
+	ch := k.NextRelease()
+	select {
+	case <-ch:
+		t.Fatal("release before NextRelease must not be reported")
+	default:
+	}
+
+	k.Press(0xE)
+	k.Release(0xE)
+	assert.Equal(t, uint8(0xE), <-ch)
+}
+
+func TestMemoryKeypad_concurrent(t *testing.T) {
+	k := NewKeypad()
+	var wg sync.WaitGroup
+	for i := 0; i < KeyCount; i++ {
+		wg.Add(1)
+		go func(key uint8) {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				k.Press(key)
+				k.IsDown(key)
+				k.Release(key)
+			}
+		}(uint8(i))
+	}
+	wg.Wait()
+
+	for i := 0; i < KeyCount; i++ {
+		assert.False(t, k.IsDown(uint8(i)))
+	}
+}
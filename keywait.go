@@ -0,0 +1,39 @@
+package chip8
+
+import "errors"
+
+// KeyWait is the state of an FX0A instruction blocked on the keypad.
+// Pressed and Key record the key seen down, whose release ends the
+// wait.
+type KeyWait struct {
+	Active   bool
+	Register uint8
+	Pressed  bool
+	Key      uint8
+}
+
+const keyWaitSize = 4
+
+var ErrInvalidKeyWait = errors.New("chip8: invalid key wait state")
+
+func (c *Chip8) WaitingForKey() bool {
+	return c.KeyWait.Active
+}
+
+func (w KeyWait) MarshalBinary() ([]byte, error) {
+	return []byte{b2i[w.Active], w.Register, b2i[w.Pressed], w.Key}, nil
+}
+
+func (w *KeyWait) UnmarshalBinary(data []byte) error {
+	if len(data) != keyWaitSize || data[0] > 1 || data[2] > 1 ||
+		data[1] > 0xF || data[3] > 0xF {
+		return ErrInvalidKeyWait
+	}
+	*w = KeyWait{
+		Active:   data[0] == 1,
+		Register: data[1],
+		Pressed:  data[2] == 1,
+		Key:      data[3],
+	}
+	return nil
+}
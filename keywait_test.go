@@ -0,0 +1,25 @@
+package chip8
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKeyWaitBinary(t *testing.T) {
+	w := KeyWait{Active: true, Register: 0x7, Pressed: true, Key: 0xC}
+
+	data, err := w.MarshalBinary()
+	assert.Nil(t, err)
+
+	var got KeyWait
+	assert.Nil(t, got.UnmarshalBinary(data))
+	assert.Equal(t, w, got)
+}
+
+func TestKeyWaitBinary_invalid(t *testing.T) {
+	var w KeyWait
+	assert.Equal(t, ErrInvalidKeyWait, w.UnmarshalBinary([]byte{1, 0x10, 0, 0}))
+	assert.Equal(t, ErrInvalidKeyWait, w.UnmarshalBinary([]byte{1, 0, 1, 0x10}))
+	assert.Equal(t, ErrInvalidKeyWait, w.UnmarshalBinary([]byte{1}))
+}
@@ -0,0 +1,259 @@
+package chip8
+
+import (
+	"context"
+	"io"
+	"sync"
+	"time"
+)
+
+const (
+	TimerFrequency     = 60
+	DefaultInstrPerSec = 700
+)
+
+// Machine drives a Chip8 in real time. The CPU runs at
+// InstructionsPerSecond while the delay and sound timers tick at
+// TimerFrequency, both derived from the same Clock.
+type Machine struct {
+	Chip8                 *Chip8
+	Clock                 Clock
+	InstructionsPerSecond int
+	// OnFrame is called after every timer tick, with the machine locked.
+	OnFrame func(c *Chip8)
+	// Rewinder, when set, records every frame so the machine can be
+	// rewound.
+	Rewinder *Rewinder
+	// Tracer, when set, sees every instruction executed.
+	Tracer Tracer
+	Cycles uint64
+	Frames uint64
+
+	mu       sync.Mutex
+	paused   bool
+	started  bool
+	last     time.Time
+	cpuAcc   int64
+	timerAcc int64
+	emuAcc   int64
+	err      error
+}
+
+func NewMachine(c *Chip8) *Machine {
+	return &Machine{
+		Chip8:                 c,
+		Clock:                 SystemClock,
+		InstructionsPerSecond: DefaultInstrPerSec,
+	}
+}
+
+// Run keeps the machine in step with its clock until ctx is done or an
+// instruction fails, in which case the machine is halted and the
+// error returned.
+func (m *Machine) Run(ctx context.Context) error {
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-m.Clock.After(time.Second / TimerFrequency):
+			if err := m.Update(); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// Update catches the emulation up with the clock, executing every
+// instruction and timer tick that fell due since the previous call.
+func (m *Machine) Update() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.err != nil {
+		return m.err
+	}
+	now := m.Clock.Now()
+	if !m.started {
+		m.started = true
+		m.last = now
+		return nil
+	}
+	elapsed := now.Sub(m.last)
+	m.last = now
+	if m.paused || elapsed <= 0 {
+		return nil
+	}
+	return m.advance(int64(elapsed))
+}
+
+// advance works in fixed point: the accumulators hold nanoseconds
+// multiplied by the event frequency, so one event is due every
+// time.Second units and no rounding drift builds up.
+func (m *Machine) advance(elapsed int64) error {
+	second := int64(time.Second)
+	for elapsed > 0 {
+		untilTick := (second - m.timerAcc + TimerFrequency - 1) / TimerFrequency
+		chunk := elapsed
+		if untilTick < chunk {
+			chunk = untilTick
+		}
+		elapsed -= chunk
+
+		m.cpuAcc += chunk * int64(m.InstructionsPerSecond)
+		n := int(m.cpuAcc / second)
+		m.cpuAcc %= second
+		if err := m.step(n); err != nil {
+			return err
+		}
+
+		m.timerAcc += chunk * TimerFrequency
+		if m.timerAcc >= second {
+			m.timerAcc -= second
+			m.frame()
+		}
+	}
+	return nil
+}
+
+func (m *Machine) frame() {
+	m.Chip8.TickTimers()
+	m.Frames++
+	if m.Rewinder != nil {
+		m.Rewinder.Record(m.Chip8, m.Cycles, m.Frames)
+	}
+	if m.OnFrame != nil {
+		m.OnFrame(m.Chip8)
+	}
+}
+
+// Emulate executes n instructions in emulated time, ignoring the clock:
+// timers tick once every InstructionsPerSecond/TimerFrequency
+// instructions however fast they run. It suits debuggers and tools that
+// need the same results on every run.
+func (m *Machine) Emulate(n int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.err != nil {
+		return m.err
+	}
+	for i := 0; i < n; i++ {
+		if err := m.step(1); err != nil {
+			return err
+		}
+		m.emuAcc += TimerFrequency
+		if m.emuAcc >= int64(m.InstructionsPerSecond) {
+			m.emuAcc -= int64(m.InstructionsPerSecond)
+			m.frame()
+		}
+	}
+	return nil
+}
+
+// Step executes n instructions right away, whether or not the machine
+// is paused. Timers are left alone.
+func (m *Machine) Step(n int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.err != nil {
+		return m.err
+	}
+	return m.step(n)
+}
+
+func (m *Machine) step(n int) error {
+	for i := 0; i < n; i++ {
+		if m.Tracer != nil {
+			m.Tracer.Before(m.Chip8, m.Cycles)
+		}
+		err := m.Chip8.Step()
+		if m.Tracer != nil {
+			m.Tracer.After(m.Chip8, err)
+		}
+		if err != nil {
+			m.err = err
+			return err
+		}
+		m.Cycles++
+	}
+	return nil
+}
+
+func (m *Machine) SaveState(w io.Writer) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.Chip8.SaveState(w)
+}
+
+// LoadState restores a save state and clears any error that halted the
+// machine.
+func (m *Machine) LoadState(r io.Reader) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.Chip8.LoadState(r); err != nil {
+		return err
+	}
+	m.err = nil
+	return nil
+}
+
+// Rewind moves the machine back the given number of recorded frames
+// and clears any error that halted it.
+func (m *Machine) Rewind(frames int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.Rewinder == nil {
+		return ErrRewindUnavailable
+	}
+	cycle, frame, err := m.Rewinder.Rewind(m.Chip8, frames)
+	return m.rewound(cycle, frame, err)
+}
+
+// RewindTo moves the machine back to the latest recorded frame at or
+// before cycle.
+func (m *Machine) RewindTo(cycle uint64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.Rewinder == nil {
+		return ErrRewindUnavailable
+	}
+	cycle, frame, err := m.Rewinder.RewindTo(m.Chip8, cycle)
+	return m.rewound(cycle, frame, err)
+}
+
+func (m *Machine) rewound(cycle, frame uint64, err error) error {
+	if err != nil {
+		return err
+	}
+	m.Cycles, m.Frames = cycle, frame
+	m.err = nil
+	return nil
+}
+
+// Err returns the error that halted the machine, if any.
+func (m *Machine) Err() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.err
+}
+
+func (m *Machine) Halted() bool {
+	return m.Err() != nil
+}
+
+func (m *Machine) Pause() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.paused = true
+}
+
+func (m *Machine) Resume() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.paused = false
+}
+
+func (m *Machine) Paused() bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.paused
+}
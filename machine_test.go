@@ -0,0 +1,157 @@
+package chip8
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// newLoopMachine returns a machine running "1200: JP 1200" so every
+// executed instruction is a no-op jump.
+func newLoopMachine() (*Machine, *FakeClock) {
+	c := NewChip8(VariantChip8)
+	c.Memory[0x200] = 0x12
+	c.Memory[0x201] = 0x00
+	clock := NewFakeClock(time.Unix(0, 0))
+	m := NewMachine(c)
+	m.Clock = clock
+	m.Update()
+	return m, clock
+}
+
+func TestMachineUpdate_cpuRate(t *testing.T) {
+	m, clock := newLoopMachine()
+	m.InstructionsPerSecond = 500
+
+	clock.Advance(time.Second)
+	m.Update()
+
+	assert.Equal(t, uint64(500), m.Cycles)
+}
+
+func TestMachineUpdate_timersAt60Hz(t *testing.T) {
+	m, clock := newLoopMachine()
+	m.InstructionsPerSecond = 1000
+	m.Chip8.DelayTimer = 0xFF
+	m.Chip8.SoundTimer = 30
+
+	for i := 0; i < 100; i++ {
+		clock.Advance(10 * time.Millisecond)
+		m.Update()
+	}
+
+	assert.Equal(t, uint64(60), m.Frames)
+	assert.Equal(t, uint8(0xFF-60), m.Chip8.DelayTimer)
+	assert.Equal(t, uint8(0), m.Chip8.SoundTimer)
+	assert.Equal(t, uint64(1000), m.Cycles)
+}
+
+func TestMachineUpdate_noDrift(t *testing.T) {
+	m, clock := newLoopMachine()
+	m.InstructionsPerSecond = 7
+
+	for i := 0; i < 3000; i++ {
+		clock.Advance(time.Millisecond)
+		m.Update()
+	}
+
+	assert.Equal(t, uint64(180), m.Frames)
+	assert.Equal(t, uint64(21), m.Cycles)
+}
+
+func TestMachinePause(t *testing.T) {
+	m, clock := newLoopMachine()
+	m.InstructionsPerSecond = 600
+	m.Chip8.DelayTimer = 100
+
+	m.Pause()
+	clock.Advance(time.Second)
+	m.Update()
+	assert.True(t, m.Paused())
+	assert.Equal(t, uint64(0), m.Cycles)
+	assert.Equal(t, uint8(100), m.Chip8.DelayTimer)
+
+	m.Step(3)
+	assert.Equal(t, uint64(3), m.Cycles)
+
+	m.Resume()
+	clock.Advance(time.Second / 2)
+	m.Update()
+	assert.Equal(t, uint64(303), m.Cycles)
+	assert.Equal(t, uint8(70), m.Chip8.DelayTimer)
+}
+
+func TestMachineRun(t *testing.T) {
+	m, clock := newLoopMachine()
+	frames := make(chan uint8, 1)
+	m.OnFrame = func(c *Chip8) {
+		select {
+		case frames <- c.DelayTimer:
+		default:
+		}
+	}
+	m.Chip8.DelayTimer = 10
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		done <- m.Run(ctx)
+	}()
+
+	for {
+		clock.Advance(time.Second / TimerFrequency)
+		select {
+		case v := <-frames:
+			assert.Equal(t, uint8(9), v)
+			cancel()
+			assert.Equal(t, context.Canceled, <-done)
+			return
+		case <-time.After(time.Millisecond):
+		}
+	}
+}
+
+func TestMachineEmulate(t *testing.T) {
+	m, _ := newLoopMachine()
+	m.InstructionsPerSecond = 600
+	m.Chip8.DelayTimer = 100
+
+	assert.Nil(t, m.Emulate(95))
+	assert.Equal(t, uint64(95), m.Cycles)
+	assert.Equal(t, uint64(9), m.Frames)
+	assert.Equal(t, uint8(91), m.Chip8.DelayTimer)
+
+	assert.Nil(t, m.Emulate(5))
+	assert.Equal(t, uint64(10), m.Frames)
+}
+
+type recordingTracer struct {
+	calls []string
+}
+
+func (r *recordingTracer) Before(c *Chip8, cycle uint64) {
+	r.calls = append(r.calls, fmt.Sprintf("before %d %04X", cycle, c.PC))
+}
+
+func (r *recordingTracer) After(c *Chip8, err error) {
+	r.calls = append(r.calls, fmt.Sprintf("after %04X %v", c.PC, err))
+}
+
+func TestMachineTracer(t *testing.T) {
+	m, _ := newLoopMachine()
+	tr := &recordingTracer{}
+	m.Tracer = tr
+	copy(m.Chip8.Memory[0x200:], []byte{0x63, 0x05, 0x00, 0xEE})
+
+	assert.NotNil(t, m.Step(3))
+
+	assert.Equal(t, []string{
+		"before 0 0200",
+		"after 0202 <nil>",
+		"before 1 0202",
+		"after 0202 chip8: stack underflow (pc=0x0202 opcode=0x00ee)",
+	}, tr.calls)
+}
@@ -0,0 +1,275 @@
+// Package movie records the keypad input of a run, keyed by frame, and
+// plays it back bit-exactly from the same start state, for bug reports
+// and regression tests.
+package movie
+
+import (
+	"bufio"
+	"compress/flate"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+
+	chip8 "github.com/hermesdt/go-plan8"
+)
+
+// A movie file is the magic, a format version and a flate compressed
+// body: the ROM hash, variant, quirk profile name, random source kind
+// and seed, instructions per second, RPL flags, the start state, the
+// instructions run in every frame, the key events and the final hashes.
+// Counts and frame numbers are uvarints.
+const (
+	movieMagic = "C8MV"
+	Version    = 1
+)
+
+var (
+	ErrInvalidMovie     = errors.New("movie: invalid movie")
+	ErrUnsupportedMovie = errors.New("movie: movie is from a newer release")
+)
+
+// Event presses or releases a key once Frame frames have run, before
+// the first instruction of the next one.
+type Event struct {
+	Frame uint64
+	Key   uint8
+	Down  bool
+}
+
+func (e Event) apply(k chip8.Keypad) {
+	if e.Down {
+		k.Press(e.Key)
+	} else {
+		k.Release(e.Key)
+	}
+}
+
+// Hashes identify the state a movie ends in.
+type Hashes struct {
+	Screen    [sha256.Size]byte
+	Registers [sha256.Size]byte
+}
+
+// HashState hashes the screen pixels and the registers, stack and
+// timers of c.
+func HashState(c *chip8.Chip8) Hashes {
+	s := &c.Screen
+	pixels := make([]byte, 0, s.Width()*s.Height())
+	for y := 0; y < s.Height(); y++ {
+		for x := 0; x < s.Width(); x++ {
+			pixels = append(pixels, s.Pixel(y, x))
+		}
+	}
+	regs := sha256.New()
+	binary.Write(regs, binary.BigEndian, struct {
+		V          [16]uint8
+		I, PC, SP  uint16
+		Stack      [16]uint16
+		DelayTimer uint8
+		SoundTimer uint8
+	}{c.V, c.I, c.PC, c.SP, c.Stack, c.DelayTimer, c.SoundTimer})
+
+	h := Hashes{Screen: sha256.Sum256(pixels)}
+	copy(h.Registers[:], regs.Sum(nil))
+	return h
+}
+
+type Movie struct {
+	RomHash chip8.RomHash
+	Variant chip8.Variant
+	// Quirks is the quirk profile name; the start state holds the
+	// quirks themselves.
+	Quirks                string
+	Random                chip8.RandomKind
+	Seed                  int64
+	InstructionsPerSecond int
+	// Flags are the RPL user flags stored for the ROM at the start.
+	Flags [chip8.FlagCount]uint8
+	// State is the save state the movie starts from.
+	State []byte
+	// Frames holds the instructions run in every frame.
+	Frames []uint64
+	Events []Event
+	// Final, when set, is the state the movie ended in when recorded.
+	Final *Hashes
+}
+
+// Cycles returns the instructions run in the whole movie.
+func (mv *Movie) Cycles() uint64 {
+	var n uint64
+	for _, f := range mv.Frames {
+		n += f
+	}
+	return n
+}
+
+func (mv *Movie) Write(w io.Writer) error {
+	if _, err := io.WriteString(w, movieMagic); err != nil {
+		return err
+	}
+	if err := binary.Write(w, binary.BigEndian, uint16(Version)); err != nil {
+		return err
+	}
+	zw, err := flate.NewWriter(w, flate.BestCompression)
+	if err != nil {
+		return err
+	}
+	b := bufio.NewWriter(zw)
+	b.Write(mv.RomHash[:])
+	b.WriteByte(uint8(mv.Variant))
+	writeBytes(b, []byte(mv.Quirks))
+	b.WriteByte(uint8(mv.Random))
+	binary.Write(b, binary.BigEndian, mv.Seed)
+	writeUvarint(b, uint64(mv.InstructionsPerSecond))
+	b.Write(mv.Flags[:])
+	writeBytes(b, mv.State)
+
+	writeUvarint(b, uint64(len(mv.Frames)))
+	for _, n := range mv.Frames {
+		writeUvarint(b, n)
+	}
+	writeUvarint(b, uint64(len(mv.Events)))
+	var last uint64
+	for _, e := range mv.Events {
+		writeUvarint(b, e.Frame-last)
+		last = e.Frame
+		key := e.Key & 0xF
+		if e.Down {
+			key |= 0x80
+		}
+		b.WriteByte(key)
+	}
+	if mv.Final == nil {
+		b.WriteByte(0)
+	} else {
+		b.WriteByte(1)
+		b.Write(mv.Final.Screen[:])
+		b.Write(mv.Final.Registers[:])
+	}
+	if err := b.Flush(); err != nil {
+		return err
+	}
+	return zw.Close()
+}
+
+func Read(r io.Reader) (*Movie, error) {
+	var header [6]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil || string(header[:4]) != movieMagic {
+		return nil, ErrInvalidMovie
+	}
+	if version := binary.BigEndian.Uint16(header[4:]); version > Version {
+		return nil, fmt.Errorf("%w: version %d", ErrUnsupportedMovie, version)
+	}
+	mv, err := readBody(bufio.NewReader(flate.NewReader(r)))
+	if err != nil {
+		return nil, ErrInvalidMovie
+	}
+	return mv, nil
+}
+
+func readBody(b *bufio.Reader) (*Movie, error) {
+	mv := &Movie{}
+	if _, err := io.ReadFull(b, mv.RomHash[:]); err != nil {
+		return nil, err
+	}
+	variant, err := b.ReadByte()
+	if err != nil {
+		return nil, err
+	}
+	mv.Variant = chip8.Variant(variant)
+	quirks, err := readBytes(b)
+	if err != nil {
+		return nil, err
+	}
+	mv.Quirks = string(quirks)
+	random, err := b.ReadByte()
+	if err != nil {
+		return nil, err
+	}
+	mv.Random = chip8.RandomKind(random)
+	if err := binary.Read(b, binary.BigEndian, &mv.Seed); err != nil {
+		return nil, err
+	}
+	ips, err := binary.ReadUvarint(b)
+	if err != nil {
+		return nil, err
+	}
+	mv.InstructionsPerSecond = int(ips)
+	if _, err := io.ReadFull(b, mv.Flags[:]); err != nil {
+		return nil, err
+	}
+	if mv.State, err = readBytes(b); err != nil {
+		return nil, err
+	}
+
+	n, err := binary.ReadUvarint(b)
+	if err != nil {
+		return nil, err
+	}
+	for i := uint64(0); i < n; i++ {
+		f, err := binary.ReadUvarint(b)
+		if err != nil {
+			return nil, err
+		}
+		mv.Frames = append(mv.Frames, f)
+	}
+	if n, err = binary.ReadUvarint(b); err != nil {
+		return nil, err
+	}
+	var frame uint64
+	for i := uint64(0); i < n; i++ {
+		delta, err := binary.ReadUvarint(b)
+		if err != nil {
+			return nil, err
+		}
+		key, err := b.ReadByte()
+		if err != nil {
+			return nil, err
+		}
+		frame += delta
+		mv.Events = append(mv.Events, Event{Frame: frame, Key: key & 0xF, Down: key&0x80 != 0})
+	}
+	final, err := b.ReadByte()
+	if err != nil {
+		return nil, err
+	}
+	if final == 1 {
+		mv.Final = &Hashes{}
+		if _, err := io.ReadFull(b, mv.Final.Screen[:]); err != nil {
+			return nil, err
+		}
+		if _, err := io.ReadFull(b, mv.Final.Registers[:]); err != nil {
+			return nil, err
+		}
+	}
+	return mv, nil
+}
+
+func writeUvarint(b *bufio.Writer, v uint64) {
+	var buf [binary.MaxVarintLen64]byte
+	b.Write(buf[:binary.PutUvarint(buf[:], v)])
+}
+
+func writeBytes(b *bufio.Writer, data []byte) {
+	writeUvarint(b, uint64(len(data)))
+	b.Write(data)
+}
+
+// maxBytes bounds the length read for a byte string, well above the
+// largest save state.
+const maxBytes = 1 << 24
+
+func readBytes(b *bufio.Reader) ([]byte, error) {
+	n, err := binary.ReadUvarint(b)
+	if err != nil {
+		return nil, err
+	}
+	if n > maxBytes {
+		return nil, ErrInvalidMovie
+	}
+	data := make([]byte, n)
+	_, err = io.ReadFull(b, data)
+	return data, err
+}
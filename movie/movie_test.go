@@ -0,0 +1,55 @@
+package movie
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	chip8 "github.com/hermesdt/go-plan8"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWriteRead(t *testing.T) {
+	mv := &Movie{
+		Variant:               chip8.VariantSChip,
+		Quirks:                "schip",
+		Random:                chip8.RandomVIP,
+		Seed:                  -3,
+		InstructionsPerSecond: 1000,
+		State:                 []byte("state"),
+		Frames:                []uint64{11, 12, 300},
+		Events:                []Event{{1, 5, true}, {1, 6, true}, {400, 5, false}},
+		Final:                 &Hashes{Screen: [32]byte{1}, Registers: [32]byte{2}},
+	}
+	mv.RomHash[0] = 0xAB
+	mv.Flags[15] = 9
+	var buf bytes.Buffer
+	assert.Nil(t, mv.Write(&buf))
+
+	got, err := Read(&buf)
+	assert.Nil(t, err)
+	assert.Equal(t, mv, got)
+	assert.Equal(t, uint64(323), got.Cycles())
+}
+
+func TestWriteRead_noFinal(t *testing.T) {
+	var buf bytes.Buffer
+	assert.Nil(t, (&Movie{}).Write(&buf))
+
+	got, err := Read(&buf)
+	assert.Nil(t, err)
+	assert.Nil(t, got.Final)
+}
+
+func TestRead_errors(t *testing.T) {
+	_, err := Read(bytes.NewReader([]byte("nope")))
+	assert.Equal(t, ErrInvalidMovie, err)
+
+	_, err = Read(bytes.NewReader([]byte{'C', '8', 'M', 'V', 0, Version + 1}))
+	assert.True(t, errors.Is(err, ErrUnsupportedMovie))
+
+	var buf bytes.Buffer
+	(&Movie{State: make([]byte, 100)}).Write(&buf)
+	_, err = Read(bytes.NewReader(buf.Bytes()[:buf.Len()-4]))
+	assert.Equal(t, ErrInvalidMovie, err)
+}
@@ -0,0 +1,69 @@
+package movie
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+
+	chip8 "github.com/hermesdt/go-plan8"
+)
+
+var (
+	ErrNoHashes = errors.New("movie: movie has no final hashes")
+	ErrMismatch = errors.New("movie: final state does not match the movie")
+)
+
+// Play runs mv from its start state and returns the machine it ends in.
+// An instruction that fails stops playback with its error.
+func Play(mv *Movie) (*chip8.Chip8, error) {
+	c := chip8.NewChip8(mv.Variant)
+	flags := chip8.NewMemoryFlagStore()
+	flags.SaveFlags(mv.RomHash, mv.Flags)
+	c.Flags = flags
+	// The start state holds the random source; this is for states
+	// without one.
+	if random, err := chip8.NewRandom(mv.Random, mv.Seed); err == nil {
+		c.Random = random
+	}
+	if err := c.LoadState(bytes.NewReader(mv.State)); err != nil {
+		return nil, err
+	}
+
+	events := mv.Events
+	apply := func(frame uint64) {
+		for len(events) > 0 && events[0].Frame <= frame {
+			events[0].apply(c.Key)
+			events = events[1:]
+		}
+	}
+	apply(0)
+	for i, n := range mv.Frames {
+		for ; n > 0; n-- {
+			if err := c.Step(); err != nil {
+				return c, err
+			}
+		}
+		c.TickTimers()
+		apply(uint64(i + 1))
+	}
+	return c, nil
+}
+
+// Verify plays mv and checks that it ends in the state recorded in it.
+func Verify(mv *Movie) error {
+	if mv.Final == nil {
+		return ErrNoHashes
+	}
+	c, err := Play(mv)
+	if err != nil {
+		return err
+	}
+	got := HashState(c)
+	if got.Screen != mv.Final.Screen {
+		return fmt.Errorf("%w: screen hash %x, want %x", ErrMismatch, got.Screen, mv.Final.Screen)
+	}
+	if got.Registers != mv.Final.Registers {
+		return fmt.Errorf("%w: register hash %x, want %x", ErrMismatch, got.Registers, mv.Final.Registers)
+	}
+	return nil
+}
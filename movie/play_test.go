@@ -0,0 +1,36 @@
+package movie
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVerify(t *testing.T) {
+	m, clock, r := newRecording(t)
+	typeKeys(m, clock, 1, 0xA, 7, 7)
+	mv, err := r.Movie()
+	assert.Nil(t, err)
+
+	c, err := Play(mv)
+	assert.Nil(t, err)
+	assert.Equal(t, m.Chip8.Screen, c.Screen)
+	assert.Equal(t, m.Chip8.V, c.V)
+	assert.Nil(t, Verify(mv))
+
+	mv.Final.Screen[0] ^= 1
+	assert.True(t, errors.Is(Verify(mv), ErrMismatch))
+
+	mv.Final = nil
+	assert.Equal(t, ErrNoHashes, Verify(mv))
+}
+
+func TestVerify_otherInput(t *testing.T) {
+	m, clock, r := newRecording(t)
+	typeKeys(m, clock, 1, 2)
+	mv, _ := r.Movie()
+
+	mv.Events[0].Key, mv.Events[1].Key = 3, 3
+	assert.True(t, errors.Is(Verify(mv), ErrMismatch))
+}
@@ -0,0 +1,168 @@
+package movie
+
+import (
+	"bytes"
+	"errors"
+	"sync"
+
+	chip8 "github.com/hermesdt/go-plan8"
+)
+
+var ErrRewoundPastStart = errors.New("movie: machine rewound past the start of the recording")
+
+// Recorder records the keys pressed on a machine into a movie. It
+// stands in for the machine's keypad and holds presses and releases
+// back to the next frame, where it applies and records them, so that
+// playback applies them before the same instruction.
+//
+// Rewinding the machine drops what was recorded after the frame it
+// goes back to. Loading a save state while recording is not supported.
+type Recorder struct {
+	Machine *chip8.Machine
+
+	keypad  chip8.Keypad
+	onFrame func(*chip8.Chip8)
+	movie   Movie
+	frames  uint64
+	cycles  uint64
+	// ends holds the cycles, since the start, at the end of every frame.
+	ends  []uint64
+	final Hashes
+	err   error
+
+	mu      sync.Mutex
+	pending []Event
+}
+
+// NewRecorder starts recording m from its current state, with a random
+// source of kind seeded with seed. Call it before the machine runs and
+// before a frontend takes its keypad.
+func NewRecorder(m *chip8.Machine, kind chip8.RandomKind, seed int64) (*Recorder, error) {
+	c := m.Chip8
+	random, err := chip8.NewRandom(kind, seed)
+	if err != nil {
+		return nil, err
+	}
+	c.Random = random
+	r := &Recorder{
+		Machine: m,
+		keypad:  c.Key,
+		onFrame: m.OnFrame,
+		frames:  m.Frames,
+		cycles:  m.Cycles,
+		final:   HashState(c),
+		movie: Movie{
+			RomHash:               c.RomHash,
+			Variant:               c.Variant,
+			Quirks:                c.Quirks.ProfileName(),
+			Random:                kind,
+			Seed:                  seed,
+			InstructionsPerSecond: m.InstructionsPerSecond,
+		},
+	}
+	if c.Flags != nil {
+		if r.movie.Flags, err = c.Flags.LoadFlags(c.RomHash); err != nil {
+			return nil, err
+		}
+	}
+	var state bytes.Buffer
+	if err := m.SaveState(&state); err != nil {
+		return nil, err
+	}
+	r.movie.State = state.Bytes()
+	c.Key = r
+	m.OnFrame = r.frame
+	return r, nil
+}
+
+func (r *Recorder) Press(key uint8) {
+	r.queue(Event{Key: key & 0xF, Down: true})
+}
+
+func (r *Recorder) Release(key uint8) {
+	r.queue(Event{Key: key & 0xF})
+}
+
+func (r *Recorder) queue(e Event) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.pending = append(r.pending, e)
+}
+
+func (r *Recorder) IsDown(key uint8) bool {
+	return r.keypad.IsDown(key)
+}
+
+func (r *Recorder) NextRelease() <-chan uint8 {
+	return r.keypad.NextRelease()
+}
+
+// frame runs after every timer tick, with the machine locked.
+func (r *Recorder) frame(c *chip8.Chip8) {
+	m := r.Machine
+	if m.Frames <= r.frames {
+		r.err = ErrRewoundPastStart
+	}
+	if r.err == nil {
+		frame := m.Frames - r.frames
+		if frame <= uint64(len(r.ends)) {
+			r.rewound(frame - 1)
+		}
+		r.ends = append(r.ends, m.Cycles-r.cycles)
+
+		r.mu.Lock()
+		pending := r.pending
+		r.pending = nil
+		r.mu.Unlock()
+		for _, e := range pending {
+			e.Frame = frame
+			e.apply(r.keypad)
+			r.movie.Events = append(r.movie.Events, e)
+		}
+		r.final = HashState(c)
+	}
+	if r.onFrame != nil {
+		r.onFrame(c)
+	}
+}
+
+// rewound drops the frames after frame, which the machine went back
+// to, and the events after them. The keypad is not rewound, so events
+// at frame bring the recorded keys to the ones down now.
+func (r *Recorder) rewound(frame uint64) {
+	r.ends = r.ends[:frame]
+	var down [chip8.KeyCount]bool
+	events := r.movie.Events[:0]
+	for _, e := range r.movie.Events {
+		if e.Frame > frame {
+			break
+		}
+		events = append(events, e)
+		down[e.Key] = e.Down
+	}
+	for key := range down {
+		if now := r.keypad.IsDown(uint8(key)); now != down[key] {
+			events = append(events, Event{Frame: frame, Key: uint8(key), Down: now})
+		}
+	}
+	r.movie.Events = events
+}
+
+// Movie returns what was recorded up to the last frame, ending in the
+// state hashed by Final. Call it once the machine has stopped.
+func (r *Recorder) Movie() (*Movie, error) {
+	if r.err != nil {
+		return nil, r.err
+	}
+	mv := r.movie
+	mv.Events = append([]Event(nil), r.movie.Events...)
+	var last uint64
+	mv.Frames = make([]uint64, len(r.ends))
+	for i, end := range r.ends {
+		mv.Frames[i] = end - last
+		last = end
+	}
+	final := r.final
+	mv.Final = &final
+	return &mv, nil
+}
@@ -0,0 +1,100 @@
+package movie
+
+import (
+	"testing"
+	"time"
+
+	chip8 "github.com/hermesdt/go-plan8"
+	"github.com/hermesdt/go-plan8/asm"
+	"github.com/stretchr/testify/assert"
+)
+
+// keyDigits draws every key typed as a digit at a random column.
+const keyDigits = `
+loop:
+	LD V0, K
+	RND V1, 0x3F
+	LD F, V0
+	DRW V1, V2, 5
+	JP loop
+`
+
+func newRecording(t *testing.T) (*chip8.Machine, *chip8.FakeClock, *Recorder) {
+	rom, err := asm.Assemble(keyDigits, chip8.VariantChip8)
+	assert.Nil(t, err)
+	c := chip8.NewChip8(chip8.VariantChip8)
+	assert.Nil(t, c.LoadRomBytes(rom))
+	clock := chip8.NewFakeClock(time.Unix(0, 0))
+	m := chip8.NewMachine(c)
+	m.Clock = clock
+	m.Update()
+	r, err := NewRecorder(m, chip8.RandomXorshift, 7)
+	assert.Nil(t, err)
+	return m, clock, r
+}
+
+// advance moves the clock in uneven steps, as a frontend would.
+func advance(m *chip8.Machine, clock *chip8.FakeClock, d time.Duration) {
+	for _, step := range []time.Duration{d / 3, d / 5, d - d/3 - d/5} {
+		clock.Advance(step)
+		m.Update()
+	}
+}
+
+func typeKeys(m *chip8.Machine, clock *chip8.FakeClock, keys ...uint8) {
+	for _, k := range keys {
+		m.Chip8.Key.Press(k)
+		advance(m, clock, 50*time.Millisecond)
+		m.Chip8.Key.Release(k)
+		advance(m, clock, 70*time.Millisecond)
+	}
+}
+
+func TestRecorder_heldToNextFrame(t *testing.T) {
+	m, clock, r := newRecording(t)
+	advance(m, clock, 20*time.Millisecond)
+	frame := m.Frames
+
+	m.Chip8.Key.Press(5)
+	assert.False(t, m.Chip8.Key.IsDown(5))
+	advance(m, clock, 20*time.Millisecond)
+	assert.True(t, m.Chip8.Key.IsDown(5))
+
+	mv, err := r.Movie()
+	assert.Nil(t, err)
+	assert.Equal(t, []Event{{Frame: frame + 1, Key: 5, Down: true}}, mv.Events)
+	assert.Len(t, mv.Frames, int(m.Frames))
+	assert.True(t, mv.Cycles() <= m.Cycles)
+	assert.Equal(t, "modern", mv.Quirks)
+	assert.Equal(t, m.Chip8.RomHash, mv.RomHash)
+	assert.Equal(t, HashState(m.Chip8), *mv.Final)
+}
+
+func TestRecorder_rewind(t *testing.T) {
+	m, clock, r := newRecording(t)
+	m.Rewinder = chip8.NewRewinder(chip8.RewindConfig{})
+	typeKeys(m, clock, 1, 2)
+	m.Chip8.Key.Press(3)
+	advance(m, clock, 50*time.Millisecond)
+
+	assert.Nil(t, m.Rewind(5))
+	typeKeys(m, clock, 4)
+
+	mv, err := r.Movie()
+	assert.Nil(t, err)
+	assert.Equal(t, m.Frames, uint64(len(mv.Frames)))
+	assert.Nil(t, Verify(mv))
+}
+
+func TestRecorder_rewoundPastStart(t *testing.T) {
+	m, clock, _ := newRecording(t)
+	advance(m, clock, 100*time.Millisecond)
+	r, err := NewRecorder(m, chip8.RandomGo, 1)
+	assert.Nil(t, err)
+	m.Frames = 1
+
+	advance(m, clock, 20*time.Millisecond)
+
+	_, err = r.Movie()
+	assert.Equal(t, ErrRewoundPastStart, err)
+}
@@ -0,0 +1,151 @@
+package octo
+
+import (
+	"math"
+)
+
+// Octo evaluates :calc expressions right to left with every binary
+// operator at the same precedence, so 2 * 3 + 1 is 8. Parentheses
+// group as usual.
+var binaryOps = map[string]func(a, b float64) float64{
+	"+":   func(a, b float64) float64 { return a + b },
+	"-":   func(a, b float64) float64 { return a - b },
+	"*":   func(a, b float64) float64 { return a * b },
+	"/":   func(a, b float64) float64 { return a / b },
+	"%":   func(a, b float64) float64 { return math.Mod(a, b) },
+	"&":   func(a, b float64) float64 { return float64(int64(a) & int64(b)) },
+	"|":   func(a, b float64) float64 { return float64(int64(a) | int64(b)) },
+	"^":   func(a, b float64) float64 { return float64(int64(a) ^ int64(b)) },
+	"<<":  func(a, b float64) float64 { return float64(int64(a) << uint64(b)) },
+	">>":  func(a, b float64) float64 { return float64(int64(a) >> uint64(b)) },
+	"pow": math.Pow,
+	"min": math.Min,
+	"max": math.Max,
+	"<":   func(a, b float64) float64 { return bool2f(a < b) },
+	">":   func(a, b float64) float64 { return bool2f(a > b) },
+	"<=":  func(a, b float64) float64 { return bool2f(a <= b) },
+	">=":  func(a, b float64) float64 { return bool2f(a >= b) },
+	"==":  func(a, b float64) float64 { return bool2f(a == b) },
+	"!=":  func(a, b float64) float64 { return bool2f(a != b) },
+}
+
+var unaryOps = map[string]func(a float64) float64{
+	"-":     func(a float64) float64 { return -a },
+	"~":     func(a float64) float64 { return float64(^int64(a)) },
+	"!":     func(a float64) float64 { return bool2f(a == 0) },
+	"abs":   math.Abs,
+	"sqrt":  math.Sqrt,
+	"sin":   math.Sin,
+	"cos":   math.Cos,
+	"tan":   math.Tan,
+	"exp":   math.Exp,
+	"log":   math.Log,
+	"sign":  sign,
+	"ceil":  math.Ceil,
+	"floor": math.Floor,
+}
+
+func bool2f(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+func sign(a float64) float64 {
+	switch {
+	case a > 0:
+		return 1
+	case a < 0:
+		return -1
+	}
+	return 0
+}
+
+// calc evaluates the tokens of a :calc body.
+func (c *compiler) calc(open token, toks []token) (float64, error) {
+	p := &calcParser{c: c, open: open, toks: toks}
+	v, err := p.expr()
+	if err != nil {
+		return 0, err
+	}
+	if len(p.toks) > 0 {
+		return 0, errorf(p.toks[0].pos, "unexpected %q in expression", p.toks[0].text)
+	}
+	return v, nil
+}
+
+type calcParser struct {
+	c    *compiler
+	open token
+	toks []token
+}
+
+func (p *calcParser) expr() (float64, error) {
+	l, err := p.term()
+	if err != nil {
+		return 0, err
+	}
+	if len(p.toks) == 0 {
+		return l, nil
+	}
+	op, ok := binaryOps[p.toks[0].text]
+	if !ok {
+		return l, nil
+	}
+	p.toks = p.toks[1:]
+	r, err := p.expr()
+	if err != nil {
+		return 0, err
+	}
+	return op(l, r), nil
+}
+
+func (p *calcParser) term() (float64, error) {
+	if len(p.toks) == 0 {
+		return 0, errorf(p.open.pos, "incomplete expression")
+	}
+	t := p.toks[0]
+	p.toks = p.toks[1:]
+	if op, ok := unaryOps[t.text]; ok {
+		v, err := p.term()
+		if err != nil {
+			return 0, err
+		}
+		return op(v), nil
+	}
+	switch t.text {
+	case "(":
+		v, err := p.expr()
+		if err != nil {
+			return 0, err
+		}
+		if len(p.toks) == 0 || p.toks[0].text != ")" {
+			return 0, errorf(t.pos, "unbalanced parenthesis")
+		}
+		p.toks = p.toks[1:]
+		return v, nil
+	case "@":
+		v, err := p.term()
+		if err != nil {
+			return 0, err
+		}
+		return float64(p.c.peek(int(v))), nil
+	case "HERE":
+		return float64(p.c.here), nil
+	case "PI":
+		return math.Pi, nil
+	case "E":
+		return math.E, nil
+	}
+	if v, ok := parseNumber(t.text); ok {
+		return float64(v), nil
+	}
+	if v, ok := p.c.consts[t.text]; ok {
+		return v, nil
+	}
+	if v, ok := p.c.labels[t.text]; ok {
+		return float64(v), nil
+	}
+	return 0, errorf(t.pos, "undefined name %s", t.text)
+}
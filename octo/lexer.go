@@ -0,0 +1,59 @@
+package octo
+
+import (
+	"strconv"
+	"strings"
+)
+
+type token struct {
+	text string
+	pos  Pos
+}
+
+// lex splits src into whitespace separated tokens, dropping '#'
+// comments.
+func lex(file string, src string) []token {
+	var toks []token
+	for i, line := range strings.Split(src, "\n") {
+		for col := 0; col < len(line); {
+			c := line[col]
+			if c == ' ' || c == '\t' || c == '\r' {
+				col++
+				continue
+			}
+			if c == '#' {
+				break
+			}
+			start := col
+			for col < len(line) && !strings.ContainsRune(" \t\r", rune(line[col])) {
+				col++
+			}
+			toks = append(toks, token{line[start:col], Pos{file, i + 1, start + 1}})
+		}
+	}
+	return toks
+}
+
+// parseNumber reads a decimal, 0x hex or 0b binary literal with an
+// optional minus sign. Unlike Go a leading zero does not mean octal.
+func parseNumber(s string) (int64, bool) {
+	neg := strings.HasPrefix(s, "-")
+	if neg {
+		s = s[1:]
+	}
+	base := 10
+	switch {
+	case strings.HasPrefix(s, "0x") || strings.HasPrefix(s, "0X"):
+		base, s = 16, s[2:]
+	case strings.HasPrefix(s, "0b") || strings.HasPrefix(s, "0B"):
+		base, s = 2, s[2:]
+	}
+	v, err := strconv.ParseInt(s, base, 64)
+	if err != nil {
+		return 0, false
+	}
+	if neg {
+		v = -v
+	}
+	return v, true
+}
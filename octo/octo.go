@@ -0,0 +1,973 @@
+// Package octo compiles programs written in the Octo language into
+// CHIP-8, SUPER-CHIP or XO-CHIP ROM images.
+//
+// It supports labels, :next, :alias, :const, :calc, :macro, :org,
+// :byte and :call, every instruction statement, if ... then,
+// if ... begin ... else ... end, and loop ... while ... again.
+// :breakpoint and :monitor are accepted and ignored.
+package octo
+
+import (
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	chip8 "github.com/hermesdt/go-plan8"
+)
+
+// Pos is a 1-based source position.
+type Pos struct {
+	File string
+	Line int
+	Col  int
+}
+
+func (p Pos) String() string {
+	return fmt.Sprintf("%s:%d:%d", p.File, p.Line, p.Col)
+}
+
+type Error struct {
+	Pos Pos
+	Msg string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%v: %s", e.Pos, e.Msg)
+}
+
+func errorf(pos Pos, format string, args ...interface{}) error {
+	return &Error{Pos: pos, Msg: fmt.Sprintf(format, args...)}
+}
+
+// Compiler holds the settings a program is compiled with.
+type Compiler struct {
+	// Variant rejects instructions the variant cannot execute and
+	// bounds the program size.
+	Variant chip8.Variant
+}
+
+// Program is the output of a successful compilation.
+type Program struct {
+	// Bytes is the ROM image, loaded at chip8.RomStart.
+	Bytes  []byte
+	Labels map[string]int
+}
+
+// Compile compiles src for variant.
+func Compile(src string, variant chip8.Variant) ([]byte, error) {
+	c := &Compiler{Variant: variant}
+	p, err := c.Compile("<input>", []byte(src))
+	if err != nil {
+		return nil, err
+	}
+	return p.Bytes, nil
+}
+
+func (c *Compiler) CompileFile(name string) (*Program, error) {
+	src, err := ioutil.ReadFile(name)
+	if err != nil {
+		return nil, err
+	}
+	return c.Compile(name, src)
+}
+
+// Compile compiles src, naming it file in errors.
+func (c *Compiler) Compile(file string, src []byte) (*Program, error) {
+	s := &compiler{
+		Compiler: c,
+		toks:     lex(file, string(src)),
+		rom:      make([]byte, c.Variant.MemorySize()-chip8.RomStart),
+		written:  make([]bool, c.Variant.MemorySize()-chip8.RomStart),
+		here:     chip8.RomStart,
+		labels:   map[string]int{},
+		consts:   map[string]float64{},
+		aliases:  map[string]uint16{},
+		macros:   map[string]*macro{},
+		end:      token{pos: Pos{File: file, Line: 1, Col: 1}},
+	}
+	return s.compile()
+}
+
+// ops indexes the decoding table by instruction name.
+var ops = map[string]*chip8.Instruction{}
+
+func init() {
+	table := chip8.Instructions()
+	for i := range table {
+		ops[table[i].Name] = &table[i]
+	}
+}
+
+type compiler struct {
+	*Compiler
+	toks    []token
+	rom     []byte
+	written []bool
+	size    int
+	here    int
+	labels  map[string]int
+	consts  map[string]float64
+	aliases map[string]uint16
+	macros  map[string]*macro
+	fixups  []fixup
+	// blocks holds the jumps of open if ... begin blocks waiting for
+	// their else or end, loops the open loops.
+	blocks []int
+	loops  []loop
+	// end stands in for the token after the last one in errors.
+	end token
+	// main is set while 0x200 holds a jump to main.
+	main bool
+}
+
+type macro struct {
+	params []string
+	body   []token
+}
+
+type loop struct {
+	start  int
+	breaks []int
+}
+
+// A fixup is an address operand naming a label defined later.
+type fixup struct {
+	at   int
+	long bool
+	name token
+}
+
+func (c *compiler) compile() (*Program, error) {
+	c.main = c.reserveMain()
+	for len(c.toks) > 0 {
+		if err := c.statement(); err != nil {
+			return nil, err
+		}
+	}
+	if len(c.blocks) > 0 {
+		return nil, errorf(c.end.pos, "if ... begin without end")
+	}
+	if len(c.loops) > 0 {
+		return nil, errorf(c.end.pos, "loop without again")
+	}
+	if c.main {
+		c.fixups = append(c.fixups, fixup{at: chip8.RomStart, name: token{"main", c.end.pos}})
+	}
+	for _, f := range c.fixups {
+		addr, ok := c.labels[f.name.text]
+		if !ok {
+			return nil, errorf(f.name.pos, "undefined name %s", f.name.text)
+		}
+		if err := c.patch(f, addr); err != nil {
+			return nil, err
+		}
+	}
+	return &Program{Bytes: c.rom[:c.size], Labels: c.labels}, nil
+}
+
+// reserveMain leaves room for a jump to main when the program has a
+// main label. As in Octo, the jump is dropped again if nothing comes
+// before main.
+func (c *compiler) reserveMain() bool {
+	for i := 0; i+1 < len(c.toks); i++ {
+		if c.toks[i].text == ":" && c.toks[i+1].text == "main" {
+			c.emit(c.toks[0].pos, 0x10, 0x00)
+			return true
+		}
+	}
+	return false
+}
+
+func (c *compiler) next() (token, error) {
+	if len(c.toks) == 0 {
+		return c.end, errorf(c.end.pos, "unexpected end of file")
+	}
+	t := c.toks[0]
+	c.toks = c.toks[1:]
+	c.end.pos = t.pos
+	return t, nil
+}
+
+func (c *compiler) peekIs(text string) bool {
+	return len(c.toks) > 0 && c.toks[0].text == text
+}
+
+func (c *compiler) expect(text string) error {
+	t, err := c.next()
+	if err != nil {
+		return err
+	}
+	if t.text != text {
+		return errorf(t.pos, "expected %s, got %q", text, t.text)
+	}
+	return nil
+}
+
+func (c *compiler) emit(pos Pos, bs ...byte) error {
+	for _, b := range bs {
+		off := c.here - chip8.RomStart
+		if off < 0 || off >= len(c.rom) {
+			return errorf(pos, "program does not fit in memory")
+		}
+		if c.written[off] {
+			return errorf(pos, "overlaps the data at %#x", c.here)
+		}
+		c.rom[off], c.written[off] = b, true
+		c.here++
+		if off >= c.size {
+			c.size = off + 1
+		}
+	}
+	return nil
+}
+
+// peek returns the byte compiled at addr so far, for the @ operator.
+func (c *compiler) peek(addr int) byte {
+	off := addr - chip8.RomStart
+	if off < 0 || off >= len(c.rom) {
+		return 0
+	}
+	return c.rom[off]
+}
+
+// op emits the instruction named in the decoding table, ORing operand
+// into its pattern, after checking that the target variant has it.
+func (c *compiler) op(t token, name string, operand uint16) error {
+	in := ops[name]
+	if c.Variant < in.Variant {
+		return errorf(t.pos, "%s needs the %v variant", t.text, in.Variant)
+	}
+	w := in.Pattern | operand
+	return c.emit(t.pos, byte(w>>8), byte(w))
+}
+
+func (c *compiler) patch(f fixup, addr int) error {
+	off := f.at - chip8.RomStart
+	if f.long {
+		c.rom[off], c.rom[off+1] = byte(addr>>8), byte(addr)
+		return nil
+	}
+	if addr > 0xFFF {
+		return errorf(f.name.pos, "address %#x of %s does not fit in 12 bits", addr, f.name.text)
+	}
+	c.rom[off] = c.rom[off]&0xF0 | byte(addr>>8)
+	c.rom[off+1] = byte(addr)
+	return nil
+}
+
+// jump emits a jump to be patched later and returns its address.
+func (c *compiler) jump(t token) (int, error) {
+	at := c.here
+	return at, c.op(t, "Jump", 0)
+}
+
+// land patches the jump at at, emitted for t, to the current address.
+func (c *compiler) land(t token, at int) error {
+	if c.here > 0xFFF {
+		return errorf(t.pos, "%s at %#x is out of jump range", t.text, c.here)
+	}
+	return c.patch(fixup{at: at, name: t}, c.here)
+}
+
+func (c *compiler) statement() error {
+	t, err := c.next()
+	if err != nil {
+		return err
+	}
+	if m, ok := c.macros[t.text]; ok {
+		return c.expand(t, m)
+	}
+	if _, ok := c.register(t); ok {
+		return c.assign(t)
+	}
+	if v, ok := parseNumber(t.text); ok {
+		if v < -0x80 || v > 0xFF {
+			return errorf(t.pos, "byte %d out of range", v)
+		}
+		return c.emit(t.pos, byte(v))
+	}
+
+	switch t.text {
+	case ":":
+		name, err := c.name()
+		if err != nil {
+			return err
+		}
+		if name.text == "main" && c.main && c.size == 2 && c.here == chip8.RomStart+2 {
+			c.main, c.size, c.here = false, 0, chip8.RomStart
+			c.written[0], c.written[1] = false, false
+			for l, addr := range c.labels {
+				if addr == chip8.RomStart+2 {
+					c.labels[l] = chip8.RomStart
+				}
+			}
+		}
+		return c.label(name, c.here)
+	case ":next":
+		name, err := c.name()
+		if err != nil {
+			return err
+		}
+		return c.label(name, c.here+1)
+	case ":alias":
+		name, err := c.name()
+		if err != nil {
+			return err
+		}
+		r, err := c.reg()
+		if err != nil {
+			return err
+		}
+		c.aliases[name.text] = r
+		return nil
+	case ":const":
+		name, err := c.name()
+		if err != nil {
+			return err
+		}
+		if _, ok := c.consts[name.text]; ok {
+			return errorf(name.pos, "constant %s redefined", name.text)
+		}
+		v, err := c.value()
+		if err != nil {
+			return err
+		}
+		c.consts[name.text] = float64(v)
+		return nil
+	case ":calc":
+		name, err := c.name()
+		if err != nil {
+			return err
+		}
+		v, err := c.braced()
+		if err != nil {
+			return err
+		}
+		c.consts[name.text] = v
+		return nil
+	case ":macro":
+		return c.defineMacro()
+	case ":org":
+		v, err := c.value()
+		if err != nil {
+			return err
+		}
+		if v < chip8.RomStart || v > int64(c.Variant.MemorySize()) {
+			return errorf(t.pos, ":org %#x is outside program memory", v)
+		}
+		c.here = int(v)
+		return nil
+	case ":byte":
+		var v int64
+		if c.peekIs("{") {
+			f, err := c.braced()
+			if err != nil {
+				return err
+			}
+			v = int64(f)
+		} else if v, err = c.value(); err != nil {
+			return err
+		}
+		return c.emit(t.pos, byte(v))
+	case ":call":
+		return c.addrOp(t, "CallSub")
+	case ":breakpoint":
+		_, err := c.next()
+		return err
+	case ":monitor":
+		if _, err := c.next(); err != nil {
+			return err
+		}
+		_, err := c.next()
+		return err
+	case ";", "return":
+		return c.op(t, "Return", 0)
+	case "clear":
+		return c.op(t, "DispClr", 0)
+	case "hires":
+		return c.op(t, "HighRes", 0)
+	case "lores":
+		return c.op(t, "LowRes", 0)
+	case "exit":
+		return c.op(t, "Exit", 0)
+	case "scroll-left":
+		return c.op(t, "ScrollLeft", 0)
+	case "scroll-right":
+		return c.op(t, "ScrollRight", 0)
+	case "audio":
+		return c.op(t, "LoadAudio", 0)
+	case "scroll-down", "scroll-up", "plane":
+		v, err := c.nibble(t)
+		if err != nil {
+			return err
+		}
+		switch t.text {
+		case "scroll-down":
+			return c.op(t, "ScrollDown", v)
+		case "scroll-up":
+			return c.op(t, "ScrollUp", v)
+		}
+		return c.op(t, "SelectPlanes", v<<8)
+	case "bcd", "saveflags", "loadflags":
+		x, err := c.reg()
+		if err != nil {
+			return err
+		}
+		name := map[string]string{"bcd": "SetBCD", "saveflags": "SaveFlags", "loadflags": "LoadFlags"}[t.text]
+		return c.op(t, name, x<<8)
+	case "save", "load":
+		return c.saveLoad(t)
+	case "sprite":
+		x, err := c.reg()
+		if err != nil {
+			return err
+		}
+		y, err := c.reg()
+		if err != nil {
+			return err
+		}
+		n, err := c.nibble(t)
+		if err != nil {
+			return err
+		}
+		return c.op(t, "Draw", x<<8|y<<4|n)
+	case "jump":
+		return c.addrOp(t, "Jump")
+	case "jump0":
+		return c.addrOp(t, "JumpPlusV0")
+	case "native":
+		return c.addrOp(t, "Call")
+	case "i":
+		return c.assignI(t)
+	case "delay", "buzzer", "pitch":
+		if err := c.expect(":="); err != nil {
+			return err
+		}
+		x, err := c.reg()
+		if err != nil {
+			return err
+		}
+		name := map[string]string{"delay": "SetDelay", "buzzer": "SetSound", "pitch": "SetPitch"}[t.text]
+		return c.op(t, name, x<<8)
+	case "if":
+		return c.ifStatement(t)
+	case "else":
+		if len(c.blocks) == 0 {
+			return errorf(t.pos, "else without if ... begin")
+		}
+		at, err := c.jump(t)
+		if err != nil {
+			return err
+		}
+		if err := c.land(t, c.blocks[len(c.blocks)-1]); err != nil {
+			return err
+		}
+		c.blocks[len(c.blocks)-1] = at
+		return nil
+	case "end":
+		if len(c.blocks) == 0 {
+			return errorf(t.pos, "end without if ... begin")
+		}
+		if err := c.land(t, c.blocks[len(c.blocks)-1]); err != nil {
+			return err
+		}
+		c.blocks = c.blocks[:len(c.blocks)-1]
+		return nil
+	case "loop":
+		c.loops = append(c.loops, loop{start: c.here})
+		return nil
+	case "while":
+		if len(c.loops) == 0 {
+			return errorf(t.pos, "while outside a loop")
+		}
+		if err := c.conditional(true); err != nil {
+			return err
+		}
+		at, err := c.jump(t)
+		if err != nil {
+			return err
+		}
+		l := &c.loops[len(c.loops)-1]
+		l.breaks = append(l.breaks, at)
+		return nil
+	case "again":
+		if len(c.loops) == 0 {
+			return errorf(t.pos, "again without loop")
+		}
+		l := c.loops[len(c.loops)-1]
+		c.loops = c.loops[:len(c.loops)-1]
+		if l.start > 0xFFF {
+			return errorf(t.pos, "loop at %#x is out of jump range", l.start)
+		}
+		if err := c.op(t, "Jump", uint16(l.start)); err != nil {
+			return err
+		}
+		for _, at := range l.breaks {
+			if err := c.land(t, at); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	if strings.HasPrefix(t.text, ":") || keywords[t.text] {
+		return errorf(t.pos, "unexpected %q", t.text)
+	}
+	// Any other name calls the subroutine at that label.
+	c.toks = append([]token{t}, c.toks...)
+	return c.addrOp(t, "CallSub")
+}
+
+// keywords are the words that only make sense inside a statement.
+var keywords = map[string]bool{
+	"then": true, "begin": true, "key": true, "-key": true, "random": true,
+	"long": true, "hex": true, "bighex": true, "{": true, "}": true,
+	":=": true, "+=": true, "-=": true, "=-": true, "|=": true, "&=": true,
+	"^=": true, ">>=": true, "<<=": true, "==": true, "!=": true,
+	"<": true, ">": true, "<=": true, ">=": true,
+}
+
+func (c *compiler) name() (token, error) {
+	t, err := c.next()
+	if err != nil {
+		return t, err
+	}
+	if _, ok := parseNumber(t.text); ok || keywords[t.text] || strings.HasPrefix(t.text, ":") {
+		return t, errorf(t.pos, "%q is not a valid name", t.text)
+	}
+	if _, ok := c.register(t); ok {
+		return t, errorf(t.pos, "%q is not a valid name", t.text)
+	}
+	return t, nil
+}
+
+func (c *compiler) label(name token, addr int) error {
+	if _, ok := c.labels[name.text]; ok {
+		return errorf(name.pos, "label %s redefined", name.text)
+	}
+	c.labels[name.text] = addr
+	return nil
+}
+
+// register resolves v0-vf, in either case, and aliases.
+func (c *compiler) register(t token) (uint16, bool) {
+	if r, ok := c.aliases[t.text]; ok {
+		return r, true
+	}
+	s := strings.ToLower(t.text)
+	if len(s) == 2 && s[0] == 'v' && strings.IndexByte("0123456789abcdef", s[1]) >= 0 {
+		return uint16(strings.IndexByte("0123456789abcdef", s[1])), true
+	}
+	return 0, false
+}
+
+func (c *compiler) reg() (uint16, error) {
+	t, err := c.next()
+	if err != nil {
+		return 0, err
+	}
+	r, ok := c.register(t)
+	if !ok {
+		return 0, errorf(t.pos, "expected a register, got %q", t.text)
+	}
+	return r, nil
+}
+
+// value reads a number or constant.
+func (c *compiler) value() (int64, error) {
+	t, err := c.next()
+	if err != nil {
+		return 0, err
+	}
+	if v, ok := parseNumber(t.text); ok {
+		return v, nil
+	}
+	if v, ok := c.consts[t.text]; ok {
+		return int64(v), nil
+	}
+	return 0, errorf(t.pos, "expected a number or constant, got %q", t.text)
+}
+
+func (c *compiler) byteValue(after token) (uint16, error) {
+	pos := after.pos
+	if len(c.toks) > 0 {
+		pos = c.toks[0].pos
+	}
+	v, err := c.value()
+	if err != nil {
+		return 0, err
+	}
+	if v < -0x80 || v > 0xFF {
+		return 0, errorf(pos, "byte %d out of range", v)
+	}
+	return uint16(v) & 0xFF, nil
+}
+
+func (c *compiler) nibble(after token) (uint16, error) {
+	pos := after.pos
+	if len(c.toks) > 0 {
+		pos = c.toks[0].pos
+	}
+	v, err := c.value()
+	if err != nil {
+		return 0, err
+	}
+	if v < 0 || v > 0xF {
+		return 0, errorf(pos, "nibble %d out of range", v)
+	}
+	return uint16(v), nil
+}
+
+// address reads a number, constant or label, which may be defined
+// later, and returns the value to OR into the instruction at at.
+func (c *compiler) address(at int, long bool) (uint16, error) {
+	t, err := c.next()
+	if err != nil {
+		return 0, err
+	}
+	max := int64(0xFFF)
+	if long {
+		max = 0xFFFF
+	}
+	v, ok := parseNumber(t.text)
+	if f, isConst := c.consts[t.text]; isConst {
+		v, ok = int64(f), true
+	}
+	if addr, isLabel := c.labels[t.text]; isLabel {
+		v, ok = int64(addr), true
+	}
+	if !ok {
+		if _, isReg := c.register(t); isReg || keywords[t.text] {
+			return 0, errorf(t.pos, "expected an address, got %q", t.text)
+		}
+		c.fixups = append(c.fixups, fixup{at: at, long: long, name: t})
+		return 0, nil
+	}
+	if v < 0 || v > max {
+		return 0, errorf(t.pos, "address %#x out of range", v)
+	}
+	return uint16(v), nil
+}
+
+func (c *compiler) addrOp(t token, name string) error {
+	at := c.here
+	addr, err := c.address(at, false)
+	if err != nil {
+		return err
+	}
+	return c.op(t, name, addr)
+}
+
+// braced evaluates a { ... } :calc expression.
+func (c *compiler) braced() (float64, error) {
+	open, err := c.next()
+	if err != nil {
+		return 0, err
+	}
+	if open.text != "{" {
+		return 0, errorf(open.pos, "expected {, got %q", open.text)
+	}
+	var body []token
+	for depth := 1; ; {
+		t, err := c.next()
+		if err != nil {
+			return 0, errorf(open.pos, "unterminated {")
+		}
+		if t.text == "{" {
+			depth++
+		} else if t.text == "}" {
+			if depth--; depth == 0 {
+				break
+			}
+		}
+		body = append(body, t)
+	}
+	return c.calc(open, body)
+}
+
+func (c *compiler) defineMacro() error {
+	name, err := c.name()
+	if err != nil {
+		return err
+	}
+	m := &macro{}
+	for {
+		t, err := c.next()
+		if err != nil {
+			return err
+		}
+		if t.text == "{" {
+			break
+		}
+		m.params = append(m.params, t.text)
+	}
+	for depth := 1; ; {
+		t, err := c.next()
+		if err != nil {
+			return errorf(name.pos, "macro %s has no closing }", name.text)
+		}
+		if t.text == "{" {
+			depth++
+		} else if t.text == "}" {
+			if depth--; depth == 0 {
+				break
+			}
+		}
+		m.body = append(m.body, t)
+	}
+	c.macros[name.text] = m
+	return nil
+}
+
+// expand replaces a macro invocation with its body, its parameters
+// replaced by the tokens that follow the invocation.
+func (c *compiler) expand(t token, m *macro) error {
+	if len(c.toks) < len(m.params) {
+		return errorf(t.pos, "macro %s takes %d arguments", t.text, len(m.params))
+	}
+	args := map[string]string{}
+	for i, p := range m.params {
+		args[p] = c.toks[i].text
+	}
+	body := make([]token, len(m.body))
+	for i, b := range m.body {
+		if a, ok := args[b.text]; ok {
+			b.text = a
+		}
+		body[i] = b
+	}
+	c.toks = append(body, c.toks[len(m.params):]...)
+	return nil
+}
+
+// assign compiles the register statements, vx := ... and friends.
+func (c *compiler) assign(t token) error {
+	x, _ := c.register(t)
+	op, err := c.next()
+	if err != nil {
+		return err
+	}
+	aluOps := map[string]string{
+		":=": "SetVY", "|=": "OrVY", "&=": "AndVY", "^=": "XorVY", "+=": "AddVY",
+		"-=": "SubVY", ">>=": "ShiftRight", "=-": "VYSub", "<<=": "ShiftLeft",
+	}
+	name, ok := aluOps[op.text]
+	if !ok {
+		return errorf(op.pos, "unknown operator %q", op.text)
+	}
+	if len(c.toks) > 0 {
+		if y, ok := c.register(c.toks[0]); ok {
+			c.toks = c.toks[1:]
+			return c.op(op, name, x<<8|y<<4)
+		}
+	}
+
+	switch op.text {
+	case ":=":
+		switch {
+		case c.peekIs("random"):
+			c.next()
+			n, err := c.byteValue(op)
+			if err != nil {
+				return err
+			}
+			return c.op(op, "SetRandomMask", x<<8|n)
+		case c.peekIs("key"):
+			c.next()
+			return c.op(op, "ReadKey", x<<8)
+		case c.peekIs("delay"):
+			c.next()
+			return c.op(op, "SetFromDelay", x<<8)
+		}
+		n, err := c.byteValue(op)
+		if err != nil {
+			return err
+		}
+		return c.op(op, "Set", x<<8|n)
+	case "+=", "-=":
+		n, err := c.byteValue(op)
+		if err != nil {
+			return err
+		}
+		if op.text == "-=" {
+			n = -n & 0xFF
+		}
+		return c.op(op, "Add", x<<8|n)
+	}
+	return errorf(op.pos, "%s needs a register operand", op.text)
+}
+
+// assignI compiles i := ... and i += vx.
+func (c *compiler) assignI(t token) error {
+	op, err := c.next()
+	if err != nil {
+		return err
+	}
+	if op.text == "+=" {
+		x, err := c.reg()
+		if err != nil {
+			return err
+		}
+		return c.op(op, "AddI", x<<8)
+	}
+	if op.text != ":=" {
+		return errorf(op.pos, "unknown operator %q", op.text)
+	}
+	switch {
+	case c.peekIs("hex"), c.peekIs("bighex"):
+		kind, _ := c.next()
+		x, err := c.reg()
+		if err != nil {
+			return err
+		}
+		if kind.text == "hex" {
+			return c.op(kind, "SetISprite", x<<8)
+		}
+		return c.op(kind, "SetIBigSprite", x<<8)
+	case c.peekIs("long"):
+		long, _ := c.next()
+		if err := c.op(long, "SetILong", 0); err != nil {
+			return err
+		}
+		addr, err := c.address(c.here, true)
+		if err != nil {
+			return err
+		}
+		return c.emit(long.pos, byte(addr>>8), byte(addr))
+	}
+	return c.addrOp(op, "SetI")
+}
+
+func (c *compiler) saveLoad(t token) error {
+	x, err := c.reg()
+	if err != nil {
+		return err
+	}
+	if !c.peekIs("-") {
+		if t.text == "save" {
+			return c.op(t, "RegDump", x<<8)
+		}
+		return c.op(t, "RegLoad", x<<8)
+	}
+	c.next()
+	y, err := c.reg()
+	if err != nil {
+		return err
+	}
+	if t.text == "save" {
+		return c.op(t, "SaveRange", x<<8|y<<4)
+	}
+	return c.op(t, "LoadRange", x<<8|y<<4)
+}
+
+func (c *compiler) ifStatement(t token) error {
+	// Find out whether this is if ... then or if ... begin before
+	// compiling the condition, which differs between the two.
+	block := false
+	for _, u := range c.toks {
+		if u.text == "then" || u.text == "begin" {
+			block = u.text == "begin"
+			break
+		}
+	}
+	if err := c.conditional(block); err != nil {
+		return err
+	}
+	kw, err := c.next()
+	if err != nil {
+		return err
+	}
+	if kw.text != "then" && kw.text != "begin" {
+		return errorf(kw.pos, "expected then or begin, got %q", kw.text)
+	}
+	if !block {
+		return nil
+	}
+	at, err := c.jump(kw)
+	if err != nil {
+		return err
+	}
+	c.blocks = append(c.blocks, at)
+	return nil
+}
+
+var negations = map[string]string{
+	"==": "!=", "!=": "==", "key": "-key", "-key": "key",
+	"<": ">=", ">": "<=", ">=": "<", "<=": ">",
+}
+
+// conditional compiles a condition into code that skips the next
+// instruction when it is false or, negated, when it is true.
+// Comparisons other than equality subtract into vf and test the borrow
+// flag.
+func (c *compiler) conditional(negated bool) error {
+	x, err := c.reg()
+	if err != nil {
+		return err
+	}
+	op, err := c.next()
+	if err != nil {
+		return err
+	}
+	cond := op.text
+	if _, ok := negations[cond]; !ok {
+		return errorf(op.pos, "unknown condition %q", cond)
+	}
+	if negated {
+		cond = negations[cond]
+	}
+	switch cond {
+	case "key":
+		return c.op(op, "SkipNotKeyPressed", x<<8)
+	case "-key":
+		return c.op(op, "SkipKeyPressed", x<<8)
+	}
+
+	// Load the right hand side: a register or a byte.
+	var y uint16
+	isReg := false
+	if len(c.toks) > 0 {
+		y, isReg = c.register(c.toks[0])
+	}
+	var n uint16
+	if isReg {
+		c.next()
+	} else if n, err = c.byteValue(op); err != nil {
+		return err
+	}
+
+	switch cond {
+	case "==":
+		if isReg {
+			return c.op(op, "SkipNeqVY", x<<8|y<<4)
+		}
+		return c.op(op, "SkipNeq", x<<8|n)
+	case "!=":
+		if isReg {
+			return c.op(op, "SkipEqVY", x<<8|y<<4)
+		}
+		return c.op(op, "SkipEq", x<<8|n)
+	}
+	if isReg {
+		err = c.op(op, "SetVY", 0xF<<8|y<<4)
+	} else {
+		err = c.op(op, "Set", 0xF<<8|n)
+	}
+	if err != nil {
+		return err
+	}
+	// vf -= vx leaves vf 1 when rhs >= vx, vf =- vx when vx >= rhs.
+	sub, skip := "SubVY", "SkipEq"
+	switch cond {
+	case "<":
+		sub = "VYSub"
+	case ">=":
+		sub, skip = "VYSub", "SkipNeq"
+	case "<=":
+		skip = "SkipNeq"
+	}
+	if err := c.op(op, sub, 0xF<<8|x<<4); err != nil {
+		return err
+	}
+	return c.op(op, skip, 0xF<<8|1)
+}
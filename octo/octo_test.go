@@ -0,0 +1,259 @@
+package octo
+
+import (
+	"testing"
+
+	chip8 "github.com/hermesdt/go-plan8"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCompile(t *testing.T) {
+	bs, err := Compile(`
+		: main
+			clear
+			v0 := 5       # x
+			v1 += -1
+			v2 -= 1
+			va := vb
+			va >>= vb
+			v3 := random 0xF
+			v4 := key
+			i := digits
+			sprite v0 v1 5
+			bcd v2
+			save v3
+			load v3
+			delay := v0
+			buzzer := v0
+			jump main
+		: digits
+			0xF0 0x90 0b11110000
+	`, chip8.VariantChip8)
+
+	assert.Nil(t, err)
+	assert.Equal(t, []byte{
+		0x00, 0xE0,
+		0x60, 0x05,
+		0x71, 0xFF,
+		0x72, 0xFF,
+		0x8A, 0xB0,
+		0x8A, 0xB6,
+		0xC3, 0x0F,
+		0xF4, 0x0A,
+		0xA2, 0x20,
+		0xD0, 0x15,
+		0xF2, 0x33,
+		0xF3, 0x55,
+		0xF3, 0x65,
+		0xF0, 0x15,
+		0xF0, 0x18,
+		0x12, 0x00,
+		0xF0, 0x90, 0xF0,
+	}, bs)
+}
+
+func TestCompile_mainJump(t *testing.T) {
+	bs, err := Compile(`
+		: sprite 0xFF
+		: main
+			sub
+			loop again
+		: sub ;
+	`, chip8.VariantChip8)
+
+	assert.Nil(t, err)
+	assert.Equal(t, []byte{0x12, 0x03, 0xFF, 0x22, 0x07, 0x12, 0x05, 0x00, 0xEE}, bs)
+}
+
+func TestCompile_directives(t *testing.T) {
+	bs, err := Compile(`
+		:const SIZE 3
+		:calc TWICE { SIZE * 2 + 1 }
+		:alias x v7
+		:macro bump reg n { reg += n }
+		: main
+			bump x SIZE
+			x := TWICE
+		:next patch
+			v0 := 0
+			:call patch
+			:byte { @ 0x200 }
+			:org 0x20E
+			:byte 1
+	`, chip8.VariantChip8)
+
+	assert.Nil(t, err)
+	assert.Equal(t, []byte{
+		0x77, 0x03,
+		0x67, 0x09,
+		0x60, 0x00,
+		0x22, 0x05,
+		0x77,
+		0x00, 0x00, 0x00, 0x00, 0x00,
+		0x01,
+	}, bs)
+}
+
+func TestCompile_xochip(t *testing.T) {
+	src := `
+		hires
+		plane 3
+		scroll-up 2
+		i := long target
+		i := bighex v1
+		save v2 - v5
+		pitch := v0
+		: target
+	`
+	_, err := Compile(src, chip8.VariantChip8)
+	assert.Equal(t, "<input>:2:3: hires needs the schip variant", err.Error())
+
+	bs, err := Compile(src, chip8.VariantXOChip)
+	assert.Nil(t, err)
+	assert.Equal(t, []byte{
+		0x00, 0xFF,
+		0xF3, 0x01,
+		0x00, 0xD2,
+		0xF0, 0x00, 0x02, 0x10,
+		0xF1, 0x30,
+		0x52, 0x52,
+		0xF0, 0x3A,
+	}, bs)
+}
+
+func TestCompile_pastJumpRange(t *testing.T) {
+	cases := []struct {
+		src string
+		err string
+	}{
+		{":org 0x1000 loop v0 += 1 again", "<input>:1:26: loop at 0x1000 is out of jump range"},
+		{":org 0xFFC if v0 == 1 begin v0 += 1 end", "<input>:1:37: end at 0x1002 is out of jump range"},
+		{":org 0xFFC if v0 == 1 begin clear else clear end", "<input>:1:35: else at 0x1004 is out of jump range"},
+		{":org 0xFFA loop while v0 == 1 clear again", "<input>:1:37: again at 0x1002 is out of jump range"},
+	}
+	for _, c := range cases {
+		_, err := Compile(c.src, chip8.VariantXOChip)
+		if assert.NotNil(t, err, c.src) {
+			assert.Equal(t, c.err, err.Error(), c.src)
+		}
+	}
+
+	bs, err := Compile(":org 0xFF8 loop v0 += 1 again", chip8.VariantXOChip)
+	assert.Nil(t, err)
+	assert.Equal(t, []byte{0x70, 0x01, 0x1F, 0xF8}, bs[len(bs)-4:])
+}
+
+func TestCompile_control(t *testing.T) {
+	bs, err := Compile(`
+		if v0 == 1 then v1 := 2
+		if v0 key then clear
+		if v0 != v1 begin
+			v2 := 1
+		else
+			v2 := 2
+		end
+		loop
+			v0 += 1
+			while v0 != 10
+		again
+	`, chip8.VariantChip8)
+
+	assert.Nil(t, err)
+	assert.Equal(t, []byte{
+		0x40, 0x01, 0x61, 0x02, // 200
+		0xE0, 0xA1, 0x00, 0xE0, // 204
+		0x90, 0x10, 0x12, 0x10, // 208 if: skip when equal, else jump to 210
+		0x62, 0x01, 0x12, 0x12, // 20C then, jump to end at 212
+		0x62, 0x02, // 210 else
+		0x70, 0x01, // 212 loop
+		0x40, 0x0A, 0x12, 0x1A, // 214 while: break when v0 == 10
+		0x12, 0x12, // 218 again
+	}, bs)
+}
+
+// TestCompile_comparisons runs every comparison on the emulator, with
+// both a register and a constant on the right.
+func TestCompile_comparisons(t *testing.T) {
+	for _, c := range []struct {
+		a, b int
+	}{{1, 2}, {2, 2}, {3, 2}} {
+		for op, want := range map[string]bool{
+			"<": c.a < c.b, ">": c.a > c.b, "<=": c.a <= c.b, ">=": c.a >= c.b,
+			"==": c.a == c.b, "!=": c.a != c.b,
+		} {
+			for _, rhs := range []string{"v1", "2"} {
+				bs, err := Compile(`
+					v0 := `+itoa(c.a)+`
+					v1 := 2
+					v2 := 0
+					if v0 `+op+` `+rhs+` then v2 := 1
+					v3 := 0
+					if v0 `+op+` `+rhs+` begin v3 := 1 else v3 := 2 end
+					loop again
+				`, chip8.VariantChip8)
+				assert.Nil(t, err)
+
+				m := chip8.NewChip8(chip8.VariantChip8)
+				assert.Nil(t, m.LoadRomBytes(bs))
+				for i := 0; i < 20; i++ {
+					assert.Nil(t, m.Step())
+				}
+				assert.Equal(t, want, m.V[2] == 1, "%d %s %s", c.a, op, rhs)
+				assert.Equal(t, want, m.V[3] == 1, "%d %s %s begin", c.a, op, rhs)
+			}
+		}
+	}
+}
+
+func itoa(i int) string {
+	return string(rune('0' + i))
+}
+
+func TestCalc(t *testing.T) {
+	cases := map[string]int{
+		"{ 2 * 3 + 1 }":     8,
+		"{ ( 2 * 3 ) + 1 }": 7,
+		"{ 1 << 4 | 1 }":    32,
+		"{ -5 + 1 }":        -4,
+		"{ 10 min 3 }":      3,
+		"{ floor 7 / 2 }":   3,
+		"{ 3 > 2 }":         1,
+		"{ ! 0 }":           1,
+		"{ HERE }":          0x200,
+		"{ 0xFF & ~ 0x0F }": 0xF0,
+		"{ 2 pow 3 - 1 }":   4,
+		"{ abs -3 }":        3,
+		"{ 7 % 4 }":         3,
+	}
+	for expr, want := range cases {
+		bs, err := Compile(":calc x "+expr+" :byte { x & 0xFF }", chip8.VariantChip8)
+		assert.Nil(t, err, expr)
+		assert.Equal(t, []byte{byte(want)}, bs, expr)
+	}
+}
+
+func TestCompile_errors(t *testing.T) {
+	cases := []struct {
+		src string
+		err string
+	}{
+		{"jump nowhere", "<input>:1:6: undefined name nowhere"},
+		{"v0 := 256", "<input>:1:7: byte 256 out of range"},
+		{"v0 ~= v1", "<input>:1:4: unknown operator \"~=\""},
+		{"if v0 == 1 begin clear", "<input>:1:18: if ... begin without end"},
+		{"loop clear", "<input>:1:6: loop without again"},
+		{"again", "<input>:1:1: again without loop"},
+		{": a\n: a", "<input>:2:3: label a redefined"},
+		{"sprite v0 v1", "<input>:1:11: unexpected end of file"},
+		{":calc x { 1 + }", "<input>:1:9: incomplete expression"},
+		{":calc x { y }", "<input>:1:11: undefined name y"},
+		{"if v0 then clear", "<input>:1:7: unknown condition \"then\""},
+		{"0x10 :org 0x200 0x10", "<input>:1:17: overlaps the data at 0x200"},
+	}
+	for _, c := range cases {
+		_, err := Compile(c.src, chip8.VariantChip8)
+		if assert.NotNil(t, err, c.src) {
+			assert.Equal(t, c.err, err.Error(), c.src)
+		}
+	}
+}
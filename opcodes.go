@@ -1,7 +1,6 @@
 package chip8
 
 import (
-	"fmt"
 	"math/rand"
 )
 
@@ -16,10 +15,15 @@ type Opcode struct {
 	// ReadKey      ReadKey
 }
 
+// RandomNumber draws from RandomNumberFn, then the machine's source and
+// then math/rand, whichever is set first.
 func (o Opcode) RandomNumber() uint8 {
 	if o.RandomNumberFn != nil {
 		return o.RandomNumberFn()
 	}
+	if o.Chip8 != nil && o.Chip8.Random != nil {
+		return o.Chip8.Random.Next(o.Chip8)
+	}
 
 	return uint8(rand.Int31n(256))
 }
@@ -32,108 +36,101 @@ func (o Opcode) RandomNumber() uint8 {
 // 	return uint8(rand.Int31n(256))
 // }
 
-func (o *Opcode) Execute() {
-	switch {
-	case o.Value == 0x00E0:
-		o.DispClr()
-	case o.Value == 0x00EE:
-		o.Return()
-	case o.Value>>12 == 0x0:
-		o.Call()
-	case o.Value>>12 == 0x1:
-		o.Jump()
-	case o.Value>>12 == 0x2:
-		o.CallSub()
-	case o.Value>>12 == 0x3:
-		o.SkipEq()
-	case o.Value>>12 == 0x4:
-		o.SkipNeq()
-	case o.Value>>12 == 0x5:
-		o.SkipEqVY()
-	case o.Value>>12 == 0x6:
-		o.Set()
-	case o.Value>>12 == 0x7:
-		o.Add()
-	case o.Value&0xF00F == 0x8000:
-		o.SetVY()
-	case o.Value&0xF00F == 0x8001:
-		o.OrVY()
-	case o.Value&0xF00F == 0x8002:
-		o.AndVY()
-	case o.Value&0xF00F == 0x8003:
-		o.XorVY()
-	case o.Value&0xF00F == 0x8004:
-		o.AddVY()
-	case o.Value&0xF00F == 0x8005:
-		o.SubVY()
-	case o.Value&0xF00F == 0x8006:
-		o.ShiftRight()
-	case o.Value&0xF00F == 0x8007:
-		o.VYSub()
-	case o.Value&0xF00F == 0x800E:
-		o.ShiftLeft()
-	case o.Value>>12 == 0x9:
-		o.SkipNeqVY()
-	case o.Value>>12 == 0xA:
-		o.SetI()
-	case o.Value>>12 == 0xB:
-		o.JumpPlusV0()
-	case o.Value>>12 == 0xC:
-		o.SetRandomMask()
-	case o.Value>>12 == 0xD:
-		o.Draw()
-	case o.Value&0xF0FF == 0xE09E:
-		o.SkipKeyPressed()
-	case o.Value&0xF0FF == 0xE0A1:
-		o.SkipNotKeyPressed()
-	case o.Value&0xF0FF == 0xF007:
-		o.SetFromDelay()
-	case o.Value&0xF0FF == 0xF00A:
-		o.ReadKey()
-	case o.Value&0xF0FF == 0xF015:
-		o.SetDelay()
-	case o.Value&0xF0FF == 0xF018:
-		o.SetSound()
-	case o.Value&0xF0FF == 0xF01E:
-		o.AddI()
-	case o.Value&0xF0FF == 0xF029:
-		o.SetISprite()
-	case o.Value&0xF0FF == 0xF033:
-		o.SetBCD()
-	case o.Value&0xF0FF == 0xF055:
-		o.RegDump()
-	case o.Value&0xF0FF == 0xF065:
-		o.RegLoad()
-	default:
-		panic(fmt.Sprintf("unkown opcode %+v", o.Value))
+func (o *Opcode) Execute() error {
+	in := Decode(o.Value, o.Chip8.Variant)
+	if in == nil {
+		return o.fault(ErrUnknownOpcode)
+	}
+	return in.exec(o)
+}
+
+// has reports whether the machine implements the instructions added
+// by variant v.
+func (o *Opcode) has(v Variant) bool {
+	return o.Chip8.Variant >= v
+}
+
+// skip moves PC past the next instruction, which is four bytes long
+// when it is the XO-CHIP F000 NNNN long load.
+func (o *Opcode) skip() {
+	c := o.Chip8
+	c.PC += 2
+	if o.has(VariantXOChip) {
+		pc := int(c.PC)
+		if c.Memory[c.mem(pc)] == 0xF0 && c.Memory[c.mem(pc+1)] == 0x00 {
+			c.PC += 2
+		}
 	}
+	c.PC += 2
 }
 
-func (o *Opcode) Call() {
-	panic(fmt.Sprintf("Call: not implemented yet, code %v", o.Value))
+// Call would run a native COSMAC routine at NNN, which cannot be
+// emulated.
+func (o *Opcode) Call() error {
+	return o.fault(ErrUnknownOpcode)
 }
 func (o *Opcode) DispClr() {
 	o.Chip8.Screen.Clear()
 	o.Chip8.PC += 2
 }
-func (o *Opcode) Return() {
-	o.Chip8.SP--
-	o.Chip8.PC = o.Chip8.Stack[o.Chip8.SP]
+
+func (o *Opcode) ScrollDown() {
+	o.Chip8.Screen.ScrollDown(int(o.Value & 0x000F))
+	o.Chip8.PC += 2
+}
+func (o *Opcode) ScrollUp() {
+	o.Chip8.Screen.ScrollUp(int(o.Value & 0x000F))
+	o.Chip8.PC += 2
+}
+func (o *Opcode) ScrollRight() {
+	o.Chip8.Screen.ScrollRight(4)
+	o.Chip8.PC += 2
+}
+func (o *Opcode) ScrollLeft() {
+	o.Chip8.Screen.ScrollLeft(4)
+	o.Chip8.PC += 2
+}
+
+// Exit stops the interpreter. PC stays on 00FD so the program keeps
+// exiting if stepped again.
+func (o *Opcode) Exit() error {
+	return o.fault(ErrExit)
+}
+func (o *Opcode) LowRes() {
+	o.Chip8.Screen.SetHiRes(false)
+	o.Chip8.PC += 2
+}
+func (o *Opcode) HighRes() {
+	o.Chip8.Screen.SetHiRes(true)
+	o.Chip8.PC += 2
+}
+
+// Return resumes after the call instruction whose address CallSub left
+// on the stack.
+func (o *Opcode) Return() error {
+	pc, err := o.Chip8.pop()
+	if err != nil {
+		return o.fault(err)
+	}
+	o.Chip8.PC = pc + 2
+	return nil
 }
 func (o *Opcode) Jump() {
 	address := o.Value & 0x0FFF
 	o.Chip8.PC = address
 }
-func (o *Opcode) CallSub() {
-	o.Chip8.Stack[o.Chip8.SP] = o.Chip8.PC
-	o.Chip8.SP++
+func (o *Opcode) CallSub() error {
+	if err := o.Chip8.push(o.Chip8.PC); err != nil {
+		return o.fault(err)
+	}
 	o.Chip8.PC = o.Value & 0x0FFF
+	return nil
 }
 func (o *Opcode) SkipEq() {
 	n := uint8(o.Value & 0x00FF)
 	x := (o.Value & 0x0F00) >> 8
 	if o.Chip8.V[x] == n {
-		o.Chip8.PC += 4
+		o.skip()
 		return
 	}
 	o.Chip8.PC += 2
@@ -142,7 +139,7 @@ func (o *Opcode) SkipNeq() {
 	n := uint8(o.Value & 0x00FF)
 	x := (o.Value & 0x0F00) >> 8
 	if o.Chip8.V[x] != n {
-		o.Chip8.PC += 4
+		o.skip()
 		return
 	}
 	o.Chip8.PC += 2
@@ -151,7 +148,7 @@ func (o *Opcode) SkipEqVY() {
 	x := (o.Value & 0x0F00) >> 8
 	y := (o.Value & 0x00F0) >> 4
 	if o.Chip8.V[x] == o.Chip8.V[y] {
-		o.Chip8.PC += 4
+		o.skip()
 		return
 	}
 	o.Chip8.PC += 2
@@ -178,70 +175,80 @@ func (o *Opcode) OrVY() {
 	x := (o.Value & 0x0F00) >> 8
 	y := (o.Value & 0x00F0) >> 4
 	o.Chip8.V[x] |= o.Chip8.V[y]
+	o.resetVF()
 	o.Chip8.PC += 2
 }
 func (o *Opcode) AndVY() {
 	x := (o.Value & 0x0F00) >> 8
 	y := (o.Value & 0x00F0) >> 4
 	o.Chip8.V[x] &= o.Chip8.V[y]
+	o.resetVF()
 	o.Chip8.PC += 2
 }
 func (o *Opcode) XorVY() {
 	x := (o.Value & 0x0F00) >> 8
 	y := (o.Value & 0x00F0) >> 4
 	o.Chip8.V[x] ^= o.Chip8.V[y]
+	o.resetVF()
 	o.Chip8.PC += 2
 }
+func (o *Opcode) resetVF() {
+	if o.Chip8.Quirks.VFReset {
+		o.Chip8.V[0xF] = 0
+	}
+}
+
+// The arithmetic ops write VF last, so that VF used as an operand
+// still ends up holding the flag.
 func (o *Opcode) AddVY() {
 	x := (o.Value & 0x0F00) >> 8
 	y := (o.Value & 0x00F0) >> 4
-	o.Chip8.V[0xF] = 0x0
-	hasCarray := (0xFF - o.Chip8.V[x]) < o.Chip8.V[y]
-	if hasCarray {
-		o.Chip8.V[0xF] = 0x1
-	}
+	hasCarry := (0xFF - o.Chip8.V[x]) < o.Chip8.V[y]
 	o.Chip8.V[x] += o.Chip8.V[y]
+	o.Chip8.V[0xF] = b2i[hasCarry]
 	o.Chip8.PC += 2
 }
 func (o *Opcode) SubVY() {
 	x := (o.Value & 0x0F00) >> 8
 	y := (o.Value & 0x00F0) >> 4
-	o.Chip8.V[0xF] = 0x0
-	hasCarray := o.Chip8.V[x] < o.Chip8.V[y]
-	if hasCarray {
-		o.Chip8.V[0xF] = 0x1
-	}
+	noBorrow := o.Chip8.V[x] >= o.Chip8.V[y]
 	o.Chip8.V[x] -= o.Chip8.V[y]
+	o.Chip8.V[0xF] = b2i[noBorrow]
 	o.Chip8.PC += 2
 }
 func (o *Opcode) ShiftRight() {
 	x := (o.Value & 0x0F00) >> 8
-	o.Chip8.V[0xF] = o.Chip8.V[x] & 0x01
-	o.Chip8.V[x] >>= 1
+	v := o.shiftOperand()
+	o.Chip8.V[x] = v >> 1
+	o.Chip8.V[0xF] = v & 0x01
 	o.Chip8.PC += 2
 }
 func (o *Opcode) VYSub() {
 	x := (o.Value & 0x0F00) >> 8
 	y := (o.Value & 0x00F0) >> 4
-	o.Chip8.V[0xF] = 0x0
-	hasCarray := o.Chip8.V[x] > o.Chip8.V[y]
-	if hasCarray {
-		o.Chip8.V[0xF] = 0x1
-	}
+	noBorrow := o.Chip8.V[y] >= o.Chip8.V[x]
 	o.Chip8.V[x] = o.Chip8.V[y] - o.Chip8.V[x]
+	o.Chip8.V[0xF] = b2i[noBorrow]
 	o.Chip8.PC += 2
 }
 func (o *Opcode) ShiftLeft() {
 	x := (o.Value & 0x0F00) >> 8
-	o.Chip8.V[0xF] = o.Chip8.V[x] & 0x80
-	o.Chip8.V[x] <<= 1
+	v := o.shiftOperand()
+	o.Chip8.V[x] = v << 1
+	o.Chip8.V[0xF] = v >> 7
 	o.Chip8.PC += 2
 }
+func (o *Opcode) shiftOperand() uint8 {
+	if o.Chip8.Quirks.ShiftVY {
+		return o.Chip8.V[(o.Value&0x00F0)>>4]
+	}
+	return o.Chip8.V[(o.Value&0x0F00)>>8]
+}
 func (o *Opcode) SkipNeqVY() {
 	x := (o.Value & 0x0F00) >> 8
 	y := (o.Value & 0x00F0) >> 4
 	if o.Chip8.V[x] != o.Chip8.V[y] {
-		o.Chip8.PC += 4
+		o.skip()
 		return
 	}
 	o.Chip8.PC += 2
@@ -253,7 +260,11 @@ func (o *Opcode) SetI() {
 }
 func (o *Opcode) JumpPlusV0() {
 	n := o.Value & 0x0FFF
-	o.Chip8.PC = uint16(o.Chip8.V[0x0]) + n
+	v := o.Chip8.V[0x0]
+	if o.Chip8.Quirks.JumpVX {
+		v = o.Chip8.V[(o.Value&0x0F00)>>8]
+	}
+	o.Chip8.PC = uint16(v) + n
 }
 func (o *Opcode) SetRandomMask() {
 	x := (o.Value & 0x0F00) >> 8
@@ -261,34 +272,81 @@ func (o *Opcode) SetRandomMask() {
 	o.Chip8.V[x] = o.RandomNumber() & uint8(n)
 	o.Chip8.PC += 2
 }
-func (o *Opcode) Draw() {
-	x := int(o.Chip8.V[(o.Value&0x0F00)>>8])
-	y := int(o.Chip8.V[(o.Value&0x00F0)>>4])
-	n := int(o.Value & 0x000F)
-	o.Chip8.V[0xF] = 0
-	collision := false
 
-	for row := y; row < y+n; row++ {
-		word := o.Chip8.Memory[int(o.Chip8.I)+row-y]
-		collision = o.Chip8.Screen.DrawByte(row, x, word) || collision
+// Draw XORs an 8xN sprite at (VX, VY), or a 16x16 one when N is 0,
+// into every selected plane. With several planes selected, the sprite
+// data for each plane follows the previous one in memory. In high
+// resolution with Quirks.CountCollisions, VF receives the number of
+// sprite rows that collided or were clipped off the bottom.
+func (o *Opcode) Draw() error {
+	c := o.Chip8
+	x := int(c.V[(o.Value&0x0F00)>>8])
+	y := int(c.V[(o.Value&0x00F0)>>4])
+	rows, rowBytes := int(o.Value&0x000F), 1
+	if rows == 0 {
+		rows, rowBytes = 16, 2
+	}
+	planes := 0
+	c.Screen.eachPlane(func(int) { planes++ })
+	if err := c.checkSpan(int(c.I), planes*rows*rowBytes, false); err != nil {
+		return o.fault(err)
 	}
+	if c.Quirks.DisplayWait && c.vblank != vblankReady {
+		c.vblank = vblankWaiting
+		return nil
+	}
+	c.vblank = vblankNone
+
+	w, h := c.Screen.Width(), c.Screen.Height()
+	x %= w
+	y %= h
+	collisions := 0
+	addr := int(c.I)
+	c.Screen.eachPlane(func(p int) {
+		for r := 0; r < rows; r++ {
+			row := y + r
+			if row >= h {
+				if c.Quirks.Clip {
+					collisions += rows - r
+					break
+				}
+				row %= h
+			}
+			hit := false
+			for b := 0; b < rowBytes; b++ {
+				word := c.Memory[c.mem(addr+r*rowBytes+b)]
+				hit = c.Screen.drawByte(p, row, x+8*b, word, c.Quirks.Clip) || hit
+			}
+			if hit {
+				collisions++
+			}
+		}
+		addr += rows * rowBytes
+	})
 	o.Chip8.PC += 2
-	if collision {
-		o.Chip8.V[0xf] = 1
+
+	if c.Screen.HiRes() && c.Quirks.CountCollisions {
+		c.V[0xF] = uint8(collisions)
+		return nil
 	}
+	c.V[0xF] = 0
+	if collisions > 0 {
+		c.V[0xF] = 1
+	}
+	return nil
 }
 func (o *Opcode) SkipKeyPressed() {
 	x := (o.Value & 0x0F00) >> 8
-	if o.Chip8.Key[o.Chip8.V[x]] == 1 {
-		o.Chip8.PC += 4
+	if o.Chip8.keypad().IsDown(o.Chip8.V[x] & 0xF) {
+		o.skip()
 		return
 	}
 	o.Chip8.PC += 2
 }
 func (o *Opcode) SkipNotKeyPressed() {
 	x := (o.Value & 0x0F00) >> 8
-	if o.Chip8.Key[o.Chip8.V[x]] == 0 {
-		o.Chip8.PC += 4
+	if !o.Chip8.keypad().IsDown(o.Chip8.V[x] & 0xF) {
+		o.skip()
 		return
 	}
 	o.Chip8.PC += 2
@@ -298,9 +356,53 @@ func (o *Opcode) SetFromDelay() {
 	o.Chip8.V[x] = o.Chip8.DelayTimer
 	o.Chip8.PC += 2
 }
+
+// ReadKey keeps PC on the FX0A instruction until a key has been pressed
+// and released again, like the COSMAC VIP did. Every fetch of the
+// instruction while waiting polls the keypad once. Releases between
+// polls arrive through NextRelease, so quick taps are not missed; the
+// key seen down is kept in KeyWait, so that a restored wait ends once
+// that key is up.
 func (o *Opcode) ReadKey() {
-	panic(fmt.Sprintf("ReadKey: not implemented yet, code %v", o.Value))
+	x := uint8((o.Value & 0x0F00) >> 8)
+	c := o.Chip8
+	w := &c.KeyWait
+	if !w.Active {
+		*w = KeyWait{Active: true, Register: x}
+		c.keyRelease = nil
+	}
+	k := c.keypad()
+	if c.keyRelease == nil {
+		c.keyRelease = k.NextRelease()
+	}
+
+	select {
+	case key := <-c.keyRelease:
+		c.endKeyWait(key)
+		return
+	default:
+	}
+	if !w.Pressed {
+		for key := uint8(0); key < KeyCount; key++ {
+			if k.IsDown(key) {
+				w.Pressed, w.Key = true, key
+				break
+			}
+		}
+		return
+	}
+	if !k.IsDown(w.Key) {
+		c.endKeyWait(w.Key)
+	}
 }
+
+func (c *Chip8) endKeyWait(key uint8) {
+	c.V[c.KeyWait.Register] = key
+	c.KeyWait = KeyWait{}
+	c.keyRelease = nil
+	c.PC += 2
+}
+
 func (o *Opcode) SetDelay() {
 	x := (o.Value & 0x0F00) >> 8
 	o.Chip8.DelayTimer = o.Chip8.V[x]
@@ -318,31 +420,158 @@ func (o *Opcode) AddI() {
 }
 func (o *Opcode) SetISprite() {
 	x := (o.Value & 0x0F00) >> 8
-	char := o.Chip8.V[x]
-	o.Chip8.I = uint16(0x50 + char*5)
+	char := o.Chip8.V[x] & 0xF
+	o.Chip8.I = FontSetStart + uint16(char)*5
 	o.Chip8.PC += 2
 }
-func (o *Opcode) SetBCD() {
+func (o *Opcode) SetIBigSprite() {
+	x := (o.Value & 0x0F00) >> 8
+	char := o.Chip8.V[x] & 0xF
+	o.Chip8.I = HiResFontSetStart + uint16(char)*10
+	o.Chip8.PC += 2
+}
+func (o *Opcode) SetBCD() error {
 	x := (o.Value & 0x0F00) >> 8
 	v := o.Chip8.V[x]
-	o.Chip8.Memory[o.Chip8.I+0] = v / 100
-	o.Chip8.Memory[o.Chip8.I+1] = (v / 10) % 10
-	o.Chip8.Memory[o.Chip8.I+2] = v % 10
+	i := int(o.Chip8.I)
+	if err := o.Chip8.checkSpan(i, 3, true); err != nil {
+		return o.fault(err)
+	}
+	o.Chip8.Memory[o.Chip8.mem(i+0)] = v / 100
+	o.Chip8.Memory[o.Chip8.mem(i+1)] = (v / 10) % 10
+	o.Chip8.Memory[o.Chip8.mem(i+2)] = v % 10
 	o.Chip8.PC += 2
+	return nil
 }
-func (o *Opcode) RegDump() {
+func (o *Opcode) RegDump() error {
+	x := int((o.Value & 0x0F00) >> 8)
+	if err := o.Chip8.checkSpan(int(o.Chip8.I), x+1, true); err != nil {
+		return o.fault(err)
+	}
+	for i := 0; i <= x; i++ {
+		o.Chip8.Memory[o.Chip8.mem(int(o.Chip8.I)+i)] = o.Chip8.V[i]
+	}
+	o.incrementI(x)
+	o.Chip8.PC += 2
+	return nil
+}
+func (o *Opcode) RegLoad() error {
+	x := int((o.Value & 0x0F00) >> 8)
+	if err := o.Chip8.checkSpan(int(o.Chip8.I), x+1, false); err != nil {
+		return o.fault(err)
+	}
+	for i := 0; i <= x; i++ {
+		o.Chip8.V[i] = o.Chip8.Memory[o.Chip8.mem(int(o.Chip8.I)+i)]
+	}
+	o.incrementI(x)
+	o.Chip8.PC += 2
+	return nil
+}
+func (o *Opcode) incrementI(x int) {
+	switch o.Chip8.Quirks.LoadStore {
+	case IncrementX:
+		o.Chip8.I += uint16(x)
+	case IncrementX1:
+		o.Chip8.I += uint16(x) + 1
+	}
+}
+
+// SaveFlags copies V0..VX into the HP48 RPL user flags of the loaded
+// ROM, keeping the flags above X.
+func (o *Opcode) SaveFlags() error {
 	x := (o.Value & 0x0F00) >> 8
-	var i uint16 = 0
-	for ; i <= x; i++ {
-		o.Chip8.Memory[o.Chip8.I+i] = o.Chip8.V[i]
+	store := o.Chip8.flagStore()
+	flags, err := store.LoadFlags(o.Chip8.RomHash)
+	if err != nil {
+		return o.fault(err)
+	}
+	copy(flags[:x+1], o.Chip8.V[:x+1])
+	if err := store.SaveFlags(o.Chip8.RomHash, flags); err != nil {
+		return o.fault(err)
 	}
 	o.Chip8.PC += 2
+	return nil
 }
-func (o *Opcode) RegLoad() {
+func (o *Opcode) LoadFlags() error {
 	x := (o.Value & 0x0F00) >> 8
-	var i uint16 = 0
-	for ; i <= x; i++ {
-		o.Chip8.V[i] = o.Chip8.Memory[o.Chip8.I+i]
+	flags, err := o.Chip8.flagStore().LoadFlags(o.Chip8.RomHash)
+	if err != nil {
+		return o.fault(err)
 	}
+	copy(o.Chip8.V[:x+1], flags[:x+1])
+	o.Chip8.PC += 2
+	return nil
+}
+
+// SaveRange stores VX..VY at I, in reverse order when X > Y, leaving I
+// unchanged.
+func (o *Opcode) SaveRange() error {
+	x, y := int((o.Value&0x0F00)>>8), int((o.Value&0x00F0)>>4)
+	regs := registerRange(x, y)
+	if err := o.Chip8.checkSpan(int(o.Chip8.I), len(regs), true); err != nil {
+		return o.fault(err)
+	}
+	for i, r := range regs {
+		o.Chip8.Memory[o.Chip8.mem(int(o.Chip8.I)+i)] = o.Chip8.V[r]
+	}
+	o.Chip8.PC += 2
+	return nil
+}
+func (o *Opcode) LoadRange() error {
+	x, y := int((o.Value&0x0F00)>>8), int((o.Value&0x00F0)>>4)
+	regs := registerRange(x, y)
+	if err := o.Chip8.checkSpan(int(o.Chip8.I), len(regs), false); err != nil {
+		return o.fault(err)
+	}
+	for i, r := range regs {
+		o.Chip8.V[r] = o.Chip8.Memory[o.Chip8.mem(int(o.Chip8.I)+i)]
+	}
+	o.Chip8.PC += 2
+	return nil
+}
+func registerRange(x, y int) []int {
+	regs := []int{}
+	step := 1
+	if x > y {
+		step = -1
+	}
+	for r := x; r != y+step; r += step {
+		regs = append(regs, r)
+	}
+	return regs
+}
+
+// SetILong loads I with the 16 bit word following the instruction.
+func (o *Opcode) SetILong() error {
+	c := o.Chip8
+	if err := c.checkSpan(int(c.PC)+2, 2, false); err != nil {
+		return o.fault(err)
+	}
+	pc := int(c.PC)
+	c.I = uint16(c.Memory[c.mem(pc+2)])<<8 | uint16(c.Memory[c.mem(pc+3)])
+	c.PC += 4
+	return nil
+}
+func (o *Opcode) SelectPlanes() {
+	o.Chip8.Screen.SelectPlanes(uint8((o.Value & 0x0F00) >> 8))
+	o.Chip8.PC += 2
+}
+
+// LoadAudio copies the 16 byte (128 sample, 1 bit each) audio pattern
+// at I into the audio buffer.
+func (o *Opcode) LoadAudio() error {
+	c := o.Chip8
+	if err := c.checkSpan(int(c.I), len(c.Audio), false); err != nil {
+		return o.fault(err)
+	}
+	for i := range c.Audio {
+		c.Audio[i] = c.Memory[c.mem(int(c.I)+i)]
+	}
+	c.PC += 2
+	return nil
+}
+func (o *Opcode) SetPitch() {
+	x := (o.Value & 0x0F00) >> 8
+	o.Chip8.Pitch = o.Chip8.V[x]
 	o.Chip8.PC += 2
 }
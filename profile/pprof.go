@@ -0,0 +1,200 @@
+package profile
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+)
+
+// buffer encodes protocol buffers, enough of them for profile.proto.
+type buffer struct {
+	bytes.Buffer
+}
+
+const (
+	wireVarint = 0
+	wireBytes  = 2
+)
+
+func (b *buffer) varint(x uint64) {
+	for x >= 0x80 {
+		b.WriteByte(byte(x) | 0x80)
+		x >>= 7
+	}
+	b.WriteByte(byte(x))
+}
+
+func (b *buffer) key(field, wire int) {
+	b.varint(uint64(field)<<3 | uint64(wire))
+}
+
+func (b *buffer) uint64(field int, x uint64) {
+	if x == 0 {
+		return
+	}
+	b.key(field, wireVarint)
+	b.varint(x)
+}
+
+func (b *buffer) bool(field int, x bool) {
+	if x {
+		b.uint64(field, 1)
+	}
+}
+
+func (b *buffer) bytes(field int, bs []byte) {
+	b.key(field, wireBytes)
+	b.varint(uint64(len(bs)))
+	b.Write(bs)
+}
+
+func (b *buffer) string(field int, s string) {
+	b.bytes(field, []byte(s))
+}
+
+func (b *buffer) message(field int, m *buffer) {
+	b.bytes(field, m.Bytes())
+}
+
+func (b *buffer) packed(field int, xs []uint64) {
+	var p buffer
+	for _, x := range xs {
+		p.varint(x)
+	}
+	b.message(field, &p)
+}
+
+// Field numbers from github.com/google/pprof/proto/profile.proto.
+const (
+	profileSampleType    = 1
+	profileSample        = 2
+	profileMapping       = 3
+	profileLocation      = 4
+	profileFunction      = 5
+	profileStringTable   = 6
+	profilePeriodType    = 11
+	profilePeriod        = 12
+	profileDefaultSample = 14
+
+	valueTypeType = 1
+	valueTypeUnit = 2
+
+	sampleLocationID = 1
+	sampleValue      = 2
+
+	mappingID           = 1
+	mappingMemoryStart  = 2
+	mappingMemoryLimit  = 3
+	mappingFilename     = 5
+	mappingHasFunctions = 7
+	mappingHasLines     = 9
+
+	locationID        = 1
+	locationMappingID = 2
+	locationAddress   = 3
+	locationLine      = 4
+
+	lineFunctionID = 1
+	lineLine       = 2
+
+	functionID         = 1
+	functionName       = 2
+	functionSystemName = 3
+	functionFilename   = 4
+	functionStartLine  = 5
+)
+
+// WriteProfile writes the profile in the gzipped protocol buffer format
+// of pprof, so that "go tool pprof" can show it. Every instruction is
+// one sample of the "instructions" type. The source lines of functions
+// are memory addresses, and file names the given rom.
+func (p *Profiler) WriteProfile(w io.Writer, rom string) error {
+	var out buffer
+	strings := map[string]uint64{}
+	var table []string
+	str := func(s string) uint64 {
+		if i, ok := strings[s]; ok {
+			return i
+		}
+		strings[s] = uint64(len(table))
+		table = append(table, s)
+		return strings[s]
+	}
+	str("")
+
+	valueType := func(field int, typ, unit string) {
+		var v buffer
+		v.uint64(valueTypeType, str(typ))
+		v.uint64(valueTypeUnit, str(unit))
+		out.message(field, &v)
+	}
+	valueType(profileSampleType, "instructions", "count")
+
+	type place struct{ pc, entry uint16 }
+	locations := map[place]uint64{}
+	var places []place
+	functions := map[uint16]uint64{}
+	var entries []uint16
+	for _, s := range p.sortedSamples() {
+		ids := make([]uint64, s.depth)
+		for k := range ids {
+			pl := place{s.pcs[k], s.entries[k]}
+			if _, ok := locations[pl]; !ok {
+				locations[pl] = uint64(len(places) + 1)
+				places = append(places, pl)
+			}
+			if _, ok := functions[pl.entry]; !ok {
+				functions[pl.entry] = uint64(len(entries) + 1)
+				entries = append(entries, pl.entry)
+			}
+			ids[k] = locations[pl]
+		}
+		var sample buffer
+		sample.packed(sampleLocationID, ids)
+		sample.packed(sampleValue, []uint64{p.samples[s]})
+		out.message(profileSample, &sample)
+	}
+
+	var mapping buffer
+	mapping.uint64(mappingID, 1)
+	mapping.uint64(mappingMemoryStart, 0)
+	mapping.uint64(mappingMemoryLimit, uint64(p.variant.MemorySize()))
+	mapping.uint64(mappingFilename, str(rom))
+	mapping.bool(mappingHasFunctions, true)
+	mapping.bool(mappingHasLines, true)
+	out.message(profileMapping, &mapping)
+
+	for i, pl := range places {
+		var line buffer
+		line.uint64(lineFunctionID, functions[pl.entry])
+		line.uint64(lineLine, uint64(pl.pc))
+		var loc buffer
+		loc.uint64(locationID, uint64(i+1))
+		loc.uint64(locationMappingID, 1)
+		loc.uint64(locationAddress, uint64(pl.pc))
+		loc.message(locationLine, &line)
+		out.message(profileLocation, &loc)
+	}
+	for i, entry := range entries {
+		var fn buffer
+		fn.uint64(functionID, uint64(i+1))
+		fn.uint64(functionName, str(p.Name(entry)))
+		fn.uint64(functionSystemName, str(p.Name(entry)))
+		fn.uint64(functionFilename, str(rom))
+		fn.uint64(functionStartLine, uint64(entry))
+		out.message(profileFunction, &fn)
+	}
+
+	valueType(profilePeriodType, "instructions", "count")
+	out.uint64(profilePeriod, 1)
+	out.uint64(profileDefaultSample, str("instructions"))
+	for _, s := range table {
+		out.string(profileStringTable, s)
+	}
+
+	zw := gzip.NewWriter(w)
+	if _, err := zw.Write(out.Bytes()); err != nil {
+		return err
+	}
+	return zw.Close()
+}
@@ -0,0 +1,93 @@
+package profile
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io/ioutil"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type field struct {
+	num   int
+	value uint64
+	bytes []byte
+}
+
+// decode splits a protocol buffer message into its fields.
+func decode(t *testing.T, bs []byte) []field {
+	var fields []field
+	varint := func() uint64 {
+		var x uint64
+		for shift := uint(0); ; shift += 7 {
+			assert.NotEmpty(t, bs)
+			b := bs[0]
+			bs = bs[1:]
+			x |= uint64(b&0x7F) << shift
+			if b < 0x80 {
+				return x
+			}
+		}
+	}
+	for len(bs) > 0 {
+		key := varint()
+		f := field{num: int(key >> 3)}
+		switch key & 7 {
+		case wireVarint:
+			f.value = varint()
+		case wireBytes:
+			n := varint()
+			f.bytes, bs = bs[:n], bs[n:]
+		default:
+			t.Fatalf("unexpected wire type %d", key&7)
+		}
+		fields = append(fields, f)
+	}
+	return fields
+}
+
+func find(fields []field, num int) []field {
+	var out []field
+	for _, f := range fields {
+		if f.num == num {
+			out = append(out, f)
+		}
+	}
+	return out
+}
+
+func TestWriteProfile(t *testing.T) {
+	p := run(t, 1+13*100)
+	p.Names = map[uint16]string{0x20C: "inc"}
+	var out bytes.Buffer
+
+	assert.Nil(t, p.WriteProfile(&out, "test.ch8"))
+
+	zr, err := gzip.NewReader(&out)
+	assert.Nil(t, err)
+	raw, err := ioutil.ReadAll(zr)
+	assert.Nil(t, err)
+	fields := decode(t, raw)
+
+	var strings []string
+	for _, f := range find(fields, profileStringTable) {
+		strings = append(strings, string(f.bytes))
+	}
+	assert.Equal(t, "", strings[0])
+	assert.Subset(t, strings, []string{"instructions", "count", "main", "inc", "sub_210", "test.ch8"})
+
+	// Every instruction is in one sample.
+	var total uint64
+	for _, s := range find(fields, profileSample) {
+		values := find(decode(t, s.bytes), sampleValue)
+		assert.Len(t, values, 1)
+		total += decode(t, append([]byte{sampleValue << 3}, values[0].bytes...))[0].value
+	}
+	assert.Equal(t, p.Total, total)
+
+	assert.Len(t, find(fields, profileFunction), 3)
+	assert.Len(t, find(fields, profileMapping), 1)
+	// A location for each address in each function that ran it.
+	assert.Len(t, find(fields, profileLocation), 10)
+}
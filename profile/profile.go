@@ -0,0 +1,235 @@
+// Package profile measures which parts of a CHIP-8 program run and
+// where its instructions go: executions per address and per
+// instruction, calls per subroutine and the instructions spent in each,
+// by itself (flat) and with what it calls (cumulative). Results are
+// reported as text, as an annotated disassembly or as a pprof profile.
+//
+// Time is counted in instructions. A subroutine is identified by its
+// entry address, found from the CALL instructions on Chip8.Stack; the
+// code outside any subroutine is "main".
+package profile
+
+import (
+	"fmt"
+	"sort"
+
+	chip8 "github.com/hermesdt/go-plan8"
+	"github.com/hermesdt/go-plan8/disasm"
+)
+
+// mainEntry stands for the code outside any subroutine.
+const mainEntry = chip8.RomStart
+
+// maxDepth is the deepest stack recorded: the instruction and one call
+// site per stack level.
+const maxDepth = 17
+
+// stack is where an instruction ran: its address and the call sites
+// below it, innermost first, with the entry of the function each is
+// in.
+type stack struct {
+	depth   uint8
+	pcs     [maxDepth]uint16
+	entries [maxDepth]uint16
+}
+
+// Profiler is a chip8.Tracer that profiles the instructions it sees.
+type Profiler struct {
+	// Names names subroutines by entry address, for example from
+	// assembler labels. Others are called sub_XXX.
+	Names map[uint16]string
+
+	rom     []byte
+	variant chip8.Variant
+
+	// Counts holds the executions of each address.
+	Counts []uint64
+	// Ops holds the executions of each instruction, by name in
+	// chip8.Instructions. Bytes that are not instructions count as
+	// "Unknown".
+	Ops   map[string]uint64
+	Calls map[uint16]uint64
+	Total uint64
+
+	samples map[stack]uint64
+	cur     stack
+	op      string
+}
+
+// New profiles rom, the program loaded at chip8.RomStart, which the
+// reports disassemble.
+func New(rom []byte, variant chip8.Variant) *Profiler {
+	return &Profiler{
+		rom:     rom,
+		variant: variant,
+		Counts:  make([]uint64, variant.MemorySize()),
+		Ops:     map[string]uint64{},
+		Calls:   map[uint16]uint64{},
+		samples: map[stack]uint64{},
+	}
+}
+
+func (p *Profiler) Before(c *chip8.Chip8, cycle uint64) {
+	s := stack{depth: 1}
+	s.pcs[0] = c.PC
+	sp := int(c.SP)
+	if sp > len(c.Stack) {
+		sp = len(c.Stack)
+	}
+	for i := sp - 1; i >= 0; i-- {
+		site := c.Stack[i]
+		s.entries[s.depth-1] = p.target(c, site)
+		s.pcs[s.depth] = site
+		s.depth++
+	}
+	s.entries[s.depth-1] = mainEntry
+	p.cur = s
+
+	p.op = "Unknown"
+	if op := chip8.Decode(p.word(c, c.PC), c.Variant); op != nil {
+		p.op = op.Name
+	}
+}
+
+func (p *Profiler) After(c *chip8.Chip8, err error) {
+	if err != nil {
+		return
+	}
+	p.Total++
+	p.Counts[int(p.cur.pcs[0])%len(p.Counts)]++
+	p.Ops[p.op]++
+	p.samples[p.cur]++
+	if p.op == "CallSub" {
+		p.Calls[c.PC]++
+	}
+}
+
+// target is the subroutine called from site.
+func (p *Profiler) target(c *chip8.Chip8, site uint16) uint16 {
+	return p.word(c, site) & 0x0FFF
+}
+
+func (p *Profiler) word(c *chip8.Chip8, addr uint16) uint16 {
+	size := c.Variant.MemorySize()
+	return uint16(c.Memory[int(addr)%size])<<8 | uint16(c.Memory[(int(addr)+1)%size])
+}
+
+// Name names the function entered at entry.
+func (p *Profiler) Name(entry uint16) string {
+	if name, ok := p.Names[entry]; ok {
+		return name
+	}
+	if entry == mainEntry {
+		return "main"
+	}
+	return fmt.Sprintf("sub_%03X", entry)
+}
+
+// Func is the profile of a subroutine, or of main.
+type Func struct {
+	Entry uint16
+	Name  string
+	Calls uint64
+	// Flat counts the instructions executed in the function itself,
+	// Cum those as well as the ones in what it called.
+	Flat, Cum uint64
+}
+
+// Funcs returns the functions that ran, most flat instructions first.
+func (p *Profiler) Funcs() []Func {
+	funcs := map[uint16]*Func{}
+	get := func(entry uint16) *Func {
+		f, ok := funcs[entry]
+		if !ok {
+			f = &Func{Entry: entry, Name: p.Name(entry), Calls: p.Calls[entry]}
+			funcs[entry] = f
+		}
+		return f
+	}
+	for s, n := range p.samples {
+		get(s.entries[0]).Flat += n
+		// Recursive functions count once per instruction.
+		seen := map[uint16]bool{}
+		for _, e := range s.entries[:s.depth] {
+			if !seen[e] {
+				seen[e] = true
+				get(e).Cum += n
+			}
+		}
+	}
+	for entry := range p.Calls {
+		get(entry)
+	}
+	var out []Func
+	for _, f := range funcs {
+		out = append(out, *f)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Flat != out[j].Flat {
+			return out[i].Flat > out[j].Flat
+		}
+		return out[i].Entry < out[j].Entry
+	})
+	return out
+}
+
+type OpCount struct {
+	Name  string
+	Count uint64
+}
+
+// OpCounts returns the instructions executed, most frequent first.
+func (p *Profiler) OpCounts() []OpCount {
+	var out []OpCount
+	for name, n := range p.Ops {
+		out = append(out, OpCount{name, n})
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Count != out[j].Count {
+			return out[i].Count > out[j].Count
+		}
+		return out[i].Name < out[j].Name
+	})
+	return out
+}
+
+// Coverage counts the instructions reachable in the ROM and how many
+// of them executed.
+func (p *Profiler) Coverage() (executed, total int) {
+	for _, in := range p.disassemble() {
+		if in.IsData() {
+			continue
+		}
+		total++
+		if p.Counts[in.Address] > 0 {
+			executed++
+		}
+	}
+	return executed, total
+}
+
+func (p *Profiler) disassemble() []disasm.Instruction {
+	return disasm.Disassemble(p.rom, chip8.RomStart, p.variant)
+}
+
+// sortedSamples returns the stacks in a fixed order, so that output is
+// reproducible.
+func (p *Profiler) sortedSamples() []stack {
+	var out []stack
+	for s := range p.samples {
+		out = append(out, s)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		a, b := out[i], out[j]
+		for k := 0; k < maxDepth; k++ {
+			if a.pcs[k] != b.pcs[k] {
+				return a.pcs[k] < b.pcs[k]
+			}
+			if a.entries[k] != b.entries[k] {
+				return a.entries[k] < b.entries[k]
+			}
+		}
+		return a.depth < b.depth
+	})
+	return out
+}
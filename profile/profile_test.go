@@ -0,0 +1,97 @@
+package profile
+
+import (
+	"testing"
+
+	chip8 "github.com/hermesdt/go-plan8"
+	"github.com/hermesdt/go-plan8/asm"
+	"github.com/stretchr/testify/assert"
+)
+
+const testProgram = `
+	LD V0, 0        ; 200
+loop:
+	CALL inc        ; 202
+	CALL twice      ; 204
+	SE V1, 0        ; 206
+	CLS             ; 208
+	JP loop         ; 20A
+inc:
+	ADD V0, 1       ; 20C
+	RET             ; 20E
+twice:
+	CALL inc        ; 210
+	CALL inc        ; 212
+	RET             ; 214
+`
+
+// run profiles n instructions of testProgram.
+func run(t *testing.T, n int) *Profiler {
+	rom, err := asm.Assemble(testProgram, chip8.VariantChip8)
+	assert.Nil(t, err)
+	c := chip8.NewChip8(chip8.VariantChip8)
+	assert.Nil(t, c.LoadRomBytes(rom))
+	p := New(rom, chip8.VariantChip8)
+	m := chip8.NewMachine(c)
+	m.Tracer = p
+	assert.Nil(t, m.Emulate(n))
+	return p
+}
+
+func TestProfilerCounts(t *testing.T) {
+	// The first instruction, then 100 times round the loop of 13.
+	p := run(t, 1+13*100)
+
+	assert.Equal(t, uint64(1301), p.Total)
+	assert.Equal(t, uint64(1), p.Counts[0x200])
+	assert.Equal(t, uint64(100), p.Counts[0x20A])
+	assert.Equal(t, uint64(300), p.Counts[0x20C])
+	assert.Equal(t, uint64(0), p.Counts[0x208])
+	assert.Equal(t, uint64(400), p.Ops["CallSub"])
+	assert.Equal(t, uint64(400), p.Ops["Return"])
+	assert.Equal(t, map[uint16]uint64{0x20C: 300, 0x210: 100}, p.Calls)
+
+	executed, total := p.Coverage()
+	assert.Equal(t, 10, executed)
+	assert.Equal(t, 11, total)
+}
+
+func TestProfilerFuncs(t *testing.T) {
+	p := run(t, 1+13*100)
+	p.Names = map[uint16]string{0x210: "twice"}
+
+	assert.Equal(t, []Func{
+		{Entry: 0x20C, Name: "sub_20C", Calls: 300, Flat: 600, Cum: 600},
+		{Entry: 0x200, Name: "main", Flat: 401, Cum: 1301},
+		{Entry: 0x210, Name: "twice", Calls: 100, Flat: 300, Cum: 700},
+	}, p.Funcs())
+	assert.Equal(t, OpCount{"CallSub", 400}, p.OpCounts()[0])
+}
+
+func TestProfilerRecursion(t *testing.T) {
+	rom, err := asm.Assemble(`
+		LD V0, 3        ; 200
+		CALL down       ; 202
+	end:
+		JP end          ; 204
+	down:
+		ADD V0, -1      ; 206
+		SE V0, 0        ; 208
+		CALL down       ; 20A
+		RET             ; 20C
+	`, chip8.VariantChip8)
+	assert.Nil(t, err)
+	c := chip8.NewChip8(chip8.VariantChip8)
+	assert.Nil(t, c.LoadRomBytes(rom))
+	p := New(rom, chip8.VariantChip8)
+	m := chip8.NewMachine(c)
+	m.Tracer = p
+	assert.Nil(t, m.Emulate(2+4+4+3+1))
+
+	funcs := p.Funcs()
+	assert.Equal(t, "sub_206", funcs[0].Name)
+	assert.Equal(t, uint64(3), funcs[0].Calls)
+	// Nested calls of the same function count their instructions once.
+	assert.Equal(t, funcs[0].Flat, funcs[0].Cum)
+	assert.Equal(t, uint64(11), funcs[0].Flat)
+}
@@ -0,0 +1,67 @@
+package profile
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+
+	"github.com/hermesdt/go-plan8/disasm"
+)
+
+func percent(n, total uint64) float64 {
+	if total == 0 {
+		return 0
+	}
+	return 100 * float64(n) / float64(total)
+}
+
+// WriteFlat prints the coverage, the functions and the instructions
+// executed.
+func (p *Profiler) WriteFlat(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	executed, total := p.Coverage()
+	fmt.Fprintf(bw, "instructions: %d\n", p.Total)
+	fmt.Fprintf(bw, "coverage: %d of %d instructions (%.1f%%)\n\n", executed, total, percent(uint64(executed), uint64(total)))
+
+	fmt.Fprintf(bw, "%10s %6s %10s %6s %8s  %s\n", "flat", "flat%", "cum", "cum%", "calls", "function")
+	for _, f := range p.Funcs() {
+		calls := fmt.Sprint(f.Calls)
+		if f.Entry == mainEntry && f.Calls == 0 {
+			calls = "-"
+		}
+		fmt.Fprintf(bw, "%10d %5.1f%% %10d %5.1f%% %8s  %s\n",
+			f.Flat, percent(f.Flat, p.Total), f.Cum, percent(f.Cum, p.Total), calls, f.Name)
+	}
+
+	fmt.Fprintf(bw, "\n%10s %6s  %s\n", "count", "%", "instruction")
+	for _, op := range p.OpCounts() {
+		fmt.Fprintf(bw, "%10d %5.1f%%  %s\n", op.Count, percent(op.Count, p.Total), op.Name)
+	}
+	return bw.Flush()
+}
+
+// WriteAnnotated prints the disassembled ROM with the executions of
+// each instruction, "." for code that never ran. Subroutines that were
+// called or named are labelled.
+func (p *Profiler) WriteAnnotated(w io.Writer, s disasm.Syntax) error {
+	bw := bufio.NewWriter(w)
+	for _, in := range p.disassemble() {
+		_, named := p.Names[in.Address]
+		if calls := p.Calls[in.Address]; calls > 0 || named || in.Address == mainEntry {
+			fmt.Fprintf(bw, "%s:", p.Name(in.Address))
+			if calls > 0 {
+				fmt.Fprintf(bw, " ; %d calls", calls)
+			}
+			fmt.Fprintln(bw)
+		}
+		count, pct := "", ""
+		if !in.IsData() {
+			count, pct = ".", "."
+			if n := p.Counts[in.Address]; n > 0 {
+				count, pct = fmt.Sprint(n), fmt.Sprintf("%.1f%%", percent(n, p.Total))
+			}
+		}
+		fmt.Fprintf(bw, "%10s %6s  %04X  %-9X %s\n", count, pct, in.Address, in.Bytes, in.Format(s))
+	}
+	return bw.Flush()
+}
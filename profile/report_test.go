@@ -0,0 +1,36 @@
+package profile
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/hermesdt/go-plan8/disasm"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWriteFlat(t *testing.T) {
+	p := run(t, 1+13*100)
+	var out bytes.Buffer
+
+	assert.Nil(t, p.WriteFlat(&out))
+
+	report := out.String()
+	assert.Contains(t, report, "instructions: 1301\ncoverage: 10 of 11 instructions (90.9%)\n")
+	assert.Contains(t, report, "       401  30.8%       1301 100.0%        -  main\n")
+	assert.Contains(t, report, "       300  23.1%        700  53.8%      100  sub_210\n")
+	assert.Contains(t, report, "       400  30.7%  CallSub\n")
+}
+
+func TestWriteAnnotated(t *testing.T) {
+	p := run(t, 1+13*100)
+	p.Names = map[uint16]string{0x20C: "inc"}
+	var out bytes.Buffer
+
+	assert.Nil(t, p.WriteAnnotated(&out, disasm.Cowgod))
+
+	listing := out.String()
+	assert.Contains(t, listing, "main:\n         1   0.1%  0200  6000      LD V0, 0x00\n")
+	assert.Contains(t, listing, "         .      .  0208  00E0      CLS\n")
+	assert.Contains(t, listing, "inc: ; 300 calls\n       300  23.1%  020C  7001      ADD V0, 0x01\n")
+	assert.Contains(t, listing, "sub_210: ; 100 calls\n")
+}
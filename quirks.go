@@ -0,0 +1,103 @@
+package chip8
+
+import (
+	"fmt"
+	"strings"
+)
+
+// IndexIncrement is how far FX55/FX65 move I after the transfer.
+type IndexIncrement uint8
+
+const (
+	IncrementNone IndexIncrement = iota
+	IncrementX
+	IncrementX1
+)
+
+// Quirks selects between the behaviours CHIP-8 interpreters disagree
+// on. The zero value shifts VX in place, leaves I alone after
+// FX55/FX65, jumps with BNNN, keeps VF on logic ops, wraps sprites and
+// draws without waiting.
+type Quirks struct {
+	// ShiftVY makes 8XY6/8XYE shift VY and store the result in VX.
+	ShiftVY bool
+	// LoadStore is the I increment applied by FX55/FX65.
+	LoadStore IndexIncrement
+	// JumpVX turns BNNN into BXNN, jumping to XNN + VX.
+	JumpVX bool
+	// VFReset makes 8XY1/8XY2/8XY3 clear VF.
+	VFReset bool
+	// Clip cuts sprites off at the screen edges instead of wrapping.
+	Clip bool
+	// DisplayWait makes DXYN wait for the next timer tick (vertical
+	// blank) before drawing.
+	DisplayWait bool
+	// CountCollisions makes DXYN in high resolution store the number of
+	// colliding or clipped rows in VF, as SUPER-CHIP 1.1 does.
+	CountCollisions bool
+}
+
+var (
+	QuirksVIP = Quirks{
+		ShiftVY:     true,
+		LoadStore:   IncrementX1,
+		VFReset:     true,
+		Clip:        true,
+		DisplayWait: true,
+	}
+	QuirksChip48 = Quirks{
+		LoadStore: IncrementX,
+		JumpVX:    true,
+		Clip:      true,
+	}
+	QuirksSChip11 = Quirks{
+		JumpVX:          true,
+		Clip:            true,
+		CountCollisions: true,
+	}
+	QuirksXOChip = Quirks{
+		ShiftVY:   true,
+		LoadStore: IncrementX1,
+	}
+	QuirksModern = Quirks{
+		Clip: true,
+	}
+)
+
+var quirkProfiles = []struct {
+	name   string
+	quirks Quirks
+}{
+	{"vip", QuirksVIP},
+	{"chip48", QuirksChip48},
+	{"schip", QuirksSChip11},
+	{"xochip", QuirksXOChip},
+	{"modern", QuirksModern},
+}
+
+func QuirkProfileNames() []string {
+	names := []string{}
+	for _, p := range quirkProfiles {
+		names = append(names, p.name)
+	}
+	return names
+}
+
+func LookupQuirks(name string) (Quirks, error) {
+	for _, p := range quirkProfiles {
+		if p.name == strings.ToLower(name) {
+			return p.quirks, nil
+		}
+	}
+	return Quirks{}, fmt.Errorf("chip8: unknown quirk profile %q", name)
+}
+
+// ProfileName returns the name of the preset q matches, or "custom".
+func (q Quirks) ProfileName() string {
+	for _, p := range quirkProfiles {
+		if p.quirks == q {
+			return p.name
+		}
+	}
+	return "custom"
+}
@@ -0,0 +1,136 @@
+package chip8
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLookupQuirks(t *testing.T) {
+	for _, name := range QuirkProfileNames() {
+		q, err := LookupQuirks(name)
+		assert.Nil(t, err)
+		assert.Equal(t, name, q.ProfileName())
+	}
+
+	q, err := LookupQuirks("VIP")
+	assert.Nil(t, err)
+	assert.Equal(t, QuirksVIP, q)
+
+	_, err = LookupQuirks("pdp-8")
+	assert.NotNil(t, err)
+	assert.Equal(t, "custom", Quirks{DisplayWait: true}.ProfileName())
+}
+
+func TestQuirks_shift(t *testing.T) {
+	o := Opcode{Value: 0x8126, Chip8: &Chip8{Quirks: QuirksVIP}}
+	o.Chip8.V[1] = 0x10
+	o.Chip8.V[2] = 0x03
+
+	o.Execute()
+
+	assert.Equal(t, uint8(0x01), o.Chip8.V[1])
+	assert.Equal(t, uint8(0x01), o.Chip8.V[0xF])
+
+	o = Opcode{Value: 0x812E, Chip8: &Chip8{Quirks: QuirksSChip11}}
+	o.Chip8.V[1] = 0x41
+	o.Chip8.V[2] = 0x81
+
+	o.Execute()
+
+	assert.Equal(t, uint8(0x82), o.Chip8.V[1])
+	assert.Equal(t, uint8(0x00), o.Chip8.V[0xF])
+}
+
+func TestQuirks_loadStoreIncrement(t *testing.T) {
+	cases := []struct {
+		quirks Quirks
+		i      uint16
+	}{
+		{QuirksVIP, 0x304},
+		{QuirksChip48, 0x303},
+		{QuirksSChip11, 0x300},
+		{QuirksXOChip, 0x304},
+	}
+	for _, c := range cases {
+		for _, op := range []uint16{0xF355, 0xF365} {
+			o := Opcode{Value: op, Chip8: &Chip8{I: 0x300, Quirks: c.quirks}}
+			o.Execute()
+			assert.Equal(t, c.i, o.Chip8.I, c.quirks.ProfileName())
+		}
+	}
+}
+
+func TestQuirks_jump(t *testing.T) {
+	o := Opcode{Value: 0xB220, Chip8: &Chip8{}}
+	o.Chip8.V[0] = 0x01
+	o.Chip8.V[2] = 0x10
+
+	o.Execute()
+	assert.Equal(t, uint16(0x221), o.Chip8.PC)
+
+	o.Chip8.Quirks = QuirksChip48
+	o.Execute()
+	assert.Equal(t, uint16(0x230), o.Chip8.PC)
+}
+
+func TestQuirks_vfReset(t *testing.T) {
+	for _, op := range []uint16{0x8121, 0x8122, 0x8123} {
+		o := Opcode{Value: op, Chip8: &Chip8{Quirks: QuirksVIP}}
+		o.Chip8.V[0xF] = 0x05
+		o.Execute()
+		assert.Equal(t, uint8(0), o.Chip8.V[0xF])
+
+		o = Opcode{Value: op, Chip8: &Chip8{Quirks: QuirksModern}}
+		o.Chip8.V[0xF] = 0x05
+		o.Execute()
+		assert.Equal(t, uint8(5), o.Chip8.V[0xF])
+	}
+}
+
+func TestQuirks_clip(t *testing.T) {
+	draw := func(q Quirks) *Chip8 {
+		o := Opcode{Value: 0xD012, Chip8: &Chip8{I: 0x300, Quirks: q}}
+		o.Chip8.V[0] = uint8(ScreenWidth - 4)
+		o.Chip8.V[1] = uint8(ScreenHeight - 1)
+		o.Chip8.Memory[0x300] = 0xFF
+		o.Chip8.Memory[0x301] = 0xFF
+		o.Execute()
+		return o.Chip8
+	}
+
+	c := draw(QuirksModern)
+	assert.True(t, c.Screen.Get(ScreenHeight-1, ScreenWidth-1))
+	assert.False(t, c.Screen.Get(ScreenHeight-1, 0))
+	assert.False(t, c.Screen.Get(0, ScreenWidth-1))
+
+	c = draw(QuirksXOChip)
+	assert.True(t, c.Screen.Get(ScreenHeight-1, 3))
+	assert.True(t, c.Screen.Get(0, ScreenWidth-1))
+	assert.True(t, c.Screen.Get(0, 3))
+}
+
+func TestQuirks_clipWrapsStartCoordinates(t *testing.T) {
+	o := Opcode{Value: 0xD011, Chip8: &Chip8{I: 0x300, Quirks: QuirksModern}}
+	o.Chip8.V[0] = uint8(ScreenWidth + 2)
+	o.Chip8.V[1] = uint8(ScreenHeight + 1)
+	o.Chip8.Memory[0x300] = 0x80
+
+	o.Execute()
+
+	assert.True(t, o.Chip8.Screen.Get(1, 2))
+}
+
+func TestQuirks_displayWait(t *testing.T) {
+	o := Opcode{Value: 0xD011, Chip8: &Chip8{I: 0x300, PC: 0x200, Quirks: QuirksVIP}}
+	o.Chip8.Memory[0x300] = 0x80
+
+	o.Execute()
+	assert.Equal(t, uint16(0x200), o.Chip8.PC)
+	assert.False(t, o.Chip8.Screen.Get(0, 0))
+
+	o.Chip8.TickTimers()
+	o.Execute()
+	assert.Equal(t, uint16(0x202), o.Chip8.PC)
+	assert.True(t, o.Chip8.Screen.Get(0, 0))
+}
@@ -0,0 +1,190 @@
+package chip8
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+)
+
+var ErrRewindUnavailable = errors.New("chip8: not enough rewind history")
+
+const (
+	DefaultRewindBytes    = 8 << 20
+	DefaultRewindPageSize = 64
+)
+
+type RewindConfig struct {
+	// MaxBytes bounds the memory held by recorded deltas. The oldest
+	// frames are dropped once it is exceeded.
+	MaxBytes int
+	// PageSize is the granularity, in bytes, at which changes between
+	// frames are detected and stored.
+	PageSize int
+}
+
+// Rewinder records a frame of machine state at a time into a bounded
+// history and restores earlier frames from it.
+//
+// It keeps a full copy of the latest recorded frame (CPU registers,
+// screen and memory, flattened into one byte slice) and, for every
+// frame, the previous contents of the pages that changed since the
+// frame before. Rewinding restores the latest copy and undoes pages
+// backwards until the wanted frame is reached.
+type Rewinder struct {
+	config  RewindConfig
+	latest  []byte
+	scratch bytes.Buffer
+	frames  []rewindFrame
+	size    int
+}
+
+type rewindFrame struct {
+	cycle uint64
+	frame uint64
+	undo  []rewindPage
+}
+
+type rewindPage struct {
+	offset int
+	data   []byte
+}
+
+func NewRewinder(config RewindConfig) *Rewinder {
+	if config.MaxBytes <= 0 {
+		config.MaxBytes = DefaultRewindBytes
+	}
+	if config.PageSize <= 0 {
+		config.PageSize = DefaultRewindPageSize
+	}
+	return &Rewinder{config: config}
+}
+
+// Len returns the number of frames that can be restored.
+func (r *Rewinder) Len() int {
+	return len(r.frames)
+}
+
+// Size returns the bytes held by recorded deltas.
+func (r *Rewinder) Size() int {
+	return r.size
+}
+
+func (r *Rewinder) Reset() {
+	r.latest = nil
+	r.frames = nil
+	r.size = 0
+}
+
+func (r *Rewinder) Record(c *Chip8, cycle, frame uint64) {
+	r.scratch.Reset()
+	c.encodeFrame(&r.scratch)
+	current := r.scratch.Bytes()
+
+	if len(current) != len(r.latest) {
+		r.Reset()
+		r.latest = append([]byte(nil), current...)
+		r.frames = append(r.frames, rewindFrame{cycle: cycle, frame: frame})
+		return
+	}
+
+	f := rewindFrame{cycle: cycle, frame: frame}
+	page := r.config.PageSize
+	for off := 0; off < len(current); off += page {
+		end := off + page
+		if end > len(current) {
+			end = len(current)
+		}
+		if bytes.Equal(current[off:end], r.latest[off:end]) {
+			continue
+		}
+		f.undo = append(f.undo, rewindPage{off, append([]byte(nil), r.latest[off:end]...)})
+		copy(r.latest[off:end], current[off:end])
+		r.size += end - off
+	}
+	r.frames = append(r.frames, f)
+
+	for r.size > r.config.MaxBytes && len(r.frames) > 1 {
+		r.frames = r.frames[1:]
+		r.size -= r.frames[0].undoSize()
+		r.frames[0].undo = nil
+	}
+}
+
+func (f rewindFrame) undoSize() int {
+	n := 0
+	for _, p := range f.undo {
+		n += len(p.data)
+	}
+	return n
+}
+
+// Rewind restores c to the frame recorded frames before the latest one,
+// dropping the history after it, and returns its cycle and frame count.
+func (r *Rewinder) Rewind(c *Chip8, frames int) (cycle, frame uint64, err error) {
+	target := len(r.frames) - 1 - frames
+	if frames < 0 || target < 0 {
+		return 0, 0, ErrRewindUnavailable
+	}
+	return r.restore(c, target)
+}
+
+// RewindTo restores c to the latest recorded frame at or before cycle.
+func (r *Rewinder) RewindTo(c *Chip8, cycle uint64) (uint64, uint64, error) {
+	for i := len(r.frames) - 1; i >= 0; i-- {
+		if r.frames[i].cycle <= cycle {
+			return r.restore(c, i)
+		}
+	}
+	return 0, 0, ErrRewindUnavailable
+}
+
+func (r *Rewinder) restore(c *Chip8, target int) (uint64, uint64, error) {
+	for i := len(r.frames) - 1; i > target; i-- {
+		for _, p := range r.frames[i].undo {
+			copy(r.latest[p.offset:], p.data)
+		}
+		r.size -= r.frames[i].undoSize()
+	}
+	r.frames = r.frames[:target+1]
+	if err := c.decodeFrame(r.latest); err != nil {
+		return 0, 0, err
+	}
+	f := r.frames[target]
+	return f.cycle, f.frame, nil
+}
+
+// encodeFrame flattens the state that changes while a program runs:
+// registers, screen, memory and the random source, whose length ends
+// the frame.
+func (c *Chip8) encodeFrame(b *bytes.Buffer) {
+	c.encodeCPU(b)
+	c.encodeScreen(b)
+	c.encodeMemory(b)
+	n := b.Len()
+	c.encodeRandom(b)
+	binary.Write(b, binary.BigEndian, uint32(b.Len()-n))
+}
+
+func (c *Chip8) decodeFrame(data []byte) error {
+	cpu := binary.Size(&cpuState{})
+	screen := 2 + Planes*len(c.Screen.px[0])/8
+	if len(data) < cpu+screen+4 {
+		return ErrInvalidState
+	}
+	data, n := data[:len(data)-4], int(binary.BigEndian.Uint32(data[len(data)-4:]))
+	if n > len(data)-cpu-screen {
+		return ErrInvalidState
+	}
+	data, random := data[:len(data)-n], data[len(data)-n:]
+	if err := c.decodeCPU(data[:cpu]); err != nil {
+		return err
+	}
+	if err := c.decodeScreen(data[cpu : cpu+screen]); err != nil {
+		return err
+	}
+	if err := c.decodeRandom(random); err != nil {
+		return err
+	}
+	c.keyRelease = nil
+	return c.decodeMemory(data[cpu+screen:])
+}
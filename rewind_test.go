@@ -0,0 +1,102 @@
+package chip8
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// newCounterMachine runs a ROM that increments V0 and stores it at
+// 0x300 + V0 forever, so every frame leaves a different state.
+func newCounterMachine(config RewindConfig) (*Machine, *FakeClock) {
+	c := NewChip8(VariantChip8)
+	c.LoadRomBytes([]byte{
+		0x70, 0x01, // ADD V0, 1
+		0xA3, 0x00, // LD I, 0x300
+		0xF0, 0x1E, // ADD I, V0
+		0xF0, 0x55, // LD [I], V0
+		0x12, 0x00, // JP 0x200
+	})
+	clock := NewFakeClock(time.Unix(0, 0))
+	m := NewMachine(c)
+	m.Clock = clock
+	m.InstructionsPerSecond = 600
+	m.Rewinder = NewRewinder(config)
+	m.Update()
+	return m, clock
+}
+
+func runFrames(m *Machine, clock *FakeClock, n int) {
+	for i := 0; i < n; i++ {
+		clock.Advance(time.Second / TimerFrequency)
+		m.Update()
+	}
+}
+
+func TestMachineRewind(t *testing.T) {
+	m, clock := newCounterMachine(RewindConfig{})
+	frame10 := NewChip8(VariantChip8)
+	m.OnFrame = func(c *Chip8) {
+		if m.Frames == 10 {
+			*frame10 = *c
+		}
+	}
+	runFrames(m, clock, 11)
+	assert.Equal(t, uint64(10), m.Frames)
+	assert.Equal(t, 10, m.Rewinder.Len())
+
+	runFrames(m, clock, 5)
+	assert.Equal(t, uint64(15), m.Frames)
+
+	assert.Nil(t, m.Rewind(5))
+	assert.Equal(t, uint64(10), m.Frames)
+	assert.Equal(t, frame10.V, m.Chip8.V)
+	assert.Equal(t, frame10.Memory, m.Chip8.Memory)
+	assert.Equal(t, frame10.PC, m.Chip8.PC)
+	assert.Equal(t, uint64(10*10), m.Cycles)
+
+	assert.Nil(t, m.Rewind(2))
+	assert.Equal(t, uint64(8), m.Frames)
+	assert.Equal(t, uint8(16), m.Chip8.V[0])
+	assert.Equal(t, uint8(16), m.Chip8.Memory[0x300+16])
+	assert.Equal(t, uint8(0), m.Chip8.Memory[0x300+17])
+}
+
+func TestMachineRewindTo(t *testing.T) {
+	m, clock := newCounterMachine(RewindConfig{})
+	runFrames(m, clock, 20)
+
+	assert.Nil(t, m.RewindTo(55))
+	assert.Equal(t, uint64(50), m.Cycles)
+	assert.Equal(t, uint64(5), m.Frames)
+	assert.Equal(t, uint8(10), m.Chip8.V[0])
+
+	assert.Equal(t, ErrRewindUnavailable, m.RewindTo(5))
+	assert.Equal(t, ErrRewindUnavailable, m.Rewind(10))
+}
+
+func TestRewinder_boundedMemory(t *testing.T) {
+	m, clock := newCounterMachine(RewindConfig{MaxBytes: 1024, PageSize: 16})
+	runFrames(m, clock, 300)
+
+	assert.True(t, m.Rewinder.Size() <= 1024)
+	assert.True(t, m.Rewinder.Len() < 300)
+	assert.Nil(t, m.Rewind(m.Rewinder.Len()-1))
+	assert.Equal(t, ErrRewindUnavailable, m.Rewind(1))
+}
+
+func TestRewind_noRewinder(t *testing.T) {
+	m := NewMachine(NewChip8(VariantChip8))
+
+	assert.Equal(t, ErrRewindUnavailable, m.Rewind(1))
+}
+
+func BenchmarkRewinderRecord(b *testing.B) {
+	c := NewChip8(VariantXOChip)
+	r := NewRewinder(RewindConfig{})
+	for i := 0; i < b.N; i++ {
+		c.Memory[0x300+i%0x100]++
+		r.Record(c, uint64(i), uint64(i))
+	}
+}
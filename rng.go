@@ -0,0 +1,302 @@
+package chip8
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math/rand"
+	"strings"
+)
+
+// RandomSource supplies the random bytes of CXNN. Sources are
+// deterministic from their state, which save states and rewind frames
+// include, so a restored machine draws the same bytes again.
+type RandomSource interface {
+	Kind() RandomKind
+	// Next returns the next byte. It may read the machine.
+	Next(c *Chip8) uint8
+	encoding.BinaryMarshaler
+	encoding.BinaryUnmarshaler
+}
+
+// RandomKind names a RandomSource strategy.
+type RandomKind uint8
+
+const (
+	RandomGo RandomKind = iota
+	RandomXorshift
+	RandomReplay
+	RandomVIP
+)
+
+var ErrInvalidRandom = errors.New("chip8: invalid random source state")
+
+func (k RandomKind) String() string {
+	switch k {
+	case RandomGo:
+		return "go"
+	case RandomXorshift:
+		return "xorshift"
+	case RandomReplay:
+		return "replay"
+	case RandomVIP:
+		return "vip"
+	}
+	return fmt.Sprintf("random(%d)", uint8(k))
+}
+
+func LookupRandom(name string) (RandomKind, error) {
+	for _, k := range []RandomKind{RandomGo, RandomXorshift, RandomReplay, RandomVIP} {
+		if k.String() == strings.ToLower(name) {
+			return k, nil
+		}
+	}
+	return 0, fmt.Errorf("chip8: unknown random source %q", name)
+}
+
+// NewRandom returns a source of kind seeded with seed. Replay sources
+// have no seed and start empty, see NewReplayRandom, and VIP sources
+// need the interpreter image, see NewVIPRandom.
+func NewRandom(kind RandomKind, seed int64) (RandomSource, error) {
+	switch kind {
+	case RandomGo:
+		return NewGoRandom(seed), nil
+	case RandomXorshift:
+		return NewXorshiftRandom(seed), nil
+	case RandomReplay:
+		return NewReplayRandom(nil), nil
+	case RandomVIP:
+		return nil, ErrVIPInterpreter
+	}
+	return nil, fmt.Errorf("chip8: unknown random source %v", kind)
+}
+
+// emptyRandom returns a source of kind to unmarshal a state into.
+func emptyRandom(kind RandomKind) RandomSource {
+	switch kind {
+	case RandomGo:
+		return NewGoRandom(0)
+	case RandomXorshift:
+		return NewXorshiftRandom(0)
+	case RandomReplay:
+		return NewReplayRandom(nil)
+	case RandomVIP:
+		return &VIPRandom{}
+	}
+	return nil
+}
+
+// GoRandom draws through math/rand from a SplitMix64 source, whose
+// state is a single word, so it saves and restores as is.
+type GoRandom struct {
+	src splitMix64
+	rng *rand.Rand
+}
+
+func NewGoRandom(seed int64) *GoRandom {
+	r := &GoRandom{src: splitMix64(seed)}
+	r.rng = rand.New(&r.src)
+	return r
+}
+
+func (r *GoRandom) Kind() RandomKind { return RandomGo }
+
+func (r *GoRandom) Next(*Chip8) uint8 {
+	return uint8(r.rng.Intn(256))
+}
+
+func (r *GoRandom) MarshalBinary() ([]byte, error) {
+	b := make([]byte, 8)
+	binary.BigEndian.PutUint64(b, uint64(r.src))
+	return b, nil
+}
+
+func (r *GoRandom) UnmarshalBinary(data []byte) error {
+	if len(data) != 8 {
+		return ErrInvalidRandom
+	}
+	*r = *NewGoRandom(int64(binary.BigEndian.Uint64(data)))
+	return nil
+}
+
+// splitMix64 is a rand.Source64 whose state is its value.
+type splitMix64 uint64
+
+func (s *splitMix64) Seed(seed int64) { *s = splitMix64(seed) }
+
+func (s *splitMix64) Uint64() uint64 {
+	*s += 0x9E3779B97F4A7C15
+	z := uint64(*s)
+	z = (z ^ z>>30) * 0xBF58476D1CE4E5B9
+	z = (z ^ z>>27) * 0x94D049BB133111EB
+	return z ^ z>>31
+}
+
+func (s *splitMix64) Int63() int64 { return int64(s.Uint64() >> 1) }
+
+// XorshiftRandom is Marsaglia's xorshift32, returning the high byte of
+// each step.
+type XorshiftRandom struct {
+	State uint32
+}
+
+// xorshiftZero replaces a zero seed, which xorshift never leaves.
+const xorshiftZero = 0x9E3779B9
+
+func NewXorshiftRandom(seed int64) *XorshiftRandom {
+	s := uint32(seed) ^ uint32(seed>>32)
+	if s == 0 {
+		s = xorshiftZero
+	}
+	return &XorshiftRandom{State: s}
+}
+
+func (r *XorshiftRandom) Kind() RandomKind { return RandomXorshift }
+
+func (r *XorshiftRandom) Next(*Chip8) uint8 {
+	x := r.State
+	x ^= x << 13
+	x ^= x >> 17
+	x ^= x << 5
+	r.State = x
+	return uint8(x >> 24)
+}
+
+func (r *XorshiftRandom) MarshalBinary() ([]byte, error) {
+	b := make([]byte, 4)
+	binary.BigEndian.PutUint32(b, r.State)
+	return b, nil
+}
+
+func (r *XorshiftRandom) UnmarshalBinary(data []byte) error {
+	if len(data) != 4 || binary.BigEndian.Uint32(data) == 0 {
+		return ErrInvalidRandom
+	}
+	r.State = binary.BigEndian.Uint32(data)
+	return nil
+}
+
+// ReplayRandom returns recorded bytes in order, starting over once they
+// run out. An empty recording returns zeros.
+type ReplayRandom struct {
+	Bytes []uint8
+	Pos   int
+}
+
+func NewReplayRandom(bytes []uint8) *ReplayRandom {
+	return &ReplayRandom{Bytes: bytes}
+}
+
+func (r *ReplayRandom) Kind() RandomKind { return RandomReplay }
+
+func (r *ReplayRandom) Next(*Chip8) uint8 {
+	if len(r.Bytes) == 0 {
+		return 0
+	}
+	if r.Pos >= len(r.Bytes) {
+		r.Pos = 0
+	}
+	b := r.Bytes[r.Pos]
+	r.Pos++
+	return b
+}
+
+func (r *ReplayRandom) MarshalBinary() ([]byte, error) {
+	b := make([]byte, 4, 4+len(r.Bytes))
+	binary.BigEndian.PutUint32(b, uint32(r.Pos))
+	return append(b, r.Bytes...), nil
+}
+
+func (r *ReplayRandom) UnmarshalBinary(data []byte) error {
+	if len(data) < 4 || int(binary.BigEndian.Uint32(data)) > len(data)-4 {
+		return ErrInvalidRandom
+	}
+	r.Pos = int(binary.BigEndian.Uint32(data))
+	r.Bytes = append([]uint8(nil), data[4:]...)
+	return nil
+}
+
+// VIPRandom follows the COSMAC VIP interpreter, which keeps its
+// generator in register R9: each draw increments R9, adds the byte at
+// 0x100 plus its low byte, in the interpreter's own page, to its high
+// byte, and returns the sum, which becomes the new high byte. Page
+// holds that page of the interpreter image, which is not distributed
+// with this package; see NewVIPRandom.
+type VIPRandom struct {
+	R9   uint16
+	Page [vipPageSize]uint8
+}
+
+const (
+	vipPage     = 0x100
+	vipPageSize = 0x100
+)
+
+var ErrVIPInterpreter = errors.New("chip8: the vip random source needs the 512 byte VIP interpreter image")
+
+// NewVIPRandom returns a VIPRandom seeded with seed that draws from the
+// second page of interpreter, a dump of the VIP's 0x000-0x1FF.
+func NewVIPRandom(seed int64, interpreter []uint8) (*VIPRandom, error) {
+	if len(interpreter) != vipPage+vipPageSize {
+		return nil, ErrVIPInterpreter
+	}
+	r := &VIPRandom{R9: uint16(seed)}
+	copy(r.Page[:], interpreter[vipPage:])
+	return r, nil
+}
+
+func (r *VIPRandom) Kind() RandomKind { return RandomVIP }
+
+func (r *VIPRandom) Next(*Chip8) uint8 {
+	r.R9++
+	b := r.Page[r.R9&0xFF] + uint8(r.R9>>8)
+	r.R9 = uint16(b)<<8 | r.R9&0xFF
+	return b
+}
+
+func (r *VIPRandom) MarshalBinary() ([]byte, error) {
+	b := make([]byte, 2, 2+vipPageSize)
+	binary.BigEndian.PutUint16(b, r.R9)
+	return append(b, r.Page[:]...), nil
+}
+
+func (r *VIPRandom) UnmarshalBinary(data []byte) error {
+	if len(data) != 2+vipPageSize {
+		return ErrInvalidRandom
+	}
+	r.R9 = binary.BigEndian.Uint16(data)
+	copy(r.Page[:], data[2:])
+	return nil
+}
+
+// encodeRandom writes the kind of c.Random and its state, or nothing
+// without a source.
+func (c *Chip8) encodeRandom(b *bytes.Buffer) {
+	if c.Random == nil {
+		return
+	}
+	state, _ := c.Random.MarshalBinary()
+	b.WriteByte(uint8(c.Random.Kind()))
+	b.Write(state)
+}
+
+// decodeRandom restores the source written by encodeRandom, reusing
+// c.Random when it is of the same kind. Empty data keeps c.Random.
+func (c *Chip8) decodeRandom(data []byte) error {
+	if len(data) == 0 {
+		return nil
+	}
+	src := c.Random
+	if src == nil || src.Kind() != RandomKind(data[0]) {
+		if src = emptyRandom(RandomKind(data[0])); src == nil {
+			return ErrInvalidState
+		}
+	}
+	if err := src.UnmarshalBinary(data[1:]); err != nil {
+		return ErrInvalidState
+	}
+	c.Random = src
+	return nil
+}
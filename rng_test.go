@@ -0,0 +1,166 @@
+package chip8
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func draw(r RandomSource, c *Chip8, n int) []uint8 {
+	out := make([]uint8, n)
+	for i := range out {
+		out[i] = r.Next(c)
+	}
+	return out
+}
+
+func TestLookupRandom(t *testing.T) {
+	for _, k := range []RandomKind{RandomGo, RandomXorshift, RandomReplay, RandomVIP} {
+		got, err := LookupRandom(k.String())
+		assert.Nil(t, err)
+		assert.Equal(t, k, got)
+	}
+
+	_, err := LookupRandom("dice")
+	assert.NotNil(t, err)
+}
+
+// testInterpreter stands in for the VIP interpreter image.
+func testInterpreter() []uint8 {
+	image := make([]uint8, 0x200)
+	for i := range image {
+		image[i] = uint8(i*37 + 11)
+	}
+	return image
+}
+
+func TestRandom_seeded(t *testing.T) {
+	c := NewChip8(VariantChip8)
+	for _, k := range []RandomKind{RandomGo, RandomXorshift} {
+		a, _ := NewRandom(k, 42)
+		b, _ := NewRandom(k, 42)
+		assert.Equal(t, draw(a, c, 16), draw(b, c, 16), "%v", k)
+	}
+	_, err := NewRandom(RandomVIP, 42)
+	assert.Equal(t, ErrVIPInterpreter, err)
+
+	a, b := NewGoRandom(1), NewGoRandom(2)
+	assert.NotEqual(t, draw(a, c, 16), draw(b, c, 16))
+}
+
+func TestRandom_marshal(t *testing.T) {
+	c := NewChip8(VariantChip8)
+	vip, _ := NewVIPRandom(7, testInterpreter())
+	for _, src := range []RandomSource{NewGoRandom(7), NewXorshiftRandom(7), vip} {
+		k := src.Kind()
+		draw(src, c, 5)
+		state, err := src.MarshalBinary()
+		assert.Nil(t, err)
+		want := draw(src, c, 8)
+
+		restored := emptyRandom(k)
+		assert.Nil(t, restored.UnmarshalBinary(state), "%v", k)
+		assert.Equal(t, want, draw(restored, c, 8), "%v", k)
+		assert.NotNil(t, restored.UnmarshalBinary([]byte{1}), "%v", k)
+	}
+}
+
+func TestGoRandom_compactState(t *testing.T) {
+	r := NewGoRandom(3)
+	draw(r, nil, 100000)
+	state, _ := r.MarshalBinary()
+
+	assert.Len(t, state, 8)
+}
+
+func TestXorshiftRandom_zeroSeed(t *testing.T) {
+	r := NewXorshiftRandom(0)
+
+	assert.Equal(t, uint32(xorshiftZero), r.State)
+	assert.NotEqual(t, []uint8{0, 0, 0, 0}, draw(r, nil, 4))
+}
+
+func TestReplayRandom(t *testing.T) {
+	r := NewReplayRandom([]uint8{1, 2, 3})
+	assert.Equal(t, []uint8{1, 2, 3, 1, 2}, draw(r, nil, 5))
+
+	state, _ := r.MarshalBinary()
+	restored := NewReplayRandom(nil)
+	assert.Nil(t, restored.UnmarshalBinary(state))
+	assert.Equal(t, []uint8{3, 1}, draw(restored, nil, 2))
+	assert.Equal(t, ErrInvalidRandom, restored.UnmarshalBinary([]byte{0, 0, 0, 9, 1}))
+
+	assert.Equal(t, []uint8{0, 0}, draw(NewReplayRandom(nil), nil, 2))
+}
+
+func TestVIPRandom(t *testing.T) {
+	image := make([]uint8, 0x200)
+	image[0x101] = 0x10
+	image[0x102] = 0x05
+	r, err := NewVIPRandom(0, image)
+	assert.Nil(t, err)
+	c := NewChip8(VariantChip8)
+	c.Memory[0x101] = 0xFF
+
+	assert.Equal(t, uint8(0x10), r.Next(c))
+	assert.Equal(t, uint16(0x1001), r.R9)
+	assert.Equal(t, uint8(0x15), r.Next(c))
+	assert.Equal(t, uint16(0x1502), r.R9)
+
+	_, err = NewVIPRandom(0, image[:0x100])
+	assert.Equal(t, ErrVIPInterpreter, err)
+}
+
+func TestSaveLoadState_random(t *testing.T) {
+	c := NewChip8(VariantChip8)
+	c.Random = NewXorshiftRandom(3)
+	draw(c.Random, c, 3)
+	var buf bytes.Buffer
+	assert.Nil(t, c.SaveState(&buf))
+	want := draw(c.Random, c, 8)
+
+	restored := NewChip8(VariantChip8)
+	assert.Nil(t, restored.LoadState(bytes.NewReader(buf.Bytes())))
+
+	assert.Equal(t, RandomXorshift, restored.Random.Kind())
+	assert.Equal(t, want, draw(restored.Random, restored, 8))
+}
+
+func TestLoadState_keepsRandomWithoutChunk(t *testing.T) {
+	var head bytes.Buffer
+	NewChip8(VariantChip8).encodeHeader(&head)
+	c := NewChip8(VariantChip8)
+	src := NewXorshiftRandom(9)
+	c.Random = src
+
+	assert.Nil(t, c.LoadState(bytes.NewReader(writeState(1, map[string][]byte{chunkHeader: head.Bytes()}, []string{chunkHeader}))))
+	assert.Equal(t, RandomSource(src), c.Random)
+
+	bad := writeState(1, map[string][]byte{chunkRandom: {0xFF}}, []string{chunkRandom})
+	assert.Equal(t, ErrInvalidState, c.LoadState(bytes.NewReader(bad)))
+}
+
+func TestMachineRewind_random(t *testing.T) {
+	c := NewChip8(VariantChip8)
+	c.Random = NewGoRandom(5)
+	c.LoadRomBytes([]byte{
+		0xC0, 0xFF, // RND V0, 0xFF
+		0x12, 0x00, // JP 0x200
+	})
+	m := NewMachine(c)
+	m.InstructionsPerSecond = 600
+	m.Rewinder = NewRewinder(RewindConfig{})
+	m.Emulate(50)
+	var frames []uint8
+	m.OnFrame = func(c *Chip8) { frames = append(frames, c.V[0]) }
+
+	m.Emulate(20)
+	first := frames
+	assert.Nil(t, m.Rewind(2))
+	frames = nil
+	m.Emulate(20)
+
+	assert.Len(t, first, 2)
+	assert.Equal(t, first, frames)
+}
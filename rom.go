@@ -1,24 +1,35 @@
 package chip8
 
 import (
-	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	"io/ioutil"
-	"log"
 )
 
-func (c *Chip8) LoadRom(filename string) {
+const RomStart = 0x200
+
+// RomHash identifies a ROM by the SHA-256 of its contents.
+type RomHash [sha256.Size]byte
+
+func (h RomHash) String() string {
+	return hex.EncodeToString(h[:])
+}
+
+func (c *Chip8) LoadRom(filename string) error {
 	bs, err := ioutil.ReadFile(filename)
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
+	return c.LoadRomBytes(bs)
+}
 
-	rom := c.Memory[0x200:]
-	write := bytes.NewBuffer(rom)
-	write.Reset()
-	n, err := write.Write(bs)
-	if err != nil {
-		log.Fatal(err)
+func (c *Chip8) LoadRomBytes(bs []byte) error {
+	rom := c.Memory[RomStart:c.Variant.MemorySize()]
+	if len(bs) > len(rom) {
+		return fmt.Errorf("%w: %d bytes, %d available", ErrRomTooLarge, len(bs), len(rom))
 	}
-	fmt.Printf("copied %d bytes\n", n)
+	copy(rom, bs)
+	c.RomHash = sha256.Sum256(bs)
+	return nil
 }
@@ -0,0 +1,179 @@
+// Package runner runs programs without a window or terminal, for
+// batches of ROMs in CI: in emulated time, with seeded randomness and
+// scripted keys, until a frame count or a stop condition, reporting the
+// final state as JSON.
+package runner
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	chip8 "github.com/hermesdt/go-plan8"
+)
+
+// Reason is why a run stopped.
+type Reason string
+
+const (
+	StopFrames Reason = "frames"
+	StopPC     Reason = "pc"
+	StopMemory Reason = "memory"
+	// StopLoop is a jump to itself, which programs use to halt.
+	StopLoop  Reason = "loop"
+	StopExit  Reason = "exit"
+	StopError Reason = "error"
+)
+
+// MemoryCondition stops a run once the byte at Addr holds Value.
+type MemoryCondition struct {
+	Addr  uint16
+	Value uint8
+}
+
+// ParseMemoryCondition parses a hex address and value, as "300=FF".
+func ParseMemoryCondition(s string) (MemoryCondition, error) {
+	parts := strings.SplitN(s, "=", 2)
+	if len(parts) == 2 {
+		addr, err1 := strconv.ParseUint(parts[0], 16, 16)
+		value, err2 := strconv.ParseUint(parts[1], 16, 8)
+		if err1 == nil && err2 == nil {
+			return MemoryCondition{uint16(addr), uint8(value)}, nil
+		}
+	}
+	return MemoryCondition{}, fmt.Errorf("runner: bad memory condition %q", s)
+}
+
+// Runner runs a Chip8 with its program loaded. Runs with the same
+// settings give the same results.
+type Runner struct {
+	Chip8 *chip8.Chip8
+	// Frames is the most timer frames to run.
+	Frames                uint64
+	InstructionsPerSecond int
+	// Seed seeds the random bytes of CXNN.
+	Seed int64
+	// Random, when set, replaces the RandomGo source seeded with Seed.
+	Random      chip8.RandomSource
+	UntilPC     []uint16
+	UntilMemory []MemoryCondition
+	// StopOnLoop stops at a jump to itself.
+	StopOnLoop bool
+	Script     Script
+}
+
+// DefaultFrames is ten seconds of frames.
+const DefaultFrames = 10 * chip8.TimerFrequency
+
+func New(c *chip8.Chip8) *Runner {
+	return &Runner{
+		Chip8:                 c,
+		Frames:                DefaultFrames,
+		InstructionsPerSecond: chip8.DefaultInstrPerSec,
+		StopOnLoop:            true,
+	}
+}
+
+// Result is the state a run ended in.
+type Result struct {
+	Reason Reason `json:"reason"`
+	Error  string `json:"error,omitempty"`
+	Cycles uint64 `json:"cycles"`
+	Frames uint64 `json:"frames"`
+
+	PC uint16    `json:"pc"`
+	I  uint16    `json:"i"`
+	SP uint16    `json:"sp"`
+	V  [16]uint8 `json:"v"`
+	DT uint8     `json:"dt"`
+	ST uint8     `json:"st"`
+
+	// Screen has a row of pixel values, 0 to 3, per screen line.
+	Screen     []string `json:"screen"`
+	ScreenHash string   `json:"screen_hash"`
+}
+
+// Run runs the program until it stops.
+func (r *Runner) Run() *Result {
+	c := r.Chip8
+	c.Random = r.Random
+	if c.Random == nil {
+		c.Random = chip8.NewGoRandom(r.Seed)
+	}
+	m := chip8.NewMachine(c)
+	m.InstructionsPerSecond = r.InstructionsPerSecond
+	script := r.Script.sorted()
+
+	reason := StopFrames
+	var err error
+	for m.Frames < r.Frames {
+		for len(script) > 0 && script[0].Frame <= m.Frames {
+			script[0].apply(c.Key)
+			script = script[1:]
+		}
+		if reason = r.stopped(c); reason != "" {
+			break
+		}
+		if err = m.Emulate(1); err != nil {
+			reason = StopError
+			if errors.Is(err, chip8.ErrExit) {
+				reason, err = StopExit, nil
+			}
+			break
+		}
+		reason = StopFrames
+	}
+	return r.result(m, reason, err)
+}
+
+// stopped checks the conditions before the instruction at PC runs.
+func (r *Runner) stopped(c *chip8.Chip8) Reason {
+	for _, pc := range r.UntilPC {
+		if c.PC == pc {
+			return StopPC
+		}
+	}
+	for _, mc := range r.UntilMemory {
+		if c.Memory[mc.Addr] == mc.Value {
+			return StopMemory
+		}
+	}
+	if r.StopOnLoop {
+		if op := c.FetchOpcode().Value; op&0xF000 == 0x1000 && op&0x0FFF == c.PC {
+			return StopLoop
+		}
+	}
+	return ""
+}
+
+func (r *Runner) result(m *chip8.Machine, reason Reason, err error) *Result {
+	c := m.Chip8
+	res := &Result{
+		Reason: reason,
+		Cycles: m.Cycles,
+		Frames: m.Frames,
+		PC:     c.PC,
+		I:      c.I,
+		SP:     c.SP,
+		V:      c.V,
+		DT:     c.DelayTimer,
+		ST:     c.SoundTimer,
+	}
+	if err != nil {
+		res.Error = err.Error()
+	}
+	s := &c.Screen
+	for y := 0; y < s.Height(); y++ {
+		var row strings.Builder
+		for x := 0; x < s.Width(); x++ {
+			row.WriteByte('0' + s.Pixel(y, x))
+		}
+		res.Screen = append(res.Screen, row.String())
+	}
+	sum := sha256.Sum256([]byte(strings.Join(res.Screen, "\n")))
+	res.ScreenHash = hex.EncodeToString(sum[:])
+	return res
+}
@@ -0,0 +1,129 @@
+package runner
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	chip8 "github.com/hermesdt/go-plan8"
+	"github.com/hermesdt/go-plan8/asm"
+	"github.com/stretchr/testify/assert"
+)
+
+func newRunner(t *testing.T, src string, variant chip8.Variant) *Runner {
+	rom, err := asm.Assemble(src, variant)
+	assert.Nil(t, err)
+	c := chip8.NewChip8(variant)
+	assert.Nil(t, c.LoadRomBytes(rom))
+	return New(c)
+}
+
+const counter = `
+	LD I, 0x300
+loop:
+	ADD V0, 1       ; 202
+	LD B, V0        ; 204
+	JP loop         ; 206
+`
+
+func TestRunFrames(t *testing.T) {
+	r := newRunner(t, counter, chip8.VariantChip8)
+	r.Frames = 3
+
+	res := r.Run()
+
+	assert.Equal(t, StopFrames, res.Reason)
+	assert.Equal(t, uint64(3), res.Frames)
+	// 700 instructions a second are 11 2/3 a frame.
+	assert.Equal(t, uint64(35), res.Cycles)
+	assert.Len(t, res.Screen, 32)
+	assert.Equal(t, strings.Repeat("0", 64), res.Screen[0])
+	assert.Len(t, res.ScreenHash, 64)
+}
+
+func TestRunConditions(t *testing.T) {
+	r := newRunner(t, counter, chip8.VariantChip8)
+	r.UntilMemory = []MemoryCondition{{0x302, 7}}
+	res := r.Run()
+	assert.Equal(t, StopMemory, res.Reason)
+	assert.Equal(t, uint8(7), res.V[0])
+	assert.Equal(t, uint16(0x206), res.PC)
+
+	r = newRunner(t, counter, chip8.VariantChip8)
+	r.UntilPC = []uint16{0x204}
+	res = r.Run()
+	assert.Equal(t, StopPC, res.Reason)
+	assert.Equal(t, uint64(2), res.Cycles)
+
+	r = newRunner(t, "LD V3, 9\nend: JP end", chip8.VariantChip8)
+	res = r.Run()
+	assert.Equal(t, StopLoop, res.Reason)
+	assert.Equal(t, uint64(1), res.Cycles)
+	assert.Equal(t, uint8(9), res.V[3])
+
+	r = newRunner(t, "LD V3, 9\nEXIT", chip8.VariantSChip)
+	res = r.Run()
+	assert.Equal(t, StopExit, res.Reason)
+	assert.Equal(t, "", res.Error)
+
+	r = newRunner(t, "RET", chip8.VariantChip8)
+	res = r.Run()
+	assert.Equal(t, StopError, res.Reason)
+	assert.Contains(t, res.Error, "stack underflow")
+}
+
+func TestRunSeeded(t *testing.T) {
+	const src = `
+		RND V0, 0xFF
+		RND V1, 0xFF
+		RND V2, 0xFF
+	end:
+		JP end
+	`
+	run := func(seed int64) *Result {
+		r := newRunner(t, src, chip8.VariantChip8)
+		r.Seed = seed
+		return r.Run()
+	}
+
+	assert.Equal(t, run(1).V, run(1).V)
+	assert.NotEqual(t, run(1).V, run(2).V)
+
+	r := newRunner(t, src, chip8.VariantChip8)
+	r.Random = chip8.NewReplayRandom([]uint8{0x12, 0x34})
+	assert.Equal(t, [3]uint8{0x12, 0x34, 0x12}, [3]uint8{r.Run().V[0], r.Chip8.V[1], r.Chip8.V[2]})
+}
+
+func TestRunScript(t *testing.T) {
+	// Draws the digit of each key typed.
+	r := newRunner(t, `
+		LD V0, K
+		LD F, V0
+		DRW V1, V1, 5
+	end:
+		JP end
+	`, chip8.VariantChip8)
+	r.Script = Script{{Frame: 3, Key: 0xA, Down: false}, {Frame: 2, Key: 0xA, Down: true}}
+
+	res := r.Run()
+
+	assert.Equal(t, StopLoop, res.Reason)
+	assert.Equal(t, uint8(0xA), res.V[0])
+	assert.Equal(t, uint64(3), res.Frames)
+	assert.Equal(t, "1111", res.Screen[0][:4])
+
+	out, err := json.Marshal(res)
+	assert.Nil(t, err)
+	assert.Contains(t, string(out), `"reason":"loop","cycles":`)
+	assert.Contains(t, string(out), `"v":[10,0,`)
+}
+
+func TestParseMemoryCondition(t *testing.T) {
+	mc, err := ParseMemoryCondition("3FF=a0")
+	assert.Nil(t, err)
+	assert.Equal(t, MemoryCondition{0x3FF, 0xA0}, mc)
+	for _, s := range []string{"300", "300=100", "x=1"} {
+		_, err = ParseMemoryCondition(s)
+		assert.NotNil(t, err, s)
+	}
+}
@@ -0,0 +1,86 @@
+package runner
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	chip8 "github.com/hermesdt/go-plan8"
+)
+
+// KeyEvent presses or releases a key before the first instruction of a
+// frame.
+type KeyEvent struct {
+	Frame uint64
+	Key   uint8
+	Down  bool
+}
+
+func (e KeyEvent) apply(k chip8.Keypad) {
+	if e.Down {
+		k.Press(e.Key)
+	} else {
+		k.Release(e.Key)
+	}
+}
+
+func (e KeyEvent) String() string {
+	sign := "-"
+	if e.Down {
+		sign = "+"
+	}
+	return fmt.Sprintf("%d:%X%s", e.Frame, e.Key, sign)
+}
+
+// Script is a list of key events, in any order.
+type Script []KeyEvent
+
+// ParseScript parses events separated by commas or spaces, each a frame
+// number, a colon, a hex key and + to press or - to release it:
+// "60:5+ 90:5-" holds key 5 from frame 60 to frame 90.
+func ParseScript(s string) (Script, error) {
+	var script Script
+	for _, item := range strings.FieldsFunc(s, func(r rune) bool { return r == ',' || r == ' ' }) {
+		colon := strings.IndexByte(item, ':')
+		if colon < 0 || len(item) != colon+3 {
+			return nil, fmt.Errorf("runner: bad key event %q", item)
+		}
+		frame, err := strconv.ParseUint(item[:colon], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("runner: bad key event %q", item)
+		}
+		key, err := strconv.ParseUint(item[colon+1:colon+2], 16, 8)
+		if err != nil {
+			return nil, fmt.Errorf("runner: bad key event %q", item)
+		}
+		e := KeyEvent{Frame: frame, Key: uint8(key)}
+		switch item[colon+2] {
+		case '+':
+			e.Down = true
+		case '-':
+		default:
+			return nil, fmt.Errorf("runner: bad key event %q", item)
+		}
+		script = append(script, e)
+	}
+	return script, nil
+}
+
+func (s Script) String() string {
+	items := make([]string, len(s))
+	for i, e := range s {
+		items[i] = e.String()
+	}
+	return strings.Join(items, " ")
+}
+
+// sorted returns the events by frame, keeping the order of events in
+// the same frame.
+func (s Script) sorted() Script {
+	out := append(Script(nil), s...)
+	sort.SliceStable(out, func(i, j int) bool {
+		return out[i].Frame < out[j].Frame
+	})
+	return out
+}
@@ -0,0 +1,24 @@
+package runner
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseScript(t *testing.T) {
+	s, err := ParseScript("60:5+, 90:5- 10:f+")
+
+	assert.Nil(t, err)
+	assert.Equal(t, Script{{60, 5, true}, {90, 5, false}, {10, 0xF, true}}, s)
+	assert.Equal(t, "60:5+ 90:5- 10:F+", s.String())
+	assert.Equal(t, Script{{10, 0xF, true}, {60, 5, true}, {90, 5, false}}, s.sorted())
+
+	for _, bad := range []string{"60:5", "60-5+", "x:5+", "60:g+", "60:5*", "60:55+"} {
+		_, err := ParseScript(bad)
+		assert.NotNil(t, err, bad)
+	}
+	s, err = ParseScript("")
+	assert.Nil(t, err)
+	assert.Empty(t, s)
+}
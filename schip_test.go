@@ -0,0 +1,121 @@
+package chip8
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHighResLowRes(t *testing.T) {
+	o := Opcode{Value: 0x00FF, Chip8: &Chip8{Variant: VariantSChip, PC: 0x200}}
+
+	o.Execute()
+	assert.True(t, o.Chip8.Screen.HiRes())
+	assert.Equal(t, uint16(0x202), o.Chip8.PC)
+
+	o.Value = 0x00FE
+	o.Execute()
+	assert.False(t, o.Chip8.Screen.HiRes())
+	assert.Equal(t, uint16(0x204), o.Chip8.PC)
+}
+
+func TestScrollOpcodes(t *testing.T) {
+	c := &Chip8{Variant: VariantSChip}
+	c.Screen.DrawByte(0, 8, 0x80)
+
+	for _, op := range []uint16{0x00C3, 0x00FB, 0x00FB, 0x00FC} {
+		o := Opcode{Value: op, Chip8: c}
+		assert.Nil(t, o.Execute())
+	}
+
+	assert.True(t, c.Screen.Get(3, 12))
+	assert.Equal(t, uint16(8), c.PC)
+}
+
+func TestExit(t *testing.T) {
+	o := Opcode{Value: 0x00FD, Chip8: &Chip8{Variant: VariantSChip, PC: 0x240}}
+
+	err := o.Execute()
+
+	assert.True(t, errors.Is(err, ErrExit))
+	assert.Equal(t, uint16(0x240), o.Chip8.PC)
+}
+
+func TestDraw_16x16(t *testing.T) {
+	c := &Chip8{I: 0x300}
+	c.Screen.SetHiRes(true)
+	c.V[0] = 100
+	c.V[1] = 40
+	for i := 0; i < 32; i++ {
+		c.Memory[0x300+i] = 0xFF
+	}
+	o := Opcode{Value: 0xD010, Chip8: c}
+
+	o.Execute()
+
+	for row := 40; row < 56; row++ {
+		assert.Equal(t, byte(0xFF), c.Screen.GetByte(row, 100))
+		assert.Equal(t, byte(0xFF), c.Screen.GetByte(row, 108))
+	}
+	assert.False(t, c.Screen.Get(56, 100))
+	assert.False(t, c.Screen.Get(40, 116))
+	assert.Equal(t, uint8(0), c.V[0xF])
+}
+
+func TestDraw_hiResRowCollisions(t *testing.T) {
+	c := &Chip8{I: 0x300, Quirks: QuirksSChip11}
+	c.Screen.SetHiRes(true)
+	c.V[1] = uint8(HiResHeight - 12)
+	for i := 0; i < 32; i++ {
+		c.Memory[0x300+i] = 0x01
+	}
+	c.Screen.DrawByte(HiResHeight-12, 0, 0x01)
+	c.Screen.DrawByte(HiResHeight-11, 8, 0x01)
+	c.Screen.DrawByte(HiResHeight-11, 0, 0x01)
+	o := Opcode{Value: 0xD010, Chip8: c}
+
+	o.Execute()
+
+	assert.Equal(t, uint8(2+4), c.V[0xF])
+
+	c.Quirks = QuirksXOChip
+	c.Screen.Clear()
+	c.Screen.DrawByte(HiResHeight-12, 0, 0x01)
+	c.Screen.DrawByte(HiResHeight-11, 0, 0x01)
+	o.Execute()
+
+	assert.Equal(t, uint8(1), c.V[0xF])
+}
+
+func TestSChipOpcodes_chip8Variant(t *testing.T) {
+	for _, op := range []uint16{0x00C1, 0x00FB, 0x00FC, 0x00FD, 0x00FE, 0x00FF, 0xF030, 0xF075, 0xF085} {
+		o := Opcode{Value: op, Chip8: &Chip8{}}
+		assertExecError(t, o.Execute(), ErrUnknownOpcode, 0, op)
+	}
+}
+
+func TestSetIBigSprite(t *testing.T) {
+	o := Opcode{Value: 0xF330, Chip8: &Chip8{Variant: VariantSChip}}
+	o.Chip8.V[3] = 0x7
+
+	o.Execute()
+
+	assert.Equal(t, uint16(HiResFontSetStart+70), o.Chip8.I)
+}
+
+func TestSaveLoadFlags(t *testing.T) {
+	c := &Chip8{Variant: VariantSChip}
+	c.V = [16]uint8{1, 2, 3, 4, 5, 6, 7, 8}
+	o := Opcode{Value: 0xF575, Chip8: c}
+
+	o.Execute()
+	flags, _ := c.Flags.LoadFlags(c.RomHash)
+	assert.Equal(t, []uint8{1, 2, 3, 4, 5, 6, 0}, flags[:7])
+
+	c.V = [16]uint8{}
+	o.Value = 0xF385
+	o.Execute()
+	assert.Equal(t, []uint8{1, 2, 3, 4, 0}, c.V[:5])
+	assert.Equal(t, uint16(4), c.PC)
+}
@@ -0,0 +1,296 @@
+package chip8
+
+import (
+	"bytes"
+	"compress/flate"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+)
+
+// A save state is the magic, a format version and a flate compressed
+// sequence of chunks, each a four byte tag, a uint32 length and the
+// data. Loaders skip chunks they do not know and keep defaults for
+// chunks that are missing, so states from older releases still load;
+// StateVersion only goes up when an existing chunk changes layout.
+const (
+	stateMagic   = "C8ST"
+	StateVersion = 1
+)
+
+var (
+	ErrInvalidState     = errors.New("chip8: invalid save state")
+	ErrUnsupportedState = errors.New("chip8: save state is from a newer release")
+)
+
+const (
+	chunkHeader = "HEAD"
+	chunkCPU    = "CPU "
+	chunkMemory = "MEM "
+	chunkScreen = "SCRN"
+	chunkKeys   = "KEYS"
+	chunkRandom = "RAND"
+)
+
+func (c *Chip8) SaveState(w io.Writer) error {
+	var body bytes.Buffer
+	for _, chunk := range []struct {
+		tag    string
+		encode func(*bytes.Buffer)
+	}{
+		{chunkHeader, c.encodeHeader},
+		{chunkCPU, c.encodeCPU},
+		{chunkMemory, c.encodeMemory},
+		{chunkScreen, c.encodeScreen},
+		{chunkKeys, c.encodeKeys},
+		{chunkRandom, c.encodeRandom},
+	} {
+		var data bytes.Buffer
+		chunk.encode(&data)
+		body.WriteString(chunk.tag)
+		binary.Write(&body, binary.BigEndian, uint32(data.Len()))
+		body.Write(data.Bytes())
+	}
+
+	if _, err := io.WriteString(w, stateMagic); err != nil {
+		return err
+	}
+	if err := binary.Write(w, binary.BigEndian, uint16(StateVersion)); err != nil {
+		return err
+	}
+	zw, err := flate.NewWriter(w, flate.BestCompression)
+	if err != nil {
+		return err
+	}
+	if _, err := zw.Write(body.Bytes()); err != nil {
+		return err
+	}
+	return zw.Close()
+}
+
+// LoadState replaces the machine state with the one read from r. The
+// keypad and flag store are kept, as is the random source for states
+// without one, and c is left untouched on error.
+func (c *Chip8) LoadState(r io.Reader) error {
+	var header [6]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil || string(header[:4]) != stateMagic {
+		return ErrInvalidState
+	}
+	version := binary.BigEndian.Uint16(header[4:])
+	if version > StateVersion {
+		return fmt.Errorf("%w: version %d", ErrUnsupportedState, version)
+	}
+	body, err := ioutil.ReadAll(flate.NewReader(r))
+	if err != nil {
+		return ErrInvalidState
+	}
+
+	s := &Chip8{Pitch: DefaultPitch}
+	var keys [KeyCount]bool
+	for len(body) > 0 {
+		if len(body) < 8 {
+			return ErrInvalidState
+		}
+		tag := string(body[:4])
+		n := binary.BigEndian.Uint32(body[4:8])
+		if uint64(n) > uint64(len(body)-8) {
+			return ErrInvalidState
+		}
+		data := body[8 : 8+n]
+		body = body[8+n:]
+
+		var err error
+		switch tag {
+		case chunkHeader:
+			err = s.decodeHeader(data)
+		case chunkCPU:
+			err = s.decodeCPU(data)
+		case chunkMemory:
+			err = s.decodeMemory(data)
+		case chunkScreen:
+			err = s.decodeScreen(data)
+		case chunkKeys:
+			err = decodeKeys(keys[:], data)
+		case chunkRandom:
+			err = s.decodeRandom(data)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	s.Key, s.Flags = c.Key, c.Flags
+	if s.Random == nil {
+		s.Random = c.Random
+	}
+	*c = *s
+	// Only keys that change are pressed or released, as releases wake
+	// NextRelease waiters.
+	k := c.keypad()
+	for i, down := range keys {
+		switch now := k.IsDown(uint8(i)); {
+		case down && !now:
+			k.Press(uint8(i))
+		case !down && now:
+			k.Release(uint8(i))
+		}
+	}
+	return nil
+}
+
+func (q Quirks) encode() []byte {
+	return []byte{
+		b2i[q.ShiftVY], uint8(q.LoadStore), b2i[q.JumpVX], b2i[q.VFReset],
+		b2i[q.Clip], b2i[q.DisplayWait], b2i[q.CountCollisions],
+	}
+}
+
+func decodeQuirks(data []byte) (Quirks, error) {
+	if len(data) != 7 || data[1] > uint8(IncrementX1) {
+		return Quirks{}, ErrInvalidState
+	}
+	return Quirks{
+		ShiftVY:         data[0] == 1,
+		LoadStore:       IndexIncrement(data[1]),
+		JumpVX:          data[2] == 1,
+		VFReset:         data[3] == 1,
+		Clip:            data[4] == 1,
+		DisplayWait:     data[5] == 1,
+		CountCollisions: data[6] == 1,
+	}, nil
+}
+
+func (c *Chip8) encodeHeader(b *bytes.Buffer) {
+	b.WriteByte(uint8(c.Variant))
+	b.WriteByte(uint8(c.Policy))
+	b.Write(c.Quirks.encode())
+	b.Write(c.RomHash[:])
+}
+
+func (c *Chip8) decodeHeader(data []byte) error {
+	if len(data) != 2+7+len(c.RomHash) || data[0] > uint8(VariantXOChip) || data[1] > uint8(AccessStrict) {
+		return ErrInvalidState
+	}
+	q, err := decodeQuirks(data[2:9])
+	if err != nil {
+		return err
+	}
+	c.Variant = Variant(data[0])
+	c.Policy = AccessPolicy(data[1])
+	c.Quirks = q
+	copy(c.RomHash[:], data[9:])
+	return nil
+}
+
+type cpuState struct {
+	V          [16]uint8
+	I          uint16
+	PC         uint16
+	SP         uint16
+	Stack      [16]uint16
+	DelayTimer uint8
+	SoundTimer uint8
+	KeyWait    [keyWaitSize]uint8
+	VBlank     uint8
+	Pitch      uint8
+	Audio      [16]uint8
+}
+
+func (c *Chip8) encodeCPU(b *bytes.Buffer) {
+	s := cpuState{
+		V: c.V, I: c.I, PC: c.PC, SP: c.SP, Stack: c.Stack,
+		DelayTimer: c.DelayTimer, SoundTimer: c.SoundTimer,
+		VBlank: uint8(c.vblank), Pitch: c.Pitch, Audio: c.Audio,
+	}
+	kw, _ := c.KeyWait.MarshalBinary()
+	copy(s.KeyWait[:], kw)
+	binary.Write(b, binary.BigEndian, &s)
+}
+
+func (c *Chip8) decodeCPU(data []byte) error {
+	var s cpuState
+	// Bytes added by later releases are ignored.
+	if len(data) < binary.Size(&s) {
+		return ErrInvalidState
+	}
+	binary.Read(bytes.NewReader(data), binary.BigEndian, &s)
+	if int(s.SP) > len(s.Stack) || s.VBlank > uint8(vblankReady) {
+		return ErrInvalidState
+	}
+	if err := c.KeyWait.UnmarshalBinary(s.KeyWait[:]); err != nil {
+		return ErrInvalidState
+	}
+	c.V, c.I, c.PC, c.SP, c.Stack = s.V, s.I, s.PC, s.SP, s.Stack
+	c.DelayTimer, c.SoundTimer = s.DelayTimer, s.SoundTimer
+	c.vblank, c.Pitch, c.Audio = vblankState(s.VBlank), s.Pitch, s.Audio
+	return nil
+}
+
+func (c *Chip8) encodeMemory(b *bytes.Buffer) {
+	b.Write(c.Memory[:c.Variant.MemorySize()])
+}
+
+func (c *Chip8) decodeMemory(data []byte) error {
+	if len(data) > len(c.Memory) {
+		return ErrInvalidState
+	}
+	copy(c.Memory[:], data)
+	return nil
+}
+
+// The screen chunk stores the resolution, the plane selection and then
+// every plane as a bitmap, most significant bit first.
+func (c *Chip8) encodeScreen(b *bytes.Buffer) {
+	b.WriteByte(b2i[c.Screen.hires])
+	b.WriteByte(c.Screen.selected)
+	for p := range c.Screen.px {
+		b.Write(packBits(c.Screen.px[p][:]))
+	}
+}
+
+func (c *Chip8) decodeScreen(data []byte) error {
+	size := len(c.Screen.px[0]) / 8
+	if len(data) != 2+Planes*size || data[0] > 1 {
+		return ErrInvalidState
+	}
+	c.Screen.hires = data[0] == 1
+	c.Screen.selected = data[1]
+	for p := range c.Screen.px {
+		unpackBits(c.Screen.px[p][:], data[2+p*size:])
+	}
+	return nil
+}
+
+func (c *Chip8) encodeKeys(b *bytes.Buffer) {
+	var down [KeyCount]bool
+	for i := range down {
+		down[i] = c.keypad().IsDown(uint8(i))
+	}
+	b.Write(packBits(down[:]))
+}
+
+func decodeKeys(keys []bool, data []byte) error {
+	if len(data) != KeyCount/8 {
+		return ErrInvalidState
+	}
+	unpackBits(keys, data)
+	return nil
+}
+
+func packBits(bits []bool) []byte {
+	bs := make([]byte, (len(bits)+7)/8)
+	for i, bit := range bits {
+		if bit {
+			bs[i/8] |= 0x80 >> uint(i%8)
+		}
+	}
+	return bs
+}
+
+func unpackBits(bits []bool, bs []byte) {
+	for i := range bits {
+		bits[i] = bs[i/8]&(0x80>>uint(i%8)) != 0
+	}
+}
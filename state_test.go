@@ -0,0 +1,203 @@
+package chip8
+
+import (
+	"bytes"
+	"compress/flate"
+	"encoding/binary"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newStateChip8() *Chip8 {
+	c := NewChip8(VariantXOChip)
+	c.LoadRomBytes([]byte{0x00, 0xFF, 0xF1, 0x0A})
+	c.Quirks = QuirksVIP
+	c.Policy = AccessWrap
+	c.V = [16]uint8{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16}
+	c.I = 0xBEEF
+	c.Stack[0] = 0x345
+	c.SP = 1
+	c.DelayTimer = 30
+	c.SoundTimer = 5
+	c.Memory[0xFFFF] = 0x77
+	c.Audio[3] = 0xAA
+	c.Pitch = 100
+	c.Step()
+	c.Screen.SelectPlanes(2)
+	c.Screen.SetPlane(1, 63, 127, true)
+	c.Key.Press(0xC)
+	c.Step()
+	return c
+}
+
+func TestSaveLoadState(t *testing.T) {
+	c := newStateChip8()
+	var buf bytes.Buffer
+	assert.Nil(t, c.SaveState(&buf))
+
+	restored := NewChip8(VariantChip8)
+	assert.Nil(t, restored.LoadState(bytes.NewReader(buf.Bytes())))
+
+	assert.Equal(t, VariantXOChip, restored.Variant)
+	assert.Equal(t, "vip", restored.Quirks.ProfileName())
+	assert.Equal(t, AccessWrap, restored.Policy)
+	assert.Equal(t, c.RomHash, restored.RomHash)
+	assert.Equal(t, c.V, restored.V)
+	assert.Equal(t, c.I, restored.I)
+	assert.Equal(t, c.PC, restored.PC)
+	assert.Equal(t, c.SP, restored.SP)
+	assert.Equal(t, c.Stack, restored.Stack)
+	assert.Equal(t, c.DelayTimer, restored.DelayTimer)
+	assert.Equal(t, c.SoundTimer, restored.SoundTimer)
+	assert.Equal(t, c.Memory, restored.Memory)
+	assert.Equal(t, c.Audio, restored.Audio)
+	assert.Equal(t, c.Pitch, restored.Pitch)
+	assert.Equal(t, c.Screen, restored.Screen)
+	assert.True(t, restored.WaitingForKey())
+	assert.Equal(t, KeyWait{Active: true, Register: 1, Pressed: true, Key: 0xC}, restored.KeyWait)
+	assert.True(t, restored.Key.IsDown(0xC))
+	assert.False(t, restored.Key.IsDown(0x1))
+}
+
+func TestLoadState_resumesKeyWait(t *testing.T) {
+	var buf bytes.Buffer
+	newStateChip8().SaveState(&buf)
+	c := NewChip8(VariantXOChip)
+	c.LoadState(&buf)
+
+	assert.Nil(t, c.Step())
+	c.Key.Release(0xC)
+	assert.Nil(t, c.Step())
+
+	assert.False(t, c.WaitingForKey())
+	assert.Equal(t, uint8(0xC), c.V[1])
+}
+
+func TestLoadState_keyWaitProgress(t *testing.T) {
+	c := NewChip8(VariantChip8)
+	c.LoadRomBytes([]byte{0xF2, 0x0A})
+	c.Step()
+	c.Key.Press(5)
+	c.Step()
+	var buf bytes.Buffer
+	c.SaveState(&buf)
+
+	restored := NewChip8(VariantChip8)
+	assert.Nil(t, restored.LoadState(&buf))
+	assert.Equal(t, KeyWait{Active: true, Register: 2, Pressed: true, Key: 5}, restored.KeyWait)
+	restored.Key.Release(5)
+	assert.Nil(t, restored.Step())
+
+	assert.False(t, restored.WaitingForKey())
+	assert.Equal(t, uint8(5), restored.V[2])
+}
+
+func TestDecodeCPU_longer(t *testing.T) {
+	c := NewChip8(VariantChip8)
+	c.PC = 0x234
+	var cpu bytes.Buffer
+	c.encodeCPU(&cpu)
+	cpu.Write([]byte{1, 2, 3})
+
+	restored := NewChip8(VariantChip8)
+	assert.Nil(t, restored.decodeCPU(cpu.Bytes()))
+	assert.Equal(t, uint16(0x234), restored.PC)
+}
+
+func TestLoadState_noSpuriousReleases(t *testing.T) {
+	c := NewChip8(VariantChip8)
+	c.Key.Press(3)
+	var buf bytes.Buffer
+	c.SaveState(&buf)
+
+	restored := NewChip8(VariantChip8)
+	released := restored.Key.NextRelease()
+	assert.Nil(t, restored.LoadState(bytes.NewReader(buf.Bytes())))
+
+	assert.True(t, restored.Key.IsDown(3))
+	select {
+	case key := <-released:
+		t.Errorf("spurious release of %X", key)
+	default:
+	}
+
+	restored.Key.Press(7)
+	released = restored.Key.NextRelease()
+	assert.Nil(t, restored.LoadState(bytes.NewReader(buf.Bytes())))
+	assert.False(t, restored.Key.IsDown(7))
+	assert.Equal(t, uint8(7), <-released)
+}
+
+func TestLoadState_compact(t *testing.T) {
+	var buf bytes.Buffer
+	NewChip8(VariantChip8).SaveState(&buf)
+
+	assert.True(t, buf.Len() < 512, "%d bytes", buf.Len())
+}
+
+func writeState(version uint16, chunks map[string][]byte, order []string) []byte {
+	var body bytes.Buffer
+	for _, tag := range order {
+		body.WriteString(tag)
+		binary.Write(&body, binary.BigEndian, uint32(len(chunks[tag])))
+		body.Write(chunks[tag])
+	}
+	var out bytes.Buffer
+	out.WriteString(stateMagic)
+	binary.Write(&out, binary.BigEndian, version)
+	zw, _ := flate.NewWriter(&out, flate.DefaultCompression)
+	zw.Write(body.Bytes())
+	zw.Close()
+	return out.Bytes()
+}
+
+func TestLoadState_olderAndNewerChunks(t *testing.T) {
+	c := NewChip8(VariantChip8)
+	c.PC = 0x2A2
+	var cpu, head bytes.Buffer
+	c.encodeCPU(&cpu)
+	c.encodeHeader(&head)
+	chunks := map[string][]byte{
+		chunkHeader: head.Bytes(),
+		chunkCPU:    cpu.Bytes(),
+		"XTRA":      {1, 2, 3},
+	}
+
+	restored := NewChip8(VariantChip8)
+	err := restored.LoadState(bytes.NewReader(writeState(1, chunks, []string{chunkHeader, "XTRA", chunkCPU})))
+
+	assert.Nil(t, err)
+	assert.Equal(t, uint16(0x2A2), restored.PC)
+	assert.False(t, restored.Screen.HiRes())
+}
+
+func TestLoadState_errors(t *testing.T) {
+	c := NewChip8(VariantChip8)
+	c.PC = 0x300
+
+	err := c.LoadState(bytes.NewReader(writeState(StateVersion+1, nil, nil)))
+	assert.True(t, errors.Is(err, ErrUnsupportedState))
+
+	err = c.LoadState(bytes.NewReader([]byte("nope")))
+	assert.Equal(t, ErrInvalidState, err)
+
+	bad := writeState(1, map[string][]byte{chunkCPU: {1, 2}}, []string{chunkCPU})
+	assert.Equal(t, ErrInvalidState, c.LoadState(bytes.NewReader(bad)))
+
+	truncated := writeState(1, map[string][]byte{chunkCPU: {1, 2}}, []string{chunkCPU})
+	assert.NotNil(t, c.LoadState(bytes.NewReader(truncated[:len(truncated)-2])))
+	assert.Equal(t, uint16(0x300), c.PC)
+}
+
+func TestMachineLoadState_clearsHalt(t *testing.T) {
+	c := NewChip8(VariantChip8)
+	var buf bytes.Buffer
+	c.SaveState(&buf)
+	m := NewMachine(c)
+	assert.NotNil(t, m.Step(1))
+
+	assert.Nil(t, m.LoadState(&buf))
+	assert.False(t, m.Halted())
+}
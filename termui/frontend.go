@@ -0,0 +1,176 @@
+package termui
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	chip8 "github.com/hermesdt/go-plan8"
+)
+
+// Keystrokes that control the frontend rather than the keypad.
+const (
+	keyQuit   = 0x03 // Ctrl-C, which raw mode no longer turns into SIGINT
+	keyPause  = 'p'
+	keyStep   = 'n'
+	keyFaster = '+'
+	keySlower = '-'
+)
+
+// SpeedStep is how much + and - change the instructions per second.
+const SpeedStep = 100
+
+const help = "p pause  n step  +/- speed  ^C quit"
+
+// Frontend runs a machine in a terminal: keystrokes come from In and
+// the screen, with a status line below, goes to Out. In is expected
+// to be in raw mode, see MakeRaw.
+type Frontend struct {
+	Machine  *chip8.Machine
+	In       io.Reader
+	Out      io.Writer
+	Renderer Renderer
+	Keys     *Keys
+
+	status string
+	err    error
+}
+
+func New(m *chip8.Machine, in io.Reader, out io.Writer) *Frontend {
+	return &Frontend{
+		Machine: m,
+		In:      in,
+		Out:     out,
+		Keys:    NewKeys(m.Chip8.Key),
+	}
+}
+
+// Run draws the screen every frame until ctx is done, In ends, Ctrl-C
+// is typed or the program exits with 00FD, which ends Run with nil. A
+// program that fails stays on screen with the error in the status
+// line, and Run returns the error when it ends.
+func (f *Frontend) Run(ctx context.Context) error {
+	keys := make(chan byte, 64)
+	readErr := make(chan error, 1)
+	go func() {
+		buf := make([]byte, 64)
+		for {
+			n, err := f.In.Read(buf)
+			for _, b := range buf[:n] {
+				keys <- b
+			}
+			if err != nil {
+				readErr <- err
+				return
+			}
+		}
+	}()
+
+	fmt.Fprint(f.Out, "\x1b[?25l\x1b[2J")
+	f.Renderer.Invalidate()
+	f.status = ""
+	defer func() {
+		_, rows := f.Renderer.Size(&f.Machine.Chip8.Screen)
+		fmt.Fprintf(f.Out, "\x1b[%d;1H\r\n\x1b[?25h", rows+1)
+	}()
+
+	clock := f.Machine.Clock
+	tick := clock.After(time.Second / chip8.TimerFrequency)
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case err := <-readErr:
+			if err != io.EOF {
+				return err
+			}
+			return f.result()
+		case b := <-keys:
+			if b == keyQuit {
+				return f.result()
+			}
+			f.Key(b)
+		case <-tick:
+			tick = clock.After(time.Second / chip8.TimerFrequency)
+			if err := f.Frame(); err != nil {
+				return err
+			}
+			if errors.Is(f.err, chip8.ErrExit) {
+				return nil
+			}
+		}
+	}
+}
+
+// result is the error Run ends with, nil for a program that exited.
+func (f *Frontend) result() error {
+	if errors.Is(f.err, chip8.ErrExit) {
+		return nil
+	}
+	return f.err
+}
+
+// Key handles a keystroke other than Ctrl-C. The keymap comes before
+// the frontend's own keys.
+func (f *Frontend) Key(b byte) {
+	m := f.Machine
+	if f.Keys.Type(b, m.Clock.Now()) {
+		return
+	}
+	switch b {
+	case keyPause:
+		if m.Paused() {
+			m.Resume()
+		} else {
+			m.Pause()
+		}
+	case keyStep:
+		if m.Paused() && f.err == nil {
+			f.err = m.Step(1)
+		}
+	case keyFaster:
+		m.InstructionsPerSecond += SpeedStep
+	case keySlower:
+		if m.InstructionsPerSecond > SpeedStep {
+			m.InstructionsPerSecond -= SpeedStep
+		}
+	}
+}
+
+// Frame catches the machine up with the clock, releases keys that
+// timed out and repaints what changed. It only fails if Out does.
+func (f *Frontend) Frame() error {
+	m := f.Machine
+	if f.err == nil {
+		f.err = m.Update()
+	}
+	f.Keys.Expire(m.Clock.Now())
+	s := &m.Chip8.Screen
+	if err := f.Renderer.Render(f.Out, s); err != nil {
+		return err
+	}
+	status := f.Status()
+	if status == f.status {
+		return nil
+	}
+	f.status = status
+	_, rows := f.Renderer.Size(s)
+	_, err := fmt.Fprintf(f.Out, "\x1b[%d;1H%s\x1b[K", rows+1, status)
+	return err
+}
+
+// Status describes the machine in one line.
+func (f *Frontend) Status() string {
+	m := f.Machine
+	c := m.Chip8
+	s := fmt.Sprintf("PC=%04X I=%04X DT=%02X ST=%02X  %d ips", c.PC, c.I, c.DelayTimer, c.SoundTimer, m.InstructionsPerSecond)
+	switch {
+	case f.err != nil:
+		s += "  halted: " + f.err.Error()
+	case m.Paused():
+		s += "  paused"
+	}
+	return s + "  " + help
+}
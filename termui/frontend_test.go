@@ -0,0 +1,119 @@
+package termui
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"io/ioutil"
+	"strings"
+	"testing"
+	"time"
+
+	chip8 "github.com/hermesdt/go-plan8"
+	"github.com/hermesdt/go-plan8/asm"
+	"github.com/stretchr/testify/assert"
+)
+
+// testProgram draws 0, then waits for a key and draws its digit.
+const testProgram = `
+	LD V1, 0
+	LD V0, 0
+	LD F, V0
+	DRW V1, V1, 5
+	LD V0, K
+	CLS
+	LD F, V0
+	DRW V1, V1, 5
+	RET
+`
+
+func newFrontend(t *testing.T, in io.Reader) (*Frontend, *chip8.FakeClock, *bytes.Buffer) {
+	rom, err := asm.Assemble(testProgram, chip8.VariantChip8)
+	assert.Nil(t, err)
+	c := chip8.NewChip8(chip8.VariantChip8)
+	assert.Nil(t, c.LoadRomBytes(rom))
+	m := chip8.NewMachine(c)
+	clock := chip8.NewFakeClock(time.Unix(0, 0))
+	m.Clock = clock
+	var out bytes.Buffer
+	return New(m, in, &out), clock, &out
+}
+
+func frame(t *testing.T, f *Frontend, clock *chip8.FakeClock) {
+	clock.Advance(time.Second / chip8.TimerFrequency)
+	assert.Nil(t, f.Frame())
+}
+
+func TestFrontendFrames(t *testing.T) {
+	f, clock, out := newFrontend(t, nil)
+	f.Keys.Timeout = 50 * time.Millisecond
+	assert.Nil(t, f.Frame())
+	frame(t, f, clock)
+
+	// The top of the 0 glyph, and the status line below the screen.
+	assert.Contains(t, out.String(), "\x1b[1;1H█▀▀█")
+	assert.Contains(t, out.String(), "\x1b[17;1HPC=0208 I=0050 DT=00 ST=00  700 ips  p pause")
+
+	// Typing 1 draws its glyph once the key times out.
+	out.Reset()
+	f.Key('1')
+	frame(t, f, clock)
+	assert.Equal(t, "", out.String())
+	for i := 0; i < 4; i++ {
+		frame(t, f, clock)
+	}
+	assert.Contains(t, out.String(), "\x1b[1;1H ▄█ ")
+	assert.Contains(t, out.String(), "halted: chip8: stack underflow")
+}
+
+func TestFrontendControls(t *testing.T) {
+	f, clock, _ := newFrontend(t, nil)
+	m := f.Machine
+	assert.Nil(t, f.Frame())
+
+	f.Key('p')
+	assert.True(t, m.Paused())
+	assert.Contains(t, f.Status(), "  paused")
+	frame(t, f, clock)
+	assert.Equal(t, uint64(0), m.Cycles)
+	f.Key('n')
+	f.Key('n')
+	assert.Equal(t, uint64(2), m.Cycles)
+	assert.Equal(t, uint16(0x204), m.Chip8.PC)
+	f.Key('p')
+	assert.False(t, m.Paused())
+
+	f.Key('+')
+	assert.Equal(t, 800, m.InstructionsPerSecond)
+	for i := 0; i < 10; i++ {
+		f.Key('-')
+	}
+	assert.Equal(t, SpeedStep, m.InstructionsPerSecond)
+}
+
+func TestFrontendRun(t *testing.T) {
+	f, _, out := newFrontend(t, strings.NewReader("p\x03"))
+
+	assert.Nil(t, f.Run(context.Background()))
+
+	assert.True(t, f.Machine.Paused())
+	assert.True(t, strings.HasPrefix(out.String(), "\x1b[?25l\x1b[2J"))
+	assert.True(t, strings.HasSuffix(out.String(), "\x1b[17;1H\r\n\x1b[?25h"))
+
+	// Input ending quits too.
+	f, _, _ = newFrontend(t, strings.NewReader(""))
+	assert.Nil(t, f.Run(context.Background()))
+}
+
+func TestFrontendRun_exit(t *testing.T) {
+	c := chip8.NewChip8(chip8.VariantSChip)
+	assert.Nil(t, c.LoadRomBytes([]byte{0x00, 0xFD}))
+	in, w := io.Pipe()
+	defer w.Close()
+	f := New(chip8.NewMachine(c), in, ioutil.Discard)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	assert.Nil(t, f.Run(ctx))
+	assert.Contains(t, f.Status(), "halted: chip8: program exited")
+}
@@ -0,0 +1,77 @@
+package termui
+
+import (
+	"sync"
+	"time"
+
+	chip8 "github.com/hermesdt/go-plan8"
+)
+
+// DefaultKeymap lays the hex keypad out on the left of a QWERTY
+// keyboard:
+//
+//	1 2 3 C      1 2 3 4
+//	4 5 6 D  <-  q w e r
+//	7 8 9 E      a s d f
+//	A 0 B F      z x c v
+var DefaultKeymap = map[byte]uint8{
+	'1': 0x1, '2': 0x2, '3': 0x3, '4': 0xC,
+	'q': 0x4, 'w': 0x5, 'e': 0x6, 'r': 0xD,
+	'a': 0x7, 's': 0x8, 'd': 0x9, 'f': 0xE,
+	'z': 0xA, 'x': 0x0, 'c': 0xB, 'v': 0xF,
+}
+
+// DefaultKeyTimeout is how long a keystroke holds its key down. It
+// should outlast the terminal's key repeat delay, so that a held key
+// stays down until it repeats.
+const DefaultKeyTimeout = 200 * time.Millisecond
+
+// Keys presses keypad keys for keystrokes. Terminals only report
+// keystrokes, never releases, so a key is released once Timeout passes
+// without it being typed again.
+type Keys struct {
+	Keypad  chip8.Keypad
+	Keymap  map[byte]uint8
+	Timeout time.Duration
+
+	mu       sync.Mutex
+	deadline map[uint8]time.Time
+}
+
+func NewKeys(k chip8.Keypad) *Keys {
+	return &Keys{
+		Keypad:   k,
+		Keymap:   DefaultKeymap,
+		Timeout:  DefaultKeyTimeout,
+		deadline: map[uint8]time.Time{},
+	}
+}
+
+// Type presses the key of b, reporting whether it has one. Upper case
+// letters count as lower case.
+func (k *Keys) Type(b byte, now time.Time) bool {
+	if 'A' <= b && b <= 'Z' {
+		b += 'a' - 'A'
+	}
+	key, ok := k.Keymap[b]
+	if !ok {
+		return false
+	}
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	k.deadline[key] = now.Add(k.Timeout)
+	k.Keypad.Press(key)
+	return true
+}
+
+// Expire releases the keys whose timeout has passed.
+func (k *Keys) Expire(now time.Time) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	for key, t := range k.deadline {
+		if !now.Before(t) {
+			delete(k.deadline, key)
+			k.Keypad.Release(key)
+		}
+	}
+}
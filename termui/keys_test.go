@@ -0,0 +1,29 @@
+package termui
+
+import (
+	"testing"
+	"time"
+
+	chip8 "github.com/hermesdt/go-plan8"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKeysTimeout(t *testing.T) {
+	k := chip8.NewKeypad()
+	keys := NewKeys(k)
+	keys.Timeout = 100 * time.Millisecond
+	now := time.Unix(0, 0)
+
+	assert.True(t, keys.Type('W', now))
+	assert.False(t, keys.Type('p', now))
+	assert.True(t, k.IsDown(0x5))
+
+	// Repeats keep the key down.
+	keys.Expire(now.Add(90 * time.Millisecond))
+	assert.True(t, k.IsDown(0x5))
+	keys.Type('w', now.Add(90*time.Millisecond))
+	keys.Expire(now.Add(150 * time.Millisecond))
+	assert.True(t, k.IsDown(0x5))
+	keys.Expire(now.Add(190 * time.Millisecond))
+	assert.False(t, k.IsDown(0x5))
+}
@@ -0,0 +1,34 @@
+package termui
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+var ErrNotTerminal = errors.New("termui: not a terminal")
+
+// MakeRaw switches the terminal on f to raw mode with stty, so that
+// keystrokes arrive one by one without echo, and returns a function
+// that restores it.
+func MakeRaw(f *os.File) (restore func() error, err error) {
+	saved, err := stty(f, "-g")
+	if err != nil {
+		return nil, ErrNotTerminal
+	}
+	if _, err := stty(f, "raw", "-echo"); err != nil {
+		return nil, err
+	}
+	return func() error {
+		_, err := stty(f, strings.TrimSpace(saved))
+		return err
+	}, nil
+}
+
+func stty(f *os.File, args ...string) (string, error) {
+	cmd := exec.Command("stty", args...)
+	cmd.Stdin = f
+	out, err := cmd.Output()
+	return string(out), err
+}
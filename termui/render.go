@@ -0,0 +1,140 @@
+// Package termui is the terminal frontend, for playing and debugging
+// programs over SSH: it draws the screen with Unicode block or braille
+// characters through ANSI escapes, repainting only the cells that
+// changed, and turns raw keystrokes into keypad presses.
+package termui
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+
+	chip8 "github.com/hermesdt/go-plan8"
+)
+
+// Mode is how pixels are packed into character cells.
+type Mode int
+
+const (
+	// HalfBlock draws 1x2 pixels per cell with ▀, ▄ and █.
+	HalfBlock Mode = iota
+	// Braille draws 2x4 pixels per cell with braille patterns.
+	Braille
+)
+
+var modeNames = []string{"halfblock", "braille"}
+
+func (m Mode) String() string {
+	if int(m) < len(modeNames) {
+		return modeNames[m]
+	}
+	return fmt.Sprintf("Mode(%d)", int(m))
+}
+
+func ParseMode(name string) (Mode, error) {
+	for i, n := range modeNames {
+		if n == strings.ToLower(name) {
+			return Mode(i), nil
+		}
+	}
+	return 0, fmt.Errorf("termui: unknown mode %q", name)
+}
+
+// CellSize is the pixels in one character cell.
+func (m Mode) CellSize() (w, h int) {
+	if m == Braille {
+		return 2, 4
+	}
+	return 1, 2
+}
+
+// braille holds the dot of each pixel in a braille cell, by row and
+// column.
+var braille = [4][2]rune{
+	{0x01, 0x08},
+	{0x02, 0x10},
+	{0x04, 0x20},
+	{0x40, 0x80},
+}
+
+var halfBlocks = [4]rune{' ', '▀', '▄', '█'}
+
+// cell is the character drawing the pixels of the cell at column cx,
+// row cy. A pixel is lit in any plane.
+func (m Mode) cell(s *chip8.Screen, cx, cy int) rune {
+	if m == Braille {
+		r := rune(0x2800)
+		for dy := 0; dy < 4; dy++ {
+			for dx := 0; dx < 2; dx++ {
+				if s.Pixel(cy*4+dy, cx*2+dx) != 0 {
+					r |= braille[dy][dx]
+				}
+			}
+		}
+		return r
+	}
+	i := 0
+	if s.Pixel(cy*2, cx) != 0 {
+		i |= 1
+	}
+	if s.Pixel(cy*2+1, cx) != 0 {
+		i |= 2
+	}
+	return halfBlocks[i]
+}
+
+// Renderer draws a screen at the top left of the terminal, keeping
+// what it drew so the next call only repaints cells that changed.
+type Renderer struct {
+	Mode Mode
+
+	cols, rows int
+	cells      []rune
+}
+
+// Size is the cells a screen takes.
+func (r *Renderer) Size(s *chip8.Screen) (cols, rows int) {
+	w, h := r.Mode.CellSize()
+	return s.Width() / w, s.Height() / h
+}
+
+// Invalidate forgets what was drawn, so the next Render repaints every
+// cell.
+func (r *Renderer) Invalidate() {
+	r.cells = nil
+}
+
+// Render writes the escapes and characters that bring the terminal up
+// to date with s.
+func (r *Renderer) Render(w io.Writer, s *chip8.Screen) error {
+	bw := bufio.NewWriter(w)
+	cols, rows := r.Size(s)
+	if r.cells == nil || cols != r.cols || rows != r.rows {
+		r.cols, r.rows = cols, rows
+		r.cells = make([]rune, cols*rows)
+		for i := range r.cells {
+			r.cells[i] = -1
+		}
+		// Clear what a larger screen left behind.
+		bw.WriteString("\x1b[H\x1b[J")
+	}
+	for cy := 0; cy < rows; cy++ {
+		// Runs of changed cells are written after one cursor move.
+		next := -1
+		for cx := 0; cx < cols; cx++ {
+			c := r.Mode.cell(s, cx, cy)
+			i := cy*cols + cx
+			if r.cells[i] == c {
+				continue
+			}
+			r.cells[i] = c
+			if cx != next {
+				fmt.Fprintf(bw, "\x1b[%d;%dH", cy+1, cx+1)
+			}
+			bw.WriteRune(c)
+			next = cx + 1
+		}
+	}
+	return bw.Flush()
+}
@@ -0,0 +1,82 @@
+package termui
+
+import (
+	"bytes"
+	"testing"
+
+	chip8 "github.com/hermesdt/go-plan8"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseMode(t *testing.T) {
+	m, err := ParseMode("Braille")
+	assert.Nil(t, err)
+	assert.Equal(t, Braille, m)
+	assert.Equal(t, "halfblock", HalfBlock.String())
+	_, err = ParseMode("ascii")
+	assert.NotNil(t, err)
+}
+
+func TestRenderHalfBlock(t *testing.T) {
+	var s chip8.Screen
+	s.Set(0, 0, true)
+	s.Set(1, 1, true)
+	s.Set(0, 2, true)
+	s.Set(1, 2, true)
+	r := &Renderer{}
+	var out bytes.Buffer
+
+	assert.Nil(t, r.Render(&out, &s))
+
+	cols, rows := r.Size(&s)
+	assert.Equal(t, 64, cols)
+	assert.Equal(t, 16, rows)
+	assert.Contains(t, out.String(), "\x1b[H\x1b[J\x1b[1;1H▀▄█ ")
+	assert.Contains(t, out.String(), "\x1b[16;1H")
+
+	// Only the changed cell is repainted.
+	out.Reset()
+	s.Set(31, 63, true)
+	assert.Nil(t, r.Render(&out, &s))
+	assert.Equal(t, "\x1b[16;64H▄", out.String())
+
+	out.Reset()
+	assert.Nil(t, r.Render(&out, &s))
+	assert.Equal(t, "", out.String())
+
+	// Neighbouring changes share a cursor move.
+	s.Set(2, 5, true)
+	s.Set(3, 6, true)
+	assert.Nil(t, r.Render(&out, &s))
+	assert.Equal(t, "\x1b[2;6H▀▄", out.String())
+}
+
+func TestRenderBraille(t *testing.T) {
+	var s chip8.Screen
+	s.SetHiRes(true)
+	s.Set(0, 0, true)
+	s.Set(3, 1, true)
+	s.SetPlane(1, 4, 2, true)
+	r := &Renderer{Mode: Braille}
+	var out bytes.Buffer
+
+	assert.Nil(t, r.Render(&out, &s))
+
+	cols, rows := r.Size(&s)
+	assert.Equal(t, 64, cols)
+	assert.Equal(t, 16, rows)
+	assert.Contains(t, out.String(), "\x1b[1;1H⢁⠀")
+	assert.Contains(t, out.String(), "\x1b[2;1H⠀⠁")
+
+	// A resolution switch repaints everything.
+	out.Reset()
+	s.SetHiRes(false)
+	assert.Nil(t, r.Render(&out, &s))
+	assert.Contains(t, out.String(), "\x1b[H\x1b[J")
+	assert.Contains(t, out.String(), "\x1b[8;1H")
+
+	r.Invalidate()
+	out.Reset()
+	assert.Nil(t, r.Render(&out, &s))
+	assert.Contains(t, out.String(), "\x1b[H\x1b[J")
+}
@@ -0,0 +1,201 @@
+package trace
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+
+	chip8 "github.com/hermesdt/go-plan8"
+)
+
+// binaryMagic starts a binary trace, followed by a version and the
+// variant the program ran as.
+const (
+	binaryMagic   = "C8TR"
+	binaryVersion = 1
+)
+
+// Record flags, in the byte that also holds the number of changes.
+const (
+	flagLong   = 0x40
+	flagErr    = 0x80
+	changeMask = 0x3F
+)
+
+var ErrBadTrace = errors.New("trace: not a binary trace")
+
+type encoder interface {
+	encode(w *bufio.Writer, e *Entry) error
+}
+
+func newEncoder(f Format, variant chip8.Variant) encoder {
+	switch f {
+	case JSON:
+		return jsonEncoder{}
+	case Binary:
+		return &binaryEncoder{variant: variant}
+	}
+	return textEncoder{}
+}
+
+type textEncoder struct{}
+
+// encode writes fixed width columns so traces line up under diff:
+//
+//	0000000012 0204 2208 CALL 0x208          I=0000 SP=1 V3=00->04
+func (textEncoder) encode(w *bufio.Writer, e *Entry) error {
+	_, err := w.WriteString(FormatText(e) + "\n")
+	return err
+}
+
+// FormatText renders e as a line of the Text format, without the
+// newline.
+func FormatText(e *Entry) string {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "%010d %04X %04X %-20s I=%04X SP=%X", e.Cycle, e.PC, e.Opcode, e.Mnemonic, e.I, e.SP)
+	for _, c := range e.Changes {
+		fmt.Fprintf(&b, " %v=%02X->%02X", c.Reg, c.Old, c.New)
+	}
+	if e.Err != "" {
+		fmt.Fprintf(&b, " error: %s", e.Err)
+	}
+	return b.String()
+}
+
+type jsonEncoder struct{}
+
+func (jsonEncoder) encode(w *bufio.Writer, e *Entry) error {
+	bs, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	w.Write(bs)
+	return w.WriteByte('\n')
+}
+
+// binaryEncoder writes a header, then per entry: the cycle as a uvarint
+// delta from the previous entry, PC, opcode, I (big endian words), SP,
+// a byte of flags and change count, the long word if flagged, three
+// bytes per change and, if flagged, the error as a uvarint length and
+// its text. Mnemonics are decoded again on reading.
+type binaryEncoder struct {
+	variant chip8.Variant
+	started bool
+	cycle   uint64
+}
+
+func (b *binaryEncoder) encode(w *bufio.Writer, e *Entry) error {
+	if !b.started {
+		b.started = true
+		w.WriteString(binaryMagic)
+		w.Write([]byte{binaryVersion, byte(b.variant)})
+	}
+	var buf [binary.MaxVarintLen64]byte
+	w.Write(buf[:binary.PutUvarint(buf[:], e.Cycle-b.cycle)])
+	b.cycle = e.Cycle
+	var words [7]byte
+	binary.BigEndian.PutUint16(words[0:], e.PC)
+	binary.BigEndian.PutUint16(words[2:], e.Opcode)
+	binary.BigEndian.PutUint16(words[4:], e.I)
+	words[6] = byte(e.SP)
+	w.Write(words[:])
+
+	flags := byte(len(e.Changes)) & changeMask
+	if e.Long != 0 {
+		flags |= flagLong
+	}
+	if e.Err != "" {
+		flags |= flagErr
+	}
+	w.WriteByte(flags)
+	if e.Long != 0 {
+		w.Write([]byte{byte(e.Long >> 8), byte(e.Long)})
+	}
+	for _, c := range e.Changes {
+		w.Write([]byte{byte(c.Reg), c.Old, c.New})
+	}
+	if e.Err != "" {
+		w.Write(buf[:binary.PutUvarint(buf[:], uint64(len(e.Err)))])
+		w.WriteString(e.Err)
+	}
+	return nil
+}
+
+// Reader reads back a binary trace.
+type Reader struct {
+	Variant chip8.Variant
+
+	r     *bufio.Reader
+	cycle uint64
+}
+
+// NewReader reads the header of a binary trace.
+func NewReader(r io.Reader) (*Reader, error) {
+	br := bufio.NewReader(r)
+	var header [len(binaryMagic) + 2]byte
+	if _, err := io.ReadFull(br, header[:]); err != nil {
+		return nil, ErrBadTrace
+	}
+	if string(header[:len(binaryMagic)]) != binaryMagic || header[len(binaryMagic)] != binaryVersion {
+		return nil, ErrBadTrace
+	}
+	return &Reader{Variant: chip8.Variant(header[len(binaryMagic)+1]), r: br}, nil
+}
+
+// Next returns the next entry, or io.EOF at the end of the trace.
+func (r *Reader) Next() (*Entry, error) {
+	delta, err := binary.ReadUvarint(r.r)
+	if err != nil {
+		return nil, err
+	}
+	r.cycle += delta
+	e := &Entry{Cycle: r.cycle}
+
+	var words [8]byte
+	if _, err := io.ReadFull(r.r, words[:]); err != nil {
+		return nil, unexpected(err)
+	}
+	e.PC = binary.BigEndian.Uint16(words[0:])
+	e.Opcode = binary.BigEndian.Uint16(words[2:])
+	e.I = binary.BigEndian.Uint16(words[4:])
+	e.SP = uint16(words[6])
+	flags := words[7]
+	if flags&flagLong != 0 {
+		var long [2]byte
+		if _, err := io.ReadFull(r.r, long[:]); err != nil {
+			return nil, unexpected(err)
+		}
+		e.Long = binary.BigEndian.Uint16(long[:])
+	}
+	for i := 0; i < int(flags&changeMask); i++ {
+		var c [3]byte
+		if _, err := io.ReadFull(r.r, c[:]); err != nil {
+			return nil, unexpected(err)
+		}
+		e.Changes = append(e.Changes, Change{Reg: Reg(c[0]), Old: c[1], New: c[2]})
+	}
+	if flags&flagErr != 0 {
+		n, err := binary.ReadUvarint(r.r)
+		if err != nil {
+			return nil, unexpected(err)
+		}
+		msg := make([]byte, n)
+		if _, err := io.ReadFull(r.r, msg); err != nil {
+			return nil, unexpected(err)
+		}
+		e.Err = string(msg)
+	}
+	e.Mnemonic = mnemonic(e.PC, e.Opcode, e.Long, r.Variant)
+	return e, nil
+}
+
+func unexpected(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
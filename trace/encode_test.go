@@ -0,0 +1,110 @@
+package trace
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io"
+	"testing"
+
+	chip8 "github.com/hermesdt/go-plan8"
+	"github.com/stretchr/testify/assert"
+)
+
+const xoProgram = `
+		LD I, LONG 0x1234
+		LD V5, 0x80
+		ADD V5, V5
+		RET
+`
+
+func traceProgram(t *testing.T, f Format) []byte {
+	m := newMachine(t, xoProgram, chip8.VariantXOChip)
+	var out bytes.Buffer
+	tr := New(&out, f, chip8.VariantXOChip)
+	m.Tracer = tr
+	assert.NotNil(t, m.Emulate(4))
+	assert.Nil(t, tr.Flush())
+	return out.Bytes()
+}
+
+func TestJSON(t *testing.T) {
+	var entries []Entry
+	scanner := bufio.NewScanner(bytes.NewReader(traceProgram(t, JSON)))
+	for scanner.Scan() {
+		var e Entry
+		assert.Nil(t, json.Unmarshal(scanner.Bytes(), &e))
+		entries = append(entries, e)
+	}
+
+	assert.Len(t, entries, 4)
+	assert.Equal(t, Entry{
+		Cycle: 0, PC: 0x200, Opcode: 0xF000, Long: 0x1234,
+		Mnemonic: "LD I, LONG 0x1234", I: 0x1234,
+	}, entries[0])
+	assert.Equal(t, []Change{{5, 0x80, 0}, {0xF, 0, 1}}, entries[2].Changes)
+	assert.Equal(t, "chip8: stack underflow (pc=0x0208 opcode=0x00ee)", entries[3].Err)
+
+	line := bytes.SplitN(traceProgram(t, JSON), []byte("\n"), 4)[2]
+	assert.Equal(t, `{"cycle":2,"pc":518,"opcode":34132,"mnemonic":"ADD V5, V5","i":4660,"sp":0,"changes":[{"reg":"V5","old":128,"new":0},{"reg":"VF","old":0,"new":1}]}`, string(line))
+}
+
+func TestBinaryRoundTrip(t *testing.T) {
+	var want []*Entry
+	scanner := bufio.NewScanner(bytes.NewReader(traceProgram(t, JSON)))
+	for scanner.Scan() {
+		e := &Entry{}
+		assert.Nil(t, json.Unmarshal(scanner.Bytes(), e))
+		want = append(want, e)
+	}
+
+	bin := traceProgram(t, Binary)
+	r, err := NewReader(bytes.NewReader(bin))
+	assert.Nil(t, err)
+	assert.Equal(t, chip8.VariantXOChip, r.Variant)
+	var got []*Entry
+	for {
+		e, err := r.Next()
+		if err == io.EOF {
+			break
+		}
+		assert.Nil(t, err)
+		got = append(got, e)
+	}
+	assert.Equal(t, want, got)
+
+	// Binary traces are smaller than text ones.
+	assert.True(t, len(bin) < len(traceProgram(t, Text))/2)
+
+	_, err = NewReader(bytes.NewReader([]byte("nope")))
+	assert.Equal(t, ErrBadTrace, err)
+	r, err = NewReader(bytes.NewReader(bin[:len(bin)-3]))
+	assert.Nil(t, err)
+	for err == nil {
+		_, err = r.Next()
+	}
+	assert.Equal(t, io.ErrUnexpectedEOF, err)
+}
+
+func TestReg(t *testing.T) {
+	for _, name := range []string{"V0", "VA", "VF", "DT", "ST"} {
+		var r Reg
+		assert.Nil(t, r.UnmarshalText([]byte(name)))
+		text, err := r.MarshalText()
+		assert.Nil(t, err)
+		assert.Equal(t, name, string(text))
+	}
+	var r Reg
+	assert.NotNil(t, r.UnmarshalText([]byte("VG")))
+	assert.NotNil(t, r.UnmarshalText([]byte("I")))
+	_, err := Reg(18).MarshalText()
+	assert.NotNil(t, err)
+}
+
+func TestParseFormat(t *testing.T) {
+	f, err := ParseFormat("JSON")
+	assert.Nil(t, err)
+	assert.Equal(t, JSON, f)
+	_, err = ParseFormat("xml")
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,101 @@
+package trace
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Entry records one executed instruction. PC, Opcode and Mnemonic
+// describe the instruction; I, SP and Changes the state it left.
+type Entry struct {
+	Cycle  uint64 `json:"cycle"`
+	PC     uint16 `json:"pc"`
+	Opcode uint16 `json:"opcode"`
+	// Long is the second word of a four byte instruction, such as
+	// F000 NNNN.
+	Long     uint16   `json:"long,omitempty"`
+	Mnemonic string   `json:"mnemonic"`
+	I        uint16   `json:"i"`
+	SP       uint16   `json:"sp"`
+	Changes  []Change `json:"changes,omitempty"`
+	Err      string   `json:"error,omitempty"`
+}
+
+// Change is a register the instruction changed.
+type Change struct {
+	Reg Reg   `json:"reg"`
+	Old uint8 `json:"old"`
+	New uint8 `json:"new"`
+}
+
+// Reg numbers V0-VF as 0-15, then the delay and sound timers.
+type Reg uint8
+
+const (
+	RegDT Reg = 16 + iota
+	RegST
+	regCount
+)
+
+func (r Reg) String() string {
+	switch {
+	case r < 16:
+		return fmt.Sprintf("V%X", uint8(r))
+	case r == RegDT:
+		return "DT"
+	case r == RegST:
+		return "ST"
+	}
+	return fmt.Sprintf("Reg(%d)", uint8(r))
+}
+
+func (r Reg) MarshalText() ([]byte, error) {
+	if r >= regCount {
+		return nil, fmt.Errorf("trace: bad register %d", uint8(r))
+	}
+	return []byte(r.String()), nil
+}
+
+func (r *Reg) UnmarshalText(text []byte) error {
+	s := strings.ToUpper(string(text))
+	switch {
+	case s == "DT":
+		*r = RegDT
+	case s == "ST":
+		*r = RegST
+	case len(s) == 2 && s[0] == 'V':
+		x, err := strconv.ParseUint(s[1:], 16, 4)
+		if err != nil {
+			return fmt.Errorf("trace: bad register %q", text)
+		}
+		*r = Reg(x)
+	default:
+		return fmt.Errorf("trace: bad register %q", text)
+	}
+	return nil
+}
+
+// Format is an encoding of trace entries.
+type Format int
+
+const (
+	// Text is one aligned line per entry, for reading and diffing.
+	Text Format = iota
+	// JSON is one JSON object per line.
+	JSON
+	// Binary is a compact encoding read back by Reader.
+	Binary
+)
+
+func ParseFormat(name string) (Format, error) {
+	switch strings.ToLower(name) {
+	case "text":
+		return Text, nil
+	case "json":
+		return JSON, nil
+	case "binary":
+		return Binary, nil
+	}
+	return 0, fmt.Errorf("trace: unknown format %q", name)
+}
@@ -0,0 +1,205 @@
+// Package trace logs every instruction a chip8.Machine executes, with
+// the registers it changed, as text, JSON lines or a compact binary
+// encoding. Traces can be filtered by address or instruction, or kept
+// in a ring buffer that is only written when an instruction fails.
+package trace
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	chip8 "github.com/hermesdt/go-plan8"
+	"github.com/hermesdt/go-plan8/disasm"
+)
+
+// Tracer is a chip8.Tracer writing entries to an io.Writer. Output is
+// buffered until Flush.
+type Tracer struct {
+	Filter Filter
+
+	w       *bufio.Writer
+	enc     encoder
+	variant chip8.Variant
+	err     error
+
+	// ring holds the last entries when it has a size; next is where
+	// the following one goes.
+	ring []Entry
+	next int
+	full bool
+
+	cur    Entry
+	before [regCount]uint8
+}
+
+// New traces every instruction in format f. The variant is recorded in
+// binary traces so they can be decoded again.
+func New(w io.Writer, f Format, variant chip8.Variant) *Tracer {
+	return &Tracer{w: bufio.NewWriter(w), enc: newEncoder(f, variant), variant: variant}
+}
+
+// NewRing keeps only the last n entries in memory and writes them when
+// an instruction fails, or on Dump. With n <= 0 it writes every entry,
+// like New.
+func NewRing(w io.Writer, f Format, variant chip8.Variant, n int) *Tracer {
+	t := New(w, f, variant)
+	if n <= 0 {
+		return t
+	}
+	t.ring = make([]Entry, n)
+	return t
+}
+
+// Before keeps the words of the instruction about to run; its
+// mnemonic is only disassembled once the entry is written.
+func (t *Tracer) Before(c *chip8.Chip8, cycle uint64) {
+	size := c.Variant.MemorySize()
+	pc := int(c.PC) % size
+	word := func(at int) uint16 {
+		return uint16(c.Memory[at%size])<<8 | uint16(c.Memory[(at+1)%size])
+	}
+	t.cur = Entry{Cycle: cycle, PC: c.PC, Opcode: word(pc)}
+	if op := chip8.Decode(t.cur.Opcode, c.Variant); op != nil && op.Len() == 4 {
+		t.cur.Long = word(pc + 2)
+	}
+	t.before = registers(c)
+}
+
+func (t *Tracer) After(c *chip8.Chip8, err error) {
+	e := t.cur
+	e.I, e.SP = c.I, c.SP
+	for r, v := range registers(c) {
+		if v != t.before[r] {
+			e.Changes = append(e.Changes, Change{Reg: Reg(r), Old: t.before[r], New: v})
+		}
+	}
+	if err != nil {
+		e.Err = err.Error()
+	} else if !t.Filter.match(&e, c.Variant) {
+		return
+	}
+	if t.ring == nil {
+		t.write(&e)
+		return
+	}
+	t.ring[t.next] = e
+	t.next = (t.next + 1) % len(t.ring)
+	t.full = t.full || t.next == 0
+	if err != nil {
+		t.Dump()
+	}
+}
+
+// Dump writes the entries in the ring buffer, oldest first, and empties
+// it.
+func (t *Tracer) Dump() error {
+	if t.full {
+		for i := t.next; i < len(t.ring); i++ {
+			t.write(&t.ring[i])
+		}
+	}
+	for i := 0; i < t.next; i++ {
+		t.write(&t.ring[i])
+	}
+	t.next, t.full = 0, false
+	return t.Flush()
+}
+
+// Flush writes buffered output and returns the first error writing it.
+func (t *Tracer) Flush() error {
+	if err := t.w.Flush(); err != nil && t.err == nil {
+		t.err = err
+	}
+	return t.err
+}
+
+func (t *Tracer) write(e *Entry) {
+	if t.err != nil {
+		return
+	}
+	e.disassemble(t.variant)
+	t.err = t.enc.encode(t.w, e)
+}
+
+func registers(c *chip8.Chip8) [regCount]uint8 {
+	var r [regCount]uint8
+	copy(r[:], c.V[:])
+	r[RegDT], r[RegST] = c.DelayTimer, c.SoundTimer
+	return r
+}
+
+// mnemonic disassembles an instruction from its words.
+func mnemonic(pc, opcode, long uint16, variant chip8.Variant) string {
+	bs := []byte{byte(opcode >> 8), byte(opcode), byte(long >> 8), byte(long)}
+	return disasm.Decode(bs, pc, variant).Format(disasm.Cowgod)
+}
+
+// disassemble sets the mnemonic of e if it has none yet.
+func (e *Entry) disassemble(variant chip8.Variant) {
+	if e.Mnemonic == "" {
+		e.Mnemonic = mnemonic(e.PC, e.Opcode, e.Long, variant)
+	}
+}
+
+// Filter selects the instructions traced. The zero Filter traces all;
+// instructions that fail are always traced.
+type Filter struct {
+	// Ranges, if any, limit the trace to instructions at addresses in
+	// one of them.
+	Ranges []Range
+	// Classes, if any, limit it to instructions of one of these
+	// classes: an instruction name from chip8.Instructions, such as
+	// "Draw", or a mnemonic, such as "DRW". Case is ignored.
+	Classes []string
+}
+
+// Range is an inclusive range of addresses.
+type Range struct {
+	Start, End uint16
+}
+
+// ParseRange parses "START-END" or a single address, in hex.
+func ParseRange(s string) (Range, error) {
+	parts := strings.SplitN(s, "-", 2)
+	start, err := strconv.ParseUint(strings.TrimPrefix(parts[0], "0x"), 16, 16)
+	if err != nil {
+		return Range{}, fmt.Errorf("trace: bad address range %q", s)
+	}
+	end := start
+	if len(parts) == 2 {
+		if end, err = strconv.ParseUint(strings.TrimPrefix(parts[1], "0x"), 16, 16); err != nil || end < start {
+			return Range{}, fmt.Errorf("trace: bad address range %q", s)
+		}
+	}
+	return Range{uint16(start), uint16(end)}, nil
+}
+
+func (f *Filter) match(e *Entry, variant chip8.Variant) bool {
+	if len(f.Ranges) > 0 {
+		in := false
+		for _, r := range f.Ranges {
+			in = in || e.PC >= r.Start && e.PC <= r.End
+		}
+		if !in {
+			return false
+		}
+	}
+	if len(f.Classes) == 0 {
+		return true
+	}
+	name := ""
+	if op := chip8.Decode(e.Opcode, variant); op != nil {
+		name = op.Name
+	}
+	e.disassemble(variant)
+	mnemonic := strings.SplitN(e.Mnemonic, " ", 2)[0]
+	for _, class := range f.Classes {
+		if strings.EqualFold(class, name) || strings.EqualFold(class, mnemonic) {
+			return true
+		}
+	}
+	return false
+}
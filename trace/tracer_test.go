@@ -0,0 +1,149 @@
+package trace
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	chip8 "github.com/hermesdt/go-plan8"
+	"github.com/hermesdt/go-plan8/asm"
+	"github.com/stretchr/testify/assert"
+)
+
+const testProgram = `
+		LD V3, 4          ; 200
+		CALL sub          ; 202
+		LD I, 0x300       ; 204
+	loop:
+		JP loop           ; 206
+	sub:
+		ADD V3, 1         ; 208
+		RET               ; 20A
+`
+
+func newMachine(t *testing.T, src string, variant chip8.Variant) *chip8.Machine {
+	rom, err := asm.Assemble(src, variant)
+	assert.Nil(t, err)
+	c := chip8.NewChip8(variant)
+	assert.Nil(t, c.LoadRomBytes(rom))
+	return chip8.NewMachine(c)
+}
+
+func TestTracerText(t *testing.T) {
+	m := newMachine(t, testProgram, chip8.VariantChip8)
+	var out bytes.Buffer
+	tr := New(&out, Text, chip8.VariantChip8)
+	m.Tracer = tr
+
+	assert.Nil(t, m.Emulate(6))
+	assert.Nil(t, tr.Flush())
+
+	assert.Equal(t, `0000000000 0200 6304 LD V3, 0x04          I=0000 SP=0 V3=00->04
+0000000001 0202 2208 CALL 0x208           I=0000 SP=1
+0000000002 0208 7301 ADD V3, 0x01         I=0000 SP=1 V3=04->05
+0000000003 020A 00EE RET                  I=0000 SP=0
+0000000004 0204 A300 LD I, 0x300          I=0300 SP=0
+0000000005 0206 1206 JP 0x206             I=0300 SP=0
+`, out.String())
+}
+
+func TestTracerFilter(t *testing.T) {
+	for _, test := range []struct {
+		filter Filter
+		pcs    string
+	}{
+		{Filter{Ranges: []Range{{0x208, 0x20A}}}, "0208 020A"},
+		{Filter{Classes: []string{"callsub", "RET"}}, "0202 020A"},
+		{Filter{Ranges: []Range{{0x200, 0x204}}, Classes: []string{"LD"}}, "0200 0204"},
+	} {
+		m := newMachine(t, testProgram, chip8.VariantChip8)
+		var out bytes.Buffer
+		tr := New(&out, Text, chip8.VariantChip8)
+		tr.Filter = test.filter
+		m.Tracer = tr
+
+		assert.Nil(t, m.Emulate(6))
+		assert.Nil(t, tr.Flush())
+
+		var pcs []string
+		for _, line := range strings.Split(strings.TrimSpace(out.String()), "\n") {
+			pcs = append(pcs, strings.Fields(line)[1])
+		}
+		assert.Equal(t, test.pcs, strings.Join(pcs, " "), "%+v", test.filter)
+	}
+}
+
+func TestTracerRing(t *testing.T) {
+	m := newMachine(t, `
+		LD V0, 1
+		LD V1, 2
+		LD DT, V1
+		LD V2, 3
+		RET
+	`, chip8.VariantChip8)
+	var out bytes.Buffer
+	tr := NewRing(&out, Text, chip8.VariantChip8, 3)
+	m.Tracer = tr
+
+	assert.Nil(t, m.Emulate(4))
+	assert.Equal(t, "", out.String())
+	// Mnemonics are left to the dump.
+	assert.Equal(t, "", tr.ring[0].Mnemonic)
+	assert.NotNil(t, m.Emulate(1))
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	assert.Len(t, lines, 3)
+	assert.Contains(t, lines[0], "0204 F115 LD DT, V1")
+	assert.Contains(t, lines[0], "DT=00->02")
+	assert.Contains(t, lines[2], "0208 00EE RET")
+	assert.Contains(t, lines[2], "error: chip8: stack underflow")
+
+	// The ring was emptied by the dump.
+	assert.Nil(t, tr.Dump())
+	assert.Len(t, strings.Split(strings.TrimSpace(out.String()), "\n"), 3)
+}
+
+func TestTracerRing_empty(t *testing.T) {
+	m := newMachine(t, `
+		LD V0, 1
+		RET
+	`, chip8.VariantChip8)
+	var out bytes.Buffer
+	tr := NewRing(&out, Text, chip8.VariantChip8, 0)
+	m.Tracer = tr
+
+	assert.NotNil(t, m.Emulate(2))
+	assert.Nil(t, tr.Flush())
+
+	assert.Len(t, strings.Split(strings.TrimSpace(out.String()), "\n"), 2)
+}
+
+func TestTracerLong(t *testing.T) {
+	m := newMachine(t, `
+		LD I, LONG 0x1234
+		LD V0, 1
+	`, chip8.VariantXOChip)
+	var out bytes.Buffer
+	tr := New(&out, Text, chip8.VariantXOChip)
+	m.Tracer = tr
+
+	assert.Nil(t, m.Emulate(2))
+	assert.Nil(t, tr.Flush())
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	assert.Contains(t, lines[0], "0200 F000 LD I, LONG 0x1234")
+	assert.Contains(t, lines[1], "0204 6001 LD V0, 0x01")
+}
+
+func TestParseRange(t *testing.T) {
+	r, err := ParseRange("200-2ff")
+	assert.Nil(t, err)
+	assert.Equal(t, Range{0x200, 0x2FF}, r)
+	r, err = ParseRange("0x20A")
+	assert.Nil(t, err)
+	assert.Equal(t, Range{0x20A, 0x20A}, r)
+	_, err = ParseRange("300-200")
+	assert.NotNil(t, err)
+	_, err = ParseRange("zz")
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,237 @@
+// Package tracediff compares how a program runs with a reference trace,
+// recorded by another emulator or an earlier release of this one, and
+// reports the first instruction where they part ways.
+package tracediff
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	chip8 "github.com/hermesdt/go-plan8"
+)
+
+// Record runs up to n instructions of m in emulated time, writing the
+// canonical trace of their states to w. A program that exits ends the
+// trace early without error.
+func Record(m *chip8.Machine, w io.Writer, n int) error {
+	r := &Recorder{}
+	m.Tracer = r
+	defer func() { m.Tracer = nil }()
+	bw := bufio.NewWriter(w)
+	for i := 0; i < n; i++ {
+		err := m.Emulate(1)
+		if r.State.Has != 0 {
+			fmt.Fprintln(bw, r.State.String())
+			r.State.Has = 0
+		}
+		if errors.Is(err, chip8.ErrExit) {
+			break
+		}
+		if err != nil {
+			bw.Flush()
+			return err
+		}
+	}
+	return bw.Flush()
+}
+
+// Divergence is the first instruction where a run and its reference
+// disagree.
+type Divergence struct {
+	// Line is the line of the reference trace, from 1.
+	Line int
+	Ours *State
+	Ref  *State
+	// Err is set, and Ours nil, when the run halted while the
+	// reference went on.
+	Err    error
+	Memory []MemoryDiff
+	// History holds the matching states before, oldest first.
+	History []*State
+}
+
+type MemoryDiff struct {
+	Addr      uint16
+	Ours, Ref uint8
+}
+
+// Compare runs up to n instructions of m in emulated time against the
+// reference trace ref, keeping up to history matching states for the
+// report. It returns nil if the run matches for as long as both go.
+func Compare(m *chip8.Machine, ref io.Reader, n, history int) (*Divergence, error) {
+	r := &Recorder{}
+	m.Tracer = r
+	defer func() { m.Tracer = nil }()
+	c := m.Chip8
+	refMem := append([]byte(nil), c.Memory[:c.Variant.MemorySize()]...)
+
+	scanner := bufio.NewScanner(ref)
+	line := 0
+	var past []*State
+	for i := 0; i < n; i++ {
+		want, err := nextState(scanner, &line)
+		if err == io.EOF {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("reference line %d: %v", line, err)
+		}
+
+		r.State.Has = 0
+		err = m.Emulate(1)
+		got := r.State
+		if got.Has == 0 {
+			return &Divergence{Line: line, Ref: want, Err: err, History: past}, nil
+		}
+		d := &Divergence{Line: line, Ours: &got, Ref: want, History: past}
+		if want.Has&FieldMemory != 0 {
+			for _, w := range want.Writes {
+				if int(w.Addr) >= len(refMem) {
+					return nil, fmt.Errorf("reference line %d: write to %#04x is outside memory", line, w.Addr)
+				}
+				refMem[w.Addr] = w.Value
+			}
+			d.Memory = memoryDiff(c, refMem, got.Writes, want.Writes)
+		} else {
+			// Without mem the reference is taken to have changed
+			// what we did.
+			for _, w := range got.Writes {
+				refMem[w.Addr] = w.Value
+			}
+		}
+		if !matches(&got, want) || len(d.Memory) > 0 {
+			return d, nil
+		}
+		if err != nil {
+			// The run halted, so the reference should end here too.
+			next, err2 := nextState(scanner, &line)
+			if err2 == io.EOF {
+				return nil, nil
+			}
+			if err2 != nil {
+				return nil, fmt.Errorf("reference line %d: %v", line, err2)
+			}
+			return &Divergence{Line: line, Ref: next, Err: err, History: append(past, &got)}, nil
+		}
+		if history > 0 {
+			if len(past) == history {
+				past = past[1:]
+			}
+			past = append(past, &got)
+		}
+	}
+	return nil, nil
+}
+
+// nextState reads the next state, skipping blank lines and # comments.
+func nextState(scanner *bufio.Scanner, line *int) (*State, error) {
+	for scanner.Scan() {
+		*line++
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		return ParseState(text)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return nil, io.EOF
+}
+
+// matches compares the fields the reference has, except the cycle
+// which only numbers the lines.
+func matches(got, want *State) bool {
+	for _, r := range rows(got, want) {
+		if r.differs() {
+			return false
+		}
+	}
+	return true
+}
+
+// memoryDiff compares the bytes either side wrote; all others agreed
+// before the instruction.
+func memoryDiff(c *chip8.Chip8, refMem []byte, ours, ref []Write) []MemoryDiff {
+	var diffs []MemoryDiff
+	seen := map[uint16]bool{}
+	for _, w := range append(append([]Write(nil), ours...), ref...) {
+		if seen[w.Addr] {
+			continue
+		}
+		seen[w.Addr] = true
+		if c.Memory[w.Addr] != refMem[w.Addr] {
+			diffs = append(diffs, MemoryDiff{w.Addr, c.Memory[w.Addr], refMem[w.Addr]})
+		}
+	}
+	return diffs
+}
+
+type row struct {
+	name      string
+	ours, ref string
+}
+
+func (r row) differs() bool {
+	return r.ref != "-" && r.ours != r.ref
+}
+
+// rows lays out the compared fields, with "-" for those the reference
+// does not have.
+func rows(ours, ref *State) []row {
+	var rs []row
+	add := func(f Field, name, format string, o, r interface{}) {
+		row := row{name, fmt.Sprintf(format, o), "-"}
+		if ref.Has&f != 0 {
+			row.ref = fmt.Sprintf(format, r)
+		}
+		rs = append(rs, row)
+	}
+	add(FieldPC, "PC", "%04X", ours.PC, ref.PC)
+	add(FieldOpcode, "opcode", "%04X", ours.Opcode, ref.Opcode)
+	add(FieldI, "I", "%04X", ours.I, ref.I)
+	add(FieldSP, "SP", "%X", ours.SP, ref.SP)
+	for x := range ours.V {
+		add(FieldV, fmt.Sprintf("V%X", x), "%02X", ours.V[x], ref.V[x])
+	}
+	add(FieldTimers, "DT", "%02X", ours.DT, ref.DT)
+	add(FieldTimers, "ST", "%02X", ours.ST, ref.ST)
+	return rs
+}
+
+// Write prints the divergence with the two states side by side,
+// marking the fields that differ.
+func (d *Divergence) Write(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	if len(d.History) > 0 {
+		fmt.Fprintln(bw, "last matching instructions:")
+		for _, s := range d.History {
+			fmt.Fprintf(bw, "  %v\n", s)
+		}
+	}
+	if d.Ours == nil {
+		fmt.Fprintf(bw, "run halted at line %d of the reference: %v\n", d.Line, d.Err)
+		fmt.Fprintf(bw, "reference continues with: %v\n", d.Ref)
+		return bw.Flush()
+	}
+	fmt.Fprintf(bw, "first divergence at cycle %d, line %d of the reference\n", d.Ours.Cycle, d.Line)
+	fmt.Fprintf(bw, "%-8s %-6s %s\n", "", "ours", "reference")
+	for _, r := range rows(d.Ours, d.Ref) {
+		mark := ""
+		if r.differs() {
+			mark = "  <<"
+		}
+		line := fmt.Sprintf("%-8s %-6s %-9s%s", r.name, r.ours, r.ref, mark)
+		fmt.Fprintln(bw, strings.TrimRight(line, " "))
+	}
+	if len(d.Memory) > 0 {
+		fmt.Fprintf(bw, "%-8s %-6s %s\n", "memory", "ours", "reference")
+		for _, m := range d.Memory {
+			fmt.Fprintf(bw, "%04X     %02X     %02X         <<\n", m.Addr, m.Ours, m.Ref)
+		}
+	}
+	return bw.Flush()
+}
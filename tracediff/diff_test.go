@@ -0,0 +1,144 @@
+package tracediff
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	chip8 "github.com/hermesdt/go-plan8"
+	"github.com/hermesdt/go-plan8/asm"
+	"github.com/stretchr/testify/assert"
+)
+
+const testProgram = `
+		LD V3, 4          ; 200
+		LD I, 0x300       ; 202
+	loop:
+		ADD V3, 1         ; 204
+		LD B, V3          ; 206
+		JP loop           ; 208
+`
+
+func newMachine(t *testing.T, src string) *chip8.Machine {
+	rom, err := asm.Assemble(src, chip8.VariantChip8)
+	assert.Nil(t, err)
+	c := chip8.NewChip8(chip8.VariantChip8)
+	assert.Nil(t, c.LoadRomBytes(rom))
+	return chip8.NewMachine(c)
+}
+
+func record(t *testing.T, src string, n int) []string {
+	var out bytes.Buffer
+	assert.Nil(t, Record(newMachine(t, src), &out, n))
+	return strings.Split(strings.TrimSpace(out.String()), "\n")
+}
+
+func TestRecord(t *testing.T) {
+	lines := record(t, testProgram, 5)
+
+	assert.Equal(t, []string{
+		"cycle=0 pc=0200 op=6304 i=0000 sp=0 v=00000004000000000000000000000000 dt=00 st=00 mem=",
+		"cycle=1 pc=0202 op=A300 i=0300 sp=0 v=00000004000000000000000000000000 dt=00 st=00 mem=",
+		"cycle=2 pc=0204 op=7301 i=0300 sp=0 v=00000005000000000000000000000000 dt=00 st=00 mem=",
+		"cycle=3 pc=0206 op=F333 i=0300 sp=0 v=00000005000000000000000000000000 dt=00 st=00 mem=0302:05",
+		"cycle=4 pc=0208 op=1204 i=0300 sp=0 v=00000005000000000000000000000000 dt=00 st=00 mem=",
+	}, lines)
+
+	// Programs that exit end the trace.
+	rom, err := asm.Assemble("LD V0, 1\nEXIT\nJP 0x200", chip8.VariantSChip)
+	assert.Nil(t, err)
+	c := chip8.NewChip8(chip8.VariantSChip)
+	assert.Nil(t, c.LoadRomBytes(rom))
+	m := chip8.NewMachine(c)
+	var out bytes.Buffer
+	assert.Nil(t, Record(m, &out, 10))
+	assert.Equal(t, 2, strings.Count(out.String(), "\n"))
+}
+
+func compare(t *testing.T, ref []string, history int) *Divergence {
+	d, err := Compare(newMachine(t, testProgram), strings.NewReader(strings.Join(ref, "\n")), 100, history)
+	assert.Nil(t, err)
+	return d
+}
+
+func TestCompareMatches(t *testing.T) {
+	ref := record(t, testProgram, 50)
+	assert.Nil(t, compare(t, ref, 3))
+
+	// References may leave fields out and carry comments.
+	partial := []string{"# pc only", "pc=0200", "", "pc=0202 i=0300 extra=1"}
+	assert.Nil(t, compare(t, partial, 3))
+}
+
+func TestCompareRegisters(t *testing.T) {
+	ref := record(t, testProgram, 50)
+	ref[6] = strings.Replace(ref[6], "v=00000006", "v=00000007", 1)
+
+	d := compare(t, ref, 2)
+
+	assert.NotNil(t, d)
+	assert.Equal(t, 7, d.Line)
+	assert.Equal(t, uint64(6), d.Ours.Cycle)
+	assert.Len(t, d.History, 2)
+	assert.Empty(t, d.Memory)
+
+	var out bytes.Buffer
+	assert.Nil(t, d.Write(&out))
+	report := out.String()
+	assert.Contains(t, report, "first divergence at cycle 6, line 7 of the reference\n")
+	assert.Contains(t, report, "V3       06     07         <<\n")
+	assert.Contains(t, report, "V2       00     00\n")
+	assert.Contains(t, report, "  "+ref[4]+"\n  "+ref[5]+"\n")
+}
+
+func TestCompareMemory(t *testing.T) {
+	ref := record(t, testProgram, 50)
+	ref[3] = strings.Replace(ref[3], "mem=0302:05", "mem=0301:01,0302:04", 1)
+
+	d := compare(t, ref, 0)
+
+	assert.NotNil(t, d)
+	assert.Equal(t, 4, d.Line)
+	assert.Equal(t, []MemoryDiff{{0x302, 5, 4}, {0x301, 0, 1}}, d.Memory)
+	var out bytes.Buffer
+	assert.Nil(t, d.Write(&out))
+	assert.Contains(t, out.String(), "memory   ours   reference\n0302     05     04         <<\n0301     00     01         <<\n")
+}
+
+func TestCompareMemory_partial(t *testing.T) {
+	ref := record(t, testProgram, 50)
+	ref[3] = strings.Replace(ref[3], " mem=0302:05", "", 1)
+	ref[6] = strings.Replace(ref[6], "mem=0302:06", "mem=0301:01", 1)
+
+	d := compare(t, ref, 0)
+
+	assert.NotNil(t, d)
+	assert.Equal(t, 7, d.Line)
+	assert.Equal(t, []MemoryDiff{{0x302, 6, 5}, {0x301, 0, 1}}, d.Memory)
+}
+
+func TestCompareHalted(t *testing.T) {
+	m := newMachine(t, "LD V0, 1\nRET")
+	ref := "pc=0200\npc=0202\npc=0204\n"
+
+	d, err := Compare(m, strings.NewReader(ref), 100, 5)
+
+	assert.Nil(t, err)
+	assert.NotNil(t, d)
+	assert.Nil(t, d.Ours)
+	assert.Equal(t, 3, d.Line)
+	assert.Equal(t, chip8.ErrStackUnderflow, unwrap(d.Err))
+	var out bytes.Buffer
+	assert.Nil(t, d.Write(&out))
+	assert.Contains(t, out.String(), "reference continues with: pc=0204\n")
+
+	_, err = Compare(newMachine(t, testProgram), strings.NewReader("pc=zz"), 10, 0)
+	assert.NotNil(t, err)
+}
+
+func unwrap(err error) error {
+	if e, ok := err.(*chip8.ExecError); ok {
+		return e.Err
+	}
+	return err
+}
@@ -0,0 +1,195 @@
+package tracediff
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+
+	chip8 "github.com/hermesdt/go-plan8"
+)
+
+// Field marks the parts of a State a trace line contained.
+type Field uint16
+
+const (
+	FieldCycle Field = 1 << iota
+	FieldPC
+	FieldOpcode
+	FieldI
+	FieldSP
+	FieldV
+	FieldTimers
+	FieldMemory
+
+	AllFields = FieldCycle | FieldPC | FieldOpcode | FieldI | FieldSP | FieldV | FieldTimers | FieldMemory
+)
+
+// State is the machine state after one instruction, a line of the
+// canonical trace:
+//
+//	cycle=12 pc=0202 op=7301 i=0300 sp=1 v=00000005000000000000000000000000 dt=00 st=00 mem=0300:05
+//
+// PC and op are the address and first word of the instruction
+// executed. v holds V0-VF and mem the bytes the instruction changed,
+// comma separated. Numbers are hex except the decimal cycle. Fields
+// may come in any order and any may be left out, as emulators tracing
+// for comparison may not know them all; unknown fields are ignored.
+type State struct {
+	Cycle  uint64
+	PC     uint16
+	Opcode uint16
+	I      uint16
+	SP     uint16
+	V      [16]uint8
+	DT, ST uint8
+	Writes []Write
+	Has    Field
+}
+
+// Write is a byte of memory an instruction changed.
+type Write struct {
+	Addr  uint16
+	Value uint8
+}
+
+func (s *State) String() string {
+	var b strings.Builder
+	field := func(f Field, format string, args ...interface{}) {
+		if s.Has&f != 0 {
+			if b.Len() > 0 {
+				b.WriteByte(' ')
+			}
+			fmt.Fprintf(&b, format, args...)
+		}
+	}
+	field(FieldCycle, "cycle=%d", s.Cycle)
+	field(FieldPC, "pc=%04X", s.PC)
+	field(FieldOpcode, "op=%04X", s.Opcode)
+	field(FieldI, "i=%04X", s.I)
+	field(FieldSP, "sp=%X", s.SP)
+	field(FieldV, "v=%s", strings.ToUpper(hex.EncodeToString(s.V[:])))
+	field(FieldTimers, "dt=%02X st=%02X", s.DT, s.ST)
+	var writes []string
+	for _, w := range s.Writes {
+		writes = append(writes, fmt.Sprintf("%04X:%02X", w.Addr, w.Value))
+	}
+	field(FieldMemory, "mem=%s", strings.Join(writes, ","))
+	return b.String()
+}
+
+// ParseState parses a line of a canonical trace.
+func ParseState(line string) (*State, error) {
+	s := &State{}
+	for _, kv := range strings.Fields(line) {
+		parts := strings.SplitN(kv, "=", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("tracediff: %q is not key=value", kv)
+		}
+		key, value := strings.ToLower(parts[0]), parts[1]
+		var err error
+		switch key {
+		case "cycle":
+			s.Cycle, err = strconv.ParseUint(value, 10, 64)
+			s.Has |= FieldCycle
+		case "pc":
+			s.PC, err = parseHex16(value)
+			s.Has |= FieldPC
+		case "op":
+			s.Opcode, err = parseHex16(value)
+			s.Has |= FieldOpcode
+		case "i":
+			s.I, err = parseHex16(value)
+			s.Has |= FieldI
+		case "sp":
+			s.SP, err = parseHex16(value)
+			s.Has |= FieldSP
+		case "v":
+			var v []byte
+			v, err = hex.DecodeString(value)
+			if err == nil && len(v) != len(s.V) {
+				err = fmt.Errorf("want %d registers", len(s.V))
+			}
+			copy(s.V[:], v)
+			s.Has |= FieldV
+		case "dt", "st":
+			var t uint64
+			t, err = strconv.ParseUint(value, 16, 8)
+			if key == "dt" {
+				s.DT = uint8(t)
+			} else {
+				s.ST = uint8(t)
+			}
+			s.Has |= FieldTimers
+		case "mem":
+			s.Writes, err = parseWrites(value)
+			s.Has |= FieldMemory
+		}
+		if err != nil {
+			return nil, fmt.Errorf("tracediff: bad %s %q: %v", key, value, err)
+		}
+	}
+	return s, nil
+}
+
+func parseHex16(s string) (uint16, error) {
+	v, err := strconv.ParseUint(strings.TrimPrefix(s, "0x"), 16, 16)
+	return uint16(v), err
+}
+
+func parseWrites(s string) ([]Write, error) {
+	var writes []Write
+	for _, item := range strings.Split(s, ",") {
+		if item == "" {
+			continue
+		}
+		parts := strings.SplitN(item, ":", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("%q is not ADDR:VALUE", item)
+		}
+		addr, err := parseHex16(parts[0])
+		if err != nil {
+			return nil, err
+		}
+		v, err := strconv.ParseUint(parts[1], 16, 8)
+		if err != nil {
+			return nil, err
+		}
+		writes = append(writes, Write{addr, uint8(v)})
+	}
+	return writes, nil
+}
+
+// Recorder is a chip8.Tracer that keeps the State after the latest
+// instruction.
+type Recorder struct {
+	State  State
+	Err    error
+	memory []byte
+}
+
+func (r *Recorder) Before(c *chip8.Chip8, cycle uint64) {
+	size := c.Variant.MemorySize()
+	pc := int(c.PC) % size
+	r.State = State{
+		Cycle:  cycle,
+		PC:     c.PC,
+		Opcode: uint16(c.Memory[pc])<<8 | uint16(c.Memory[(pc+1)%size]),
+		Has:    AllFields,
+	}
+	r.memory = append(r.memory[:0], c.Memory[:size]...)
+}
+
+func (r *Recorder) After(c *chip8.Chip8, err error) {
+	s := &r.State
+	s.I, s.SP, s.V, s.DT, s.ST = c.I, c.SP, c.V, c.DelayTimer, c.SoundTimer
+	if mem := c.Memory[:len(r.memory)]; !bytes.Equal(mem, r.memory) {
+		for addr, v := range mem {
+			if v != r.memory[addr] {
+				s.Writes = append(s.Writes, Write{uint16(addr), v})
+			}
+		}
+	}
+	r.Err = err
+}
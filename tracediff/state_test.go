@@ -0,0 +1,51 @@
+package tracediff
+
+import (
+	"testing"
+
+	chip8 "github.com/hermesdt/go-plan8"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStateRoundTrip(t *testing.T) {
+	s := &State{
+		Cycle: 12, PC: 0x202, Opcode: 0xF355, I: 0x300, SP: 1,
+		DT: 0x3C, ST: 2,
+		Writes: []Write{{0x300, 5}, {0x301, 0xFF}},
+		Has:    AllFields,
+	}
+	s.V[3] = 5
+	line := "cycle=12 pc=0202 op=F355 i=0300 sp=1 v=00000005000000000000000000000000 dt=3C st=02 mem=0300:05,0301:FF"
+	assert.Equal(t, line, s.String())
+
+	parsed, err := ParseState(line)
+	assert.Nil(t, err)
+	assert.Equal(t, s, parsed)
+}
+
+func TestParseStatePartial(t *testing.T) {
+	s, err := ParseState("PC=0x0204 I=a300 extra=ignored mem=")
+	assert.Nil(t, err)
+	assert.Equal(t, &State{PC: 0x204, I: 0xA300, Has: FieldPC | FieldI | FieldMemory}, s)
+	assert.Equal(t, "pc=0204 i=A300 mem=", s.String())
+
+	for _, bad := range []string{"pc", "pc=zz", "v=0011", "mem=0300", "dt=100", "cycle=-1"} {
+		_, err := ParseState(bad)
+		assert.NotNil(t, err, bad)
+	}
+}
+
+func TestRecorder(t *testing.T) {
+	c := chip8.NewChip8(chip8.VariantChip8)
+	// LD I, 0x300; LD V0, 7; LD [I], V0
+	assert.Nil(t, c.LoadRomBytes([]byte{0xA3, 0x00, 0x60, 0x07, 0xF0, 0x55}))
+	m := chip8.NewMachine(c)
+	r := &Recorder{}
+	m.Tracer = r
+
+	assert.Nil(t, m.Emulate(2))
+	assert.Equal(t, "cycle=1 pc=0202 op=6007 i=0300 sp=0 v=07000000000000000000000000000000 dt=00 st=00 mem=", r.State.String())
+	assert.Nil(t, m.Emulate(1))
+	assert.Equal(t, []Write{{0x300, 7}}, r.State.Writes)
+	assert.Equal(t, uint16(0x300), r.State.I)
+}
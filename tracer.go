@@ -0,0 +1,12 @@
+package chip8
+
+// Tracer observes every instruction a Machine executes, see the trace
+// package.
+type Tracer interface {
+	// Before is called with the instruction at PC about to execute as
+	// the given cycle.
+	Before(c *Chip8, cycle uint64)
+	// After is called once it has executed, with its error if it
+	// failed.
+	After(c *Chip8, err error)
+}
@@ -0,0 +1,59 @@
+package chip8
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Variant is the instruction set a Chip8 implements. Each variant is a
+// superset of the previous one.
+type Variant uint8
+
+const (
+	VariantChip8 Variant = iota
+	VariantSChip
+	VariantXOChip
+)
+
+const (
+	MemorySize       = 0x1000
+	XOChipMemorySize = 0x10000
+)
+
+func (v Variant) String() string {
+	switch v {
+	case VariantChip8:
+		return "chip8"
+	case VariantSChip:
+		return "schip"
+	case VariantXOChip:
+		return "xochip"
+	}
+	return fmt.Sprintf("variant(%d)", uint8(v))
+}
+
+func LookupVariant(name string) (Variant, error) {
+	for _, v := range []Variant{VariantChip8, VariantSChip, VariantXOChip} {
+		if v.String() == strings.ToLower(name) {
+			return v, nil
+		}
+	}
+	return 0, fmt.Errorf("chip8: unknown variant %q", name)
+}
+
+func (v Variant) MemorySize() int {
+	if v == VariantXOChip {
+		return XOChipMemorySize
+	}
+	return MemorySize
+}
+
+func (v Variant) DefaultQuirks() Quirks {
+	switch v {
+	case VariantSChip:
+		return QuirksSChip11
+	case VariantXOChip:
+		return QuirksXOChip
+	}
+	return QuirksModern
+}
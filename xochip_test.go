@@ -0,0 +1,153 @@
+package chip8
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewChip8_variant(t *testing.T) {
+	c := NewChip8(VariantXOChip)
+
+	assert.Equal(t, QuirksXOChip, c.Quirks)
+	assert.Nil(t, c.LoadRomBytes(make([]byte, XOChipMemorySize-RomStart)))
+
+	c = NewChip8(VariantSChip)
+	assert.Equal(t, QuirksSChip11, c.Quirks)
+	assert.NotNil(t, c.LoadRomBytes(make([]byte, MemorySize-RomStart+1)))
+}
+
+func TestLookupVariant(t *testing.T) {
+	v, err := LookupVariant("XOCHIP")
+	assert.Nil(t, err)
+	assert.Equal(t, VariantXOChip, v)
+
+	_, err = LookupVariant("megachip")
+	assert.NotNil(t, err)
+}
+
+func TestXOChipOpcodes_otherVariants(t *testing.T) {
+	for _, op := range []uint16{0x00D1, 0xF000, 0xF101, 0xF002, 0xF13A} {
+		o := Opcode{Value: op, Chip8: &Chip8{Variant: VariantSChip}}
+		assertExecError(t, o.Execute(), ErrUnknownOpcode, 0, op)
+	}
+	o := Opcode{Value: 0x5122, Chip8: &Chip8{Variant: VariantSChip}}
+	o.Execute()
+	assert.Equal(t, uint16(4), o.Chip8.PC)
+}
+
+func TestSaveLoadRange(t *testing.T) {
+	c := &Chip8{Variant: VariantXOChip, I: 0x400}
+	c.V[2], c.V[3], c.V[4] = 0x22, 0x33, 0x44
+	o := Opcode{Value: 0x5242, Chip8: c}
+
+	assert.Nil(t, o.Execute())
+	assert.Equal(t, []uint8{0x22, 0x33, 0x44}, c.Memory[0x400:0x403])
+	assert.Equal(t, uint16(0x400), c.I)
+
+	o.Value = 0x5422
+	assert.Nil(t, o.Execute())
+	assert.Equal(t, []uint8{0x44, 0x33, 0x22}, c.Memory[0x400:0x403])
+
+	c.V = [16]uint8{}
+	o.Value = 0x5A83
+	assert.Nil(t, o.Execute())
+	assert.Equal(t, uint8(0x44), c.V[0xA])
+	assert.Equal(t, uint8(0x33), c.V[0x9])
+	assert.Equal(t, uint8(0x22), c.V[0x8])
+	assert.Equal(t, uint16(6), c.PC)
+}
+
+func TestSetILong(t *testing.T) {
+	c := NewChip8(VariantXOChip)
+	c.LoadRomBytes([]byte{0xF0, 0x00, 0xBE, 0xEF})
+
+	assert.Nil(t, c.Step())
+	assert.Equal(t, uint16(0xBEEF), c.I)
+	assert.Equal(t, uint16(0x204), c.PC)
+}
+
+func TestSkip_longInstruction(t *testing.T) {
+	c := NewChip8(VariantXOChip)
+	c.LoadRomBytes([]byte{0x30, 0x00, 0xF0, 0x00, 0x12, 0x34})
+
+	assert.Nil(t, c.Step())
+	assert.Equal(t, uint16(0x206), c.PC)
+
+	c = NewChip8(VariantSChip)
+	c.LoadRomBytes([]byte{0x30, 0x00, 0xF0, 0x00, 0x12, 0x34})
+
+	assert.Nil(t, c.Step())
+	assert.Equal(t, uint16(0x204), c.PC)
+}
+
+func TestMemory64K(t *testing.T) {
+	o := Opcode{Value: 0xF233, Chip8: &Chip8{Variant: VariantXOChip, I: 0xFFF0}}
+	o.Chip8.V[2] = 255
+
+	assert.Nil(t, o.Execute())
+	assert.Equal(t, []uint8{2, 5, 5}, o.Chip8.Memory[0xFFF0:0xFFF3])
+}
+
+func TestPlanes_draw(t *testing.T) {
+	c := &Chip8{Variant: VariantXOChip, I: 0x300}
+	c.Memory[0x300] = 0x80
+	c.Memory[0x301] = 0xC0
+
+	o := Opcode{Value: 0xF301, Chip8: c}
+	o.Execute()
+	assert.Equal(t, uint8(3), c.Screen.SelectedPlanes())
+
+	o.Value = 0xD011
+	o.Execute()
+	assert.Equal(t, uint8(3), c.Screen.Pixel(0, 0))
+	assert.Equal(t, uint8(2), c.Screen.Pixel(0, 1))
+
+	o.Value = 0xF201
+	o.Execute()
+	o.Value = 0x00E0
+	o.Execute()
+	assert.Equal(t, uint8(1), c.Screen.Pixel(0, 0))
+	assert.Equal(t, uint8(0), c.Screen.Pixel(0, 1))
+}
+
+func TestPlanes_none(t *testing.T) {
+	c := &Chip8{Variant: VariantXOChip, I: 0x300}
+	c.Memory[0x300] = 0xFF
+	c.Screen.SelectPlanes(0)
+
+	o := Opcode{Value: 0xD011, Chip8: c}
+	o.Execute()
+
+	assert.Equal(t, byte(0), c.Screen.GetByte(0, 0))
+}
+
+func TestScrollUp(t *testing.T) {
+	c := &Chip8{Variant: VariantXOChip}
+	c.Screen.DrawByte(5, 0, 0xF0)
+
+	o := Opcode{Value: 0x00D3, Chip8: c}
+	o.Execute()
+
+	assert.Equal(t, byte(0xF0), c.Screen.GetByte(2, 0))
+	assert.Equal(t, byte(0x00), c.Screen.GetByte(5, 0))
+}
+
+func TestLoadAudioAndPitch(t *testing.T) {
+	c := NewChip8(VariantXOChip)
+	c.I = 0x400
+	for i := 0; i < 16; i++ {
+		c.Memory[0x400+i] = uint8(i)
+	}
+	assert.Equal(t, 4000.0, c.PlaybackRate())
+
+	o := Opcode{Value: 0xF002, Chip8: c}
+	o.Execute()
+	assert.Equal(t, uint8(15), c.Audio[15])
+
+	c.V[1] = 112
+	o.Value = 0xF13A
+	o.Execute()
+	assert.Equal(t, uint8(112), c.Pitch)
+	assert.InDelta(t, 8000.0, c.PlaybackRate(), 0.001)
+}